@@ -0,0 +1,125 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/ta2gch/iris/runtime"
+	"github.com/ta2gch/iris/runtime/ilos"
+	"github.com/ta2gch/iris/runtime/ilos/class"
+	"github.com/ta2gch/iris/runtime/ilos/instance"
+)
+
+const debuggerHelp = `:backtrace         print the calls in progress
+:frame n           select the call n of the backtrace
+:locals            print the local variables of the selected call
+:continue form     continue the condition with the value of form
+:abort             abort to the top level
+form               evaluate form in the selected call`
+
+// debugger is a break loop which the REPL enters when an error is not
+// handled. It reads its commands from the standard input of the interpreter.
+// An error in a form evaluated in the loop enters a nested loop.
+type debugger struct {
+	interpreter *runtime.Interpreter
+	level       int
+	aborting    bool // unwinding every loop to the top level
+}
+
+// debug runs a break loop on b until the user continues or aborts.
+func (d *debugger) debug(b *runtime.Break) (ilos.Instance, ilos.Instance) {
+	d.level++
+	defer func() { d.level-- }()
+	printReport(b.Condition)
+	if b.Continuable() {
+		fmt.Println("The condition is continuable with :continue.")
+	}
+	fmt.Println("Type :help for the commands of the debugger.")
+	selected := 0
+	for {
+		fmt.Printf("%v] ", d.level)
+		form, err := d.interpreter.Read()
+		if err != nil {
+			d.aborting = true
+			return b.Abort()
+		}
+		switch form {
+		case instance.NewSymbol(":HELP"):
+			fmt.Println(debuggerHelp)
+		case instance.NewSymbol(":BACKTRACE"):
+			for i, frame := range b.Frames() {
+				mark := " "
+				if i == selected {
+					mark = "*"
+				}
+				fmt.Printf("%v %v: %v\n", mark, i, frame)
+			}
+		case instance.NewSymbol(":FRAME"):
+			arg, err := d.interpreter.Read()
+			if err != nil {
+				d.aborting = true
+				return b.Abort()
+			}
+			frames := b.Frames()
+			n, err1 := strconv.Atoi(fmt.Sprint(arg))
+			if err1 != nil || n < 0 || n >= len(frames) {
+				fmt.Printf("No call %v in the backtrace\n", arg)
+				continue
+			}
+			selected = n
+			fmt.Printf("%v: %v\n", n, frames[n])
+		case instance.NewSymbol(":LOCALS"):
+			names, values := b.Locals(selected)
+			for i, name := range names {
+				fmt.Printf("%v = %v\n", name, values[i])
+			}
+		case instance.NewSymbol(":CONTINUE"):
+			arg, err := d.interpreter.Read()
+			if err != nil {
+				d.aborting = true
+				return b.Abort()
+			}
+			if !b.Continuable() {
+				fmt.Println("The condition is not continuable")
+				continue
+			}
+			value, err := d.eval(b, selected, arg)
+			if err != nil {
+				if d.aborting || ilos.InstanceOf(class.Escape, err) {
+					return nil, err
+				}
+				continue
+			}
+			return b.Continue(value)
+		case instance.NewSymbol(":ABORT"):
+			d.aborting = true
+			return b.Abort()
+		default:
+			ret, err := d.eval(b, selected, form)
+			if err != nil {
+				if d.aborting || ilos.InstanceOf(class.Escape, err) {
+					return nil, err
+				}
+				continue
+			}
+			d.interpreter.Pprint(os.Stdout, ret)
+			fmt.Println()
+		}
+	}
+}
+
+// eval evaluates form in the call n of b and prints the error it signals, if
+// any. An escape, such as that of invoke-restart, is not printed but returned
+// for the loop to unwind.
+func (d *debugger) eval(b *runtime.Break, n int, form ilos.Instance) (ilos.Instance, ilos.Instance) {
+	ret, err := b.Eval(n, form)
+	if err != nil && !d.aborting && !ilos.InstanceOf(class.Escape, err) {
+		fmt.Println(err)
+	}
+	return ret, err
+}
@@ -10,13 +10,15 @@ import (
 	"os"
 	golang "runtime"
 
+	"github.com/ta2gch/iris/reader/parser"
+	"github.com/ta2gch/iris/reader/tokenizer"
 	"github.com/ta2gch/iris/runtime"
-	"github.com/ta2gch/iris/runtime/ilos/instance"
+	"github.com/ta2gch/iris/runtime/ilos"
 )
 
 var commit string
 
-func repl(quiet bool) {
+func repl(quiet, debug bool) {
 	if !quiet {
 		if commit == "" {
 			commit = "HEAD"
@@ -25,15 +27,23 @@ func repl(quiet bool) {
 		fmt.Printf("Copyright 2017 ta2gch All Rights Reserved.\n")
 		fmt.Print(">>> ")
 	}
-	runtime.TopLevel.StandardInput = instance.NewStream(os.Stdin, nil)
-	runtime.TopLevel.StandardOutput = instance.NewStream(nil, os.Stdout)
-	runtime.TopLevel.ErrorOutput = instance.NewStream(nil, os.Stderr)
-	for exp, err := runtime.Read(runtime.TopLevel); err == nil; exp, err = runtime.Read(runtime.TopLevel) {
-		ret, err := runtime.Eval(runtime.TopLevel, exp)
-		if err != nil {
-			fmt.Println(err)
+	options := runtime.Options{}
+	d := &debugger{}
+	if debug {
+		options.Debugger = d.debug
+	}
+	interpreter := runtime.New(options)
+	d.interpreter = interpreter
+	for exp, err := interpreter.Read(); err == nil; exp, err = interpreter.Read() {
+		d.aborting = false
+		ret, err := interpreter.Eval(exp)
+		if err != nil && d.aborting {
+			fmt.Println("Aborted to the top level")
+		} else if err != nil {
+			printReport(err)
 		} else {
-			fmt.Println(ret)
+			interpreter.Pprint(os.Stdout, ret)
+			fmt.Println()
 		}
 		if !quiet {
 			fmt.Print(">>> ")
@@ -47,27 +57,87 @@ func script(path string) {
 		return
 	}
 	defer file.Close()
-	runtime.TopLevel.StandardInput = instance.NewStream(file, nil)
-	runtime.TopLevel.StandardOutput = instance.NewStream(nil, os.Stdout)
-	runtime.TopLevel.ErrorOutput = instance.NewStream(nil, os.Stderr)
+	interpreter := runtime.New(runtime.Options{StandardInput: file})
 	for {
-		exp, err := runtime.Read(runtime.TopLevel)
+		exp, err := interpreter.Read()
 		if err != nil {
-			if fmt.Sprint(err) != "#<END-OF-STREAM >" {
-				fmt.Println(err)
+			if !runtime.IsEndOfStream(runtime.AsError(err)) {
+				printReport(err)
 			}
 			return
 		}
-		_, err = runtime.Eval(runtime.TopLevel, exp)
+		_, err = interpreter.Eval(exp)
 		if err != nil {
-			fmt.Println(err)
+			printReport(err)
 			return
 		}
 	}
 }
 
+// printReport prints the condition err and, on lines of their own, the
+// location of the form whose evaluation signaled it, the restarts and the
+// backtrace.
+func printReport(err ilos.Instance) {
+	fmt.Println(err)
+	if location, ok := runtime.Location(err); ok {
+		fmt.Printf("Location: %v\n", location)
+	}
+	printRestarts(err)
+	printBacktrace(err)
+}
+
+// printRestarts prints the restarts which were active where the condition err
+// was signaled.
+func printRestarts(err ilos.Instance) {
+	names := runtime.RestartNames(err)
+	if len(names) == 0 {
+		return
+	}
+	fmt.Println("Restarts:")
+	for i, name := range names {
+		fmt.Printf("  %v: %v\n", i, name)
+	}
+}
+
+// printBacktrace prints the calls which were in progress where the condition
+// err was signaled.
+func printBacktrace(err ilos.Instance) {
+	frames := runtime.ConditionBacktrace(err)
+	if len(frames) == 0 {
+		return
+	}
+	fmt.Println("Backtrace:")
+	for i, frame := range frames {
+		fmt.Printf("  %v: %v\n", i, frame)
+	}
+}
+
+// check prints every syntax error in the script at path and reports whether
+// there was none. The script is read with the standard readtable.
+func check(path string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		fmt.Println(err)
+		return false
+	}
+	defer file.Close()
+	_, errs := parser.ParseAll(tokenizer.NewReader(file))
+	for _, err := range errs {
+		fmt.Println(err)
+	}
+	return len(errs) == 0
+}
+
 func main() {
+	checkOnly := flag.Bool("check", false, "print every syntax error in the script instead of running it")
+	debug := flag.Bool("debug", false, "enter the debugger when an error is not handled in the REPL")
 	flag.Parse()
+	if *checkOnly {
+		if flag.NArg() == 0 || !check(flag.Arg(0)) {
+			os.Exit(1)
+		}
+		return
+	}
 	if flag.NArg() > 0 {
 		script(flag.Arg(0))
 		return
@@ -77,9 +147,9 @@ func main() {
 		panic(err)
 	}
 	if (info.Mode() & os.ModeNamedPipe) == 0 {
-		repl(false)
+		repl(false, *debug)
 		return
 	}
-	repl(true)
+	repl(true, *debug)
 	return
 }
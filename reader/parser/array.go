@@ -10,22 +10,54 @@ import (
 	"github.com/ta2gch/iris/runtime/ilos/instance"
 )
 
-func list2array(dim int, list ilos.Instance) (ilos.Instance, ilos.Instance) {
+// elements returns the elements of list, or false if list is not a proper
+// list.
+func elements(list ilos.Instance) ([]ilos.Instance, bool) {
+	objs := []ilos.Instance{}
+	for ilos.InstanceOf(class.Cons, list) {
+		objs = append(objs, list.(*instance.Cons).Car)
+		list = list.(*instance.Cons).Cdr
+	}
+	return objs, list == instance.Nil
+}
+
+// sameDimensions reports whether the arrays a and b, which have the same rank,
+// have the same dimensions.
+func sameDimensions(a, b *instance.GeneralArrayStar) bool {
+	if len(a.Vector) != len(b.Vector) {
+		return false
+	}
+	return len(a.Vector) == 0 || sameDimensions(a.Vector[0], b.Vector[0])
+}
+
+// list2array returns the array of rank dim whose elements are in list, which
+// must be a proper list nested dim levels deep whose lists at each level have
+// the same length. It returns false if list is not.
+func list2array(dim int, list ilos.Instance) (*instance.GeneralArrayStar, bool) {
 	if dim == 0 {
-		return instance.NewGeneralArrayStar(nil, list), nil
+		return instance.NewGeneralArrayStar(nil, list).(*instance.GeneralArrayStar), true
 	}
-	car, cdr, arrays := instance.Nil, list, []*instance.GeneralArrayStar{}
-	for ilos.InstanceOf(class.Cons, cdr) {
-		car, cdr = cdr.(*instance.Cons).Car, cdr.(*instance.Cons).Cdr
-		array, err := list2array(dim-1, car)
-		if err != nil {
-			return nil, err
+	objs, ok := elements(list)
+	if !ok {
+		return nil, false
+	}
+	arrays := []*instance.GeneralArrayStar{}
+	for _, obj := range objs {
+		array, ok := list2array(dim-1, obj)
+		if !ok || len(arrays) > 0 && !sameDimensions(arrays[0], array) {
+			return nil, false
 		}
-		arrays = append(arrays, array.(*instance.GeneralArrayStar))
+		arrays = append(arrays, array)
 	}
-	return instance.NewGeneralArrayStar(arrays, nil), nil
+	return instance.NewGeneralArrayStar(arrays, nil).(*instance.GeneralArrayStar), true
 }
 
-func list2vector(list ilos.Instance) (ilos.Instance, ilos.Instance) {
-	return instance.NewGeneralVector(list.(instance.List).Slice()), nil
+// list2vector returns the vector of the elements of list, or false if list
+// is not a proper list.
+func list2vector(list ilos.Instance) (ilos.Instance, bool) {
+	objs, ok := elements(list)
+	if !ok {
+		return nil, false
+	}
+	return instance.NewGeneralVector(objs), true
 }
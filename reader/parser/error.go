@@ -0,0 +1,167 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package parser
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/ta2gch/iris/reader/tokenizer"
+	"github.com/ta2gch/iris/runtime/ilos"
+	"github.com/ta2gch/iris/runtime/ilos/class"
+	"github.com/ta2gch/iris/runtime/ilos/instance"
+)
+
+// Error is a syntax error, which ParseAll reports.
+type Error struct {
+	Position tokenizer.Position
+	Text     string // the text in error, if any
+	Message  string
+	Expected string // what was expected instead, if known
+}
+
+func (err *Error) Error() string {
+	s := fmt.Sprintf("%v: %v", err.Position, err.Message)
+	if err.Text != "" {
+		s += fmt.Sprintf(": %q", err.Text)
+	}
+	if err.Expected != "" {
+		s += ", expected " + err.Expected
+	}
+	return s
+}
+
+// recovery is the syntax of a Reader which ParseAll reads from. It reads by
+// the syntax the Reader had before and keeps the errors parse recovers from.
+type recovery struct {
+	tokenizer.Syntax
+	errors  []*Error
+	pending *Error // of the condition last made by syntaxError
+}
+
+// Unwrap returns the syntax the Reader had before.
+func (r *recovery) Unwrap() tokenizer.Syntax {
+	return r.Syntax
+}
+
+// unwrap returns the syntax s wraps, as a recovery does, or s itself.
+func unwrap(s tokenizer.Syntax) tokenizer.Syntax {
+	if w, ok := s.(interface{ Unwrap() tokenizer.Syntax }); ok {
+		return unwrap(w.Unwrap())
+	}
+	return s
+}
+
+// record keeps the error of the condition err, which the token text at pos
+// caused. A condition which syntaxError did not make, such as one signaled
+// by the function of a macro character, is described by itself.
+func (r *recovery) record(err ilos.Instance, pos tokenizer.Position, text string) {
+	e := r.pending
+	r.pending = nil
+	if e == nil {
+		e = &Error{pos, text, fmt.Sprint(err), ""}
+	}
+	if e.Position == (tokenizer.Position{}) {
+		e.Position, e.Text = pos, text
+	}
+	r.errors = append(r.errors, e)
+}
+
+// recovering returns the recovery of t, or nil if t is not read by ParseAll.
+func recovering(t *tokenizer.Reader) *recovery {
+	r, _ := t.Syntax().(*recovery)
+	return r
+}
+
+// readtableOf returns the readtable of the syntax s.
+func readtableOf(s tokenizer.Syntax) *Readtable {
+	if s, ok := unwrap(s).(interface{ Table() *Readtable }); ok && s.Table() != nil {
+		return s.Table()
+	}
+	return standard
+}
+
+// parseError returns a <parse-error> of text which is not an object of
+// expectedClass. The reader makes its conditions outside of any environment.
+func parseError(text string, expectedClass ilos.Class) ilos.Instance {
+	return instance.CreateBuiltIn(class.ParseError,
+		instance.NewSymbol("STRING"), instance.NewString([]rune(text)),
+		instance.NewSymbol("EXPECTED-CLASS"), expectedClass)
+}
+
+// floatError returns a <floating-point-overflow> of text, which reads as f,
+// if f is an infinity, or a <floating-point-underflow> if f is a denormal or
+// a zero which text, with a nonzero mantissa, rounds to. It returns nil if f
+// is a normal float or text is a zero.
+func floatError(text string, f float64) ilos.Instance {
+	c := class.FloatingPointOverflow
+	switch {
+	case math.IsInf(f, 0):
+	case math.Abs(f) < 0x1p-1022 && (f != 0 || strings.ContainsAny(mantissa(text), "123456789")):
+		c = class.FloatingPointUnderflow
+	default:
+		return nil
+	}
+	return instance.CreateBuiltIn(c,
+		instance.NewSymbol("OPERATION"), instance.NewSymbol("READ"),
+		instance.NewSymbol("OPERANDS"), instance.NewCons(instance.NewString([]rune(text)), instance.Nil))
+}
+
+// mantissa returns the part of the float literal text before its exponent.
+func mantissa(text string) string {
+	if i := strings.IndexAny(text, "eE"); i >= 0 {
+		return text[:i]
+	}
+	return text
+}
+
+// ieee reports whether the syntax of t lets floats read as IEEE 754 does,
+// with infinities and denormals, instead of signaling.
+func ieee(t *tokenizer.Reader) bool {
+	s, ok := unwrap(t.Syntax()).(interface{ IEEE() bool })
+	return ok && s.IEEE()
+}
+
+// syntaxError returns a <parse-error> of the text of err which is not an
+// object of expectedClass, located at the position of err. If err has no
+// position, the error is located where it is recorded. If t is recovering,
+// the error is kept to be recorded.
+func syntaxError(t *tokenizer.Reader, err *Error, expectedClass ilos.Class) ilos.Instance {
+	cond := parseError(err.Text, expectedClass)
+	if err.Position != (tokenizer.Position{}) {
+		cond = locateError(cond, err.Position)
+	}
+	if r := recovering(t); r != nil {
+		r.pending = err
+	}
+	return cond
+}
+
+// ParseAll reads every form from t to the end of its source and returns the
+// forms with every syntax error in them, in order. It recovers from an error
+// by skipping the malformed token or object, and by closing the lists which
+// are open at the end of the source, so the forms are as complete as the
+// source allows.
+func ParseAll(t *tokenizer.Reader) ([]ilos.Instance, []*Error) {
+	r := &recovery{Syntax: t.Syntax()}
+	if r.Syntax == nil {
+		r.Syntax = standard
+	}
+	defer t.SetSyntax(t.Syntax())
+	t.SetSyntax(r)
+	forms := []ilos.Instance{}
+	for {
+		obj, err := parse(t, -1)
+		switch {
+		case err == nil:
+			forms = append(forms, obj)
+		case ilos.InstanceOf(class.EndOfStream, err):
+			return forms, r.errors
+		default:
+			r.record(closeError(t, err), t.Position(), "")
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package parser
+
+import (
+	"sync"
+	"weak"
+
+	"github.com/ta2gch/iris/reader/tokenizer"
+	"github.com/ta2gch/iris/runtime/ilos"
+	"github.com/ta2gch/iris/runtime/ilos/class"
+	"github.com/ta2gch/iris/runtime/ilos/instance"
+)
+
+// Span is the source of a form, from its first rune to just after its last
+// one.
+type Span struct {
+	Start tokenizer.Position
+	End   tokenizer.Position
+}
+
+func (s Span) String() string {
+	return s.Start.String()
+}
+
+// spans is the side table of the spans of the lists made by Parse, keyed by
+// weak pointers to their first conses, so that the table does not keep the
+// lists alive. The entries of the conses which have been garbage collected are
+// swept when the table has doubled since the last sweep. The weak package
+// needs Go 1.24 or later.
+var spans = struct {
+	sync.Mutex
+	m     map[weak.Pointer[instance.Cons]]Span
+	swept int
+}{m: map[weak.Pointer[instance.Cons]]Span{}}
+
+func setLocation(obj ilos.Instance, span Span) {
+	cons, ok := obj.(*instance.Cons)
+	if !ok {
+		return
+	}
+	spans.Lock()
+	defer spans.Unlock()
+	if len(spans.m) >= 2*spans.swept+1024 {
+		for p := range spans.m {
+			if p.Value() == nil {
+				delete(spans.m, p)
+			}
+		}
+		spans.swept = len(spans.m)
+	}
+	spans.m[weak.Make(cons)] = span
+}
+
+// Location returns the span of the source of obj if obj is a list, or a form
+// written with a reader macro, made by Parse.
+func Location(obj ilos.Instance) (Span, bool) {
+	cons, ok := obj.(*instance.Cons)
+	if !ok {
+		return Span{}, false
+	}
+	spans.Lock()
+	defer spans.Unlock()
+	span, ok := spans.m[weak.Make(cons)]
+	return span, ok
+}
+
+// locateError records pos in the condition err as where it was signaled,
+// unless err already has a location, which is the more precise one.
+func locateError(err ilos.Instance, pos tokenizer.Position) ilos.Instance {
+	if !ilos.InstanceOf(class.SeriousCondition, err) {
+		return err
+	}
+	if _, ok := err.(instance.Instance).GetSlotValue(instance.NewSymbol("IRIS.LOCATION"), class.SeriousCondition); !ok {
+		err.(instance.Instance).SetSlotValue(instance.NewSymbol("IRIS.LOCATION"), instance.NewString([]rune(pos.String())), class.SeriousCondition)
+	}
+	return err
+}
@@ -5,13 +5,13 @@
 package parser
 
 import (
-	"math"
+	"math/big"
 	"regexp"
 	"strconv"
 	"strings"
+	"unicode"
 
 	"github.com/ta2gch/iris/reader/tokenizer"
-	"github.com/ta2gch/iris/runtime/env"
 	"github.com/ta2gch/iris/runtime/ilos"
 	"github.com/ta2gch/iris/runtime/ilos/class"
 	"github.com/ta2gch/iris/runtime/ilos/instance"
@@ -19,180 +19,331 @@ import (
 
 var eop = instance.NewSymbol("End Of Parentheses")
 var bod = instance.NewSymbol("Begin Of Dot")
+var eod = instance.NewSymbol("End Of Delimited List")
 
+var (
+	integerPattern   = regexp.MustCompile(`^[-+]?[[:digit:]]+$`)
+	radixPattern     = regexp.MustCompile(`^#(?:[bB]([-+]?[01]+)|[oO]([-+]?[0-7]+)|[xX]([-+]?[[:xdigit:]]+))$`)
+	ratioPattern     = regexp.MustCompile(`^[-+]?[[:digit:]]+/[[:digit:]]+$`)
+	floatPattern     = regexp.MustCompile(`^[-+]?[[:digit:]]+(?:\.[[:digit:]]+(?:[eE][-+]?[[:digit:]]+)?|[eE][-+]?[[:digit:]]+)$`)
+	characterPattern = regexp.MustCompile(`^#\\(?:([^\pZ\pC])|(\pL+|[uU]\+[[:xdigit:]]+))$`)
+	symbolPattern    = regexp.MustCompile(`^(?:[:&][-\pL\pM\pN+<>/*=?_!$%[\]^{}~.@:]+|\+|-|1\+|1-|[\pL<>/*=?_!$%[\]^{}~][-\pL\pM\pN+<>/*=?_!$%[\]^{}~.@:]*)$`)
+)
+
+// ParseAtom returns the object which tok, the text of a token other than
+// punctuation and reader macros, stands for.
 func ParseAtom(tok string) (ilos.Instance, ilos.Instance) {
+	for _, parse := range []func(string) (ilos.Instance, bool){parseNumber, parseCharacter, parseString, parseSymbol} {
+		if ret, ok := parse(tok); ok {
+			return ret, nil
+		}
+	}
+	return nil, parseError(tok, class.Object)
+}
+
+func parseNumber(tok string) (ilos.Instance, bool) {
 	//
 	// integer
 	//
-	if m, _ := regexp.MatchString("^[-+]?[[:digit:]]+$", tok); m {
-		n, _ := strconv.ParseInt(tok, 10, 64)
-		return instance.NewInteger(int(n)), nil
+	if integerPattern.MatchString(tok) {
+		n, _ := new(big.Int).SetString(tok, 10)
+		return instance.NewBigInteger(n), true
 	}
-	if r := regexp.MustCompile("^#[bB]([-+]?[01]+)$").FindStringSubmatch(tok); len(r) >= 2 {
-		n, _ := strconv.ParseInt(r[1], 2, 64)
-		return instance.NewInteger(int(n)), nil
-	}
-	if r := regexp.MustCompile("^#[oO]([-+]?[0-7]+)$").FindStringSubmatch(tok); len(r) >= 2 {
-		n, _ := strconv.ParseInt(r[1], 8, 64)
-		return instance.NewInteger(int(n)), nil
+	if r := radixPattern.FindStringSubmatch(tok); r != nil {
+		for i, base := range []int{2, 8, 16} {
+			if r[i+1] != "" {
+				n, _ := new(big.Int).SetString(r[i+1], base)
+				return instance.NewBigInteger(n), true
+			}
+		}
 	}
-	if r := regexp.MustCompile("^#[xX]([-+]?[[:xdigit:]]+)$").FindStringSubmatch(tok); len(r) >= 2 {
-		n, _ := strconv.ParseInt(r[1], 16, 64)
-		return instance.NewInteger(int(n)), nil
+	//
+	// ratio
+	//
+	if ratioPattern.MatchString(tok) {
+		if n, ok := new(big.Rat).SetString(tok); ok {
+			return instance.NewRatio(n), true
+		}
 	}
 	//
 	// float
 	//
-	if m, _ := regexp.MatchString(`^[-+]?[[:digit:]]+\.[[:digit:]]+$`, tok); m {
+	if floatPattern.MatchString(tok) {
 		n, _ := strconv.ParseFloat(tok, 64)
-		return instance.NewFloat(n), nil
+		return instance.NewFloat(n), true
 	}
-	if r := regexp.MustCompile(`^([-+]?[[:digit:]]+(?:\.[[:digit:]]+)?)[eE]([-+]?[[:digit:]]+)$`).FindStringSubmatch(tok); len(r) >= 3 {
-		n, _ := strconv.ParseFloat(r[1], 64)
-		e, _ := strconv.ParseInt(r[2], 10, 64)
-		return instance.NewFloat(n * math.Pow10(int(e))), nil
+	return nil, false
+}
+
+func parseCharacter(tok string) (ilos.Instance, bool) {
+	if r := characterPattern.FindStringSubmatch(tok); r != nil {
+		if r[1] != "" {
+			return instance.NewCharacter([]rune(r[1])[0]), true
+		}
+		if c, ok := instance.CharacterName(r[2]); ok {
+			return instance.NewCharacter(c), true
+		}
 	}
-	//
-	// character
-	//
-	if m, _ := regexp.MatchString(`^#\\newline$`, strings.ToLower(tok)); m {
-		return instance.NewCharacter('\n'), nil
+	return nil, false
+}
+
+func parseString(tok string) (ilos.Instance, bool) {
+	if len(tok) >= 2 && tok[0] == '"' && tok[len(tok)-1] == '"' {
+		return instance.NewString([]rune(unescape(tok[1 : len(tok)-1]))), true
 	}
-	if m, _ := regexp.MatchString(`^#\\space$`, strings.ToLower(tok)); m {
-		return instance.NewCharacter(' '), nil
+	return nil, false
+}
+
+func parseSymbol(tok string) (ilos.Instance, bool) {
+	if "NIL" == strings.ToUpper(tok) {
+		return instance.Nil, true
 	}
-	if r := regexp.MustCompile(`^#\\([[:graph:]])$`).FindStringSubmatch(tok); len(r) >= 2 {
-		return instance.NewCharacter(rune(r[1][0])), nil
+	if len(tok) >= 2 && tok[0] == '|' && tok[len(tok)-1] == '|' {
+		return instance.NewSymbol(unescape(tok[1 : len(tok)-1])), true
 	}
-	//
-	// string
-	//
-	if r := regexp.MustCompile(`^"(.*)"$`).FindStringSubmatch(tok); len(r) >= 2 {
-		return instance.NewString([]rune(r[1])), nil
+	if symbolPattern.MatchString(tok) {
+		return instance.NewSymbol(strings.ToUpper(tok)), true
 	}
-	//
-	// symbol
-	//
-	if "nil" == tok {
-		return instance.Nil, nil
+	return nil, false
+}
+
+// unescape removes the backslashes which escape the next character in the
+// text of a string or a symbol between vertical bars.
+func unescape(s string) string {
+	var b strings.Builder
+	escaped := false
+	for _, r := range s {
+		if r == '\\' && !escaped {
+			escaped = true
+			continue
+		}
+		escaped = false
+		b.WriteRune(r)
 	}
-	str := `^(`
-	str += `[:&][a-zA-Z]+|`
-	str += `\|.*\||`
-	str += `\+|-|1\+|1-|`
-	str += `[a-zA-Z<>/*=?_!$%[\]^{}~][-a-zA-Z0-9+<>/*=?_!$%[\]^{}~]*|`
-	str += `)$`
-	if m, _ := regexp.MatchString(str, tok); m {
-		return instance.NewSymbol(strings.ToUpper(tok)), nil
-	}
-	return nil, instance.Create(env.NewEnvironment(nil, nil, nil, nil),
-		class.ParseError,
-		instance.NewSymbol("STRING"), instance.NewString([]rune(tok)),
-		instance.NewSymbol("EXPECTED-CLASS"), class.Object)
+	return b.String()
 }
 
-func parseMacro(tok string, t *tokenizer.Reader) (ilos.Instance, ilos.Instance) {
-	cdr, err := Parse(t)
-	if err != nil {
-		return nil, err
+// parseMacro reads what the macro character of tok stands for with its
+// function in the readtable of t.
+func parseMacro(t *tokenizer.Reader, tok tokenizer.Token) (ilos.Instance, ilos.Instance) {
+	runes := []rune(tok.Text)
+	m, ok := readtable(t).macros[runes[0]]
+	if !ok {
+		return nil, syntaxError(t, &Error{tok.Start, tok.Text, "unknown reader macro", ""}, class.Object)
 	}
-	n := tok
-	if m, _ := regexp.MatchString("#[[:digit:]]+[aA]", tok); m {
-		i := strings.IndexRune(strings.ToLower(tok), 'a')
-		var v int64 = 1
-		if i != 1 {
-			var err error
-			v, err = strconv.ParseInt(tok[1:i], 10, 64)
-			if err != nil {
-				return nil, instance.Create(env.NewEnvironment(nil, nil, nil, nil),
-					class.ParseError,
-					instance.NewSymbol("STRING"), instance.NewString([]rune(tok)),
-					instance.NewSymbol("EXPECTED-CLASS"), class.Integer)
+	if m.dispatch == nil {
+		return m.function(t, runes[0])
+	}
+	sub, n := runes[len(runes)-1], -1
+	if len(runes) > 2 {
+		var err error
+		if n, err = strconv.Atoi(string(runes[1 : len(runes)-1])); err != nil {
+			return nil, syntaxError(t, &Error{tok.Start, tok.Text, "malformed argument", "a decimal number"}, class.Integer)
+		}
+	}
+	f, ok := m.dispatch[unicode.ToUpper(sub)]
+	if !ok {
+		return nil, syntaxError(t, &Error{tok.Start, tok.Text, "unknown reader macro", ""}, class.Object)
+	}
+	return f(t, sub, n)
+}
+
+// parseCons reads the rest of a list whose ( is at open.
+func parseCons(t *tokenizer.Reader, open tokenizer.Position) (ilos.Instance, ilos.Instance) {
+	objs, tail := []ilos.Instance{}, instance.Nil
+	for {
+		obj, err := parse(t, -1)
+		if err == bod {
+			if tail, err = parseTail(t); err == nil {
+				break
 			}
+			tail = instance.Nil
+		}
+		if err == eop {
+			break
 		}
-		if int(v) == 1 {
-			return list2vector(cdr)
+		if err != nil && ilos.InstanceOf(class.EndOfStream, err) {
+			unclosed := syntaxError(t, &Error{open, "(", "unclosed parenthesis", ")"}, class.Cons)
+			r := recovering(t)
+			if r == nil {
+				return nil, unclosed
+			}
+			r.record(unclosed, open, "(")
+			break
+		}
+		if err != nil {
+			return nil, err
 		}
-		return list2array(int(v), cdr)
+		objs = append(objs, obj)
 	}
-	if tok == "#" {
-		return list2vector(cdr)
+	list := tail
+	for i := len(objs) - 1; i >= 0; i-- {
+		list = instance.NewCons(objs[i], list)
 	}
-	switch tok {
-	case "#'":
-		n = "FUNCTION"
-	case ",@":
-		n = "UNQUOTE-SPLICING"
-	case ",":
-		n = "UNQUOTE"
-	case "'":
-		n = "QUOTE"
-	case "`":
-		n = "QUASIQUOTE"
+	if len(objs) > 0 {
+		setLocation(list, Span{open, t.End()})
 	}
-	m := instance.NewSymbol(n)
-	return instance.NewCons(m, instance.NewCons(cdr, instance.Nil)), nil
+	return list, nil
 }
-func parseCons(t *tokenizer.Reader) (ilos.Instance, ilos.Instance) {
-	car, err := Parse(t)
-	if err == eop {
-		return instance.Nil, nil
-	}
-	if err == bod {
-		cdr, err := Parse(t)
-		if err != nil {
-			return nil, err
+
+// parseTail reads the rest of a list after its dot, which is the last cdr of
+// the list and a ).
+func parseTail(t *tokenizer.Reader) (ilos.Instance, ilos.Instance) {
+	r := recovering(t)
+	cdr, err := parse(t, -1)
+	if err == eop || err == bod {
+		missing := syntaxError(t, &Error{t.Position(), closeText(err), "missing object after the dot", "an object"}, class.Object)
+		if r == nil {
+			return nil, missing
 		}
-		if _, err := Parse(t); err != eop {
-			return nil, err
+		r.record(missing, t.Position(), "")
+		if err == bod {
+			return parseTail(t)
 		}
-		return cdr, nil
+		return instance.Nil, nil
 	}
 	if err != nil {
 		return nil, err
 	}
-	cdr, err := parseCons(t)
-	if err != nil {
-		return nil, err
+	extra := false
+	for {
+		_, err := parse(t, -1)
+		if err == eop {
+			return cdr, nil
+		}
+		if err != nil && err != bod {
+			return nil, err
+		}
+		if !extra {
+			err = syntaxError(t, &Error{t.Position(), "", "extra object after the dotted tail", ")"}, class.Cons)
+			if r == nil {
+				return nil, err
+			}
+			r.record(err, t.Position(), "")
+			extra = true
+		}
 	}
-	return instance.NewCons(car, cdr), nil
 }
 
-// Parse builds a internal expression from tokens
+// Parse builds a internal expression from tokens. The lists in the
+// expression are given their spans in the source, which Location returns. It
+// returns the located <parse-error> of the first syntax error in the source;
+// ParseAll reports them all. It returns <end-of-stream> only if the source
+// ends before the expression; a source which ends inside it is in error.
 func Parse(t *tokenizer.Reader) (ilos.Instance, ilos.Instance) {
-	tok, err := t.Next()
+	obj, err := parse(t, -1)
 	if err != nil {
-		return nil, instance.Create(env.NewEnvironment(nil, nil, nil, nil), class.EndOfStream)
+		return nil, closeError(t, err)
 	}
-	for (len(tok) > 2 && tok[:2] == "#|") || tok[:1] == ";" {
-		tok, err = t.Next()
-		if err != nil {
-			return nil, instance.Create(env.NewEnvironment(nil, nil, nil, nil), class.EndOfStream)
-		}
+	return obj, nil
+}
+
+// closeText returns the text of the token which made parse return err, eop or
+// bod.
+func closeText(err ilos.Instance) string {
+	if err == bod {
+		return "."
 	}
-	if tok == "(" {
-		cons, err := parseCons(t)
+	return ")"
+}
+
+// closeError returns a <parse-error> located at the last token of t if err
+// is eop or bod, which that token returned where no ) or . was expected, or
+// else err itself.
+func closeError(t *tokenizer.Reader, err ilos.Instance) ilos.Instance {
+	switch err {
+	case eop:
+		return syntaxError(t, &Error{t.Position(), ")", "unmatched parenthesis", "an object"}, class.Object)
+	case bod:
+		return syntaxError(t, &Error{t.Position(), ".", "unexpected dot", "an object"}, class.Object)
+	}
+	return err
+}
+
+// parse reads the next object from t. It returns eop, bod or eod instead of
+// an object at a ), a . or the macro character closing. If t is recovering,
+// it records the syntax errors in the objects it reads and skips them.
+func parse(t *tokenizer.Reader, closing rune) (ilos.Instance, ilos.Instance) {
+	r := recovering(t)
+	for {
+		tok, err := t.Next()
+		for err == nil && tok.Kind == tokenizer.Comment {
+			tok, err = t.Next()
+		}
 		if err != nil {
-			return nil, err
+			if err, ok := err.(*tokenizer.Error); ok {
+				cond := syntaxError(t, &Error{err.Position, err.Text, err.Message, ""}, class.Object)
+				if r != nil {
+					r.record(cond, err.Position, err.Text)
+					continue
+				}
+				return nil, cond
+			}
+			return nil, instance.CreateBuiltIn(class.EndOfStream)
 		}
-		return cons, err
-	}
-	if tok == ")" {
-		return nil, eop
+		switch tok.Kind {
+		case tokenizer.Punctuation:
+			switch tok.Text {
+			case "(":
+				return parseCons(t, tok.Start)
+			case ")":
+				return nil, eop
+			}
+			return nil, bod
+		case tokenizer.Macro:
+			if tok.Text == string(closing) {
+				return nil, eod
+			}
+			obj, err := parseMacro(t, tok)
+			if err != nil {
+				if ilos.InstanceOf(class.EndOfStream, err) {
+					unterminated := syntaxError(t, &Error{tok.Start, tok.Text, "unterminated", "an object"}, class.Object)
+					if r == nil {
+						return nil, unterminated
+					}
+					r.record(unterminated, tok.Start, tok.Text)
+					return nil, err
+				}
+				if r == nil {
+					return nil, locateError(err, tok.Start)
+				}
+				r.record(locateError(err, tok.Start), tok.Start, tok.Text)
+				continue
+			}
+			if obj == nil {
+				continue
+			}
+			setLocation(obj, Span{tok.Start, t.End()})
+			return obj, nil
+		}
+		obj, cond := parseToken(t, tok)
+		if cond != nil && r != nil {
+			r.record(cond, tok.Start, tok.Text)
+			continue
+		}
+		return obj, cond
 	}
-	if tok == "." {
-		return nil, bod
+}
+
+// parseToken returns the object which tok, a number, character, string or
+// symbol, stands for.
+func parseToken(t *tokenizer.Reader, tok tokenizer.Token) (ilos.Instance, ilos.Instance) {
+	atom, kind, expectedClass := parseSymbol, "symbol", class.Symbol
+	switch tok.Kind {
+	case tokenizer.Number:
+		atom, kind, expectedClass = parseNumber, "number", class.Number
+	case tokenizer.Character:
+		atom, kind, expectedClass = parseCharacter, "character", class.Character
+	case tokenizer.String:
+		atom, kind, expectedClass = parseString, "string", class.String
 	}
-	if mat, _ := regexp.MatchString("^(?:#'|,@?|'|`|#[[:digit:]]*[aA]|#)$", tok); mat {
-		m, err := parseMacro(tok, t)
-		if err != nil {
-			return nil, err
+	if obj, ok := atom(tok.Text); ok {
+		if f, ok := obj.(instance.Float); ok && !ieee(t) {
+			if cond := floatError(tok.Text, float64(f)); cond != nil {
+				return nil, locateError(cond, tok.Start)
+			}
 		}
-		return m, nil
-	}
-	atom, err1 := ParseAtom(tok)
-	if err1 != nil {
-		return nil, err1
+		return obj, nil
 	}
-	return atom, nil
+	return nil, syntaxError(t, &Error{tok.Start, tok.Text, "malformed " + kind, ""}, expectedClass)
 }
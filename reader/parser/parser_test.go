@@ -5,10 +5,14 @@
 package parser
 
 import (
+	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 
+	"github.com/ta2gch/iris/reader/tokenizer"
 	"github.com/ta2gch/iris/runtime/ilos"
+	"github.com/ta2gch/iris/runtime/ilos/class"
 	"github.com/ta2gch/iris/runtime/ilos/instance"
 )
 
@@ -145,12 +149,102 @@ func Test_parseAtom(t *testing.T) {
 			want:      instance.NewCharacter(' '),
 			wantErr:   false,
 		},
+		{
+			name:      "tab",
+			arguments: arguments{"#\\Tab"},
+			want:      instance.NewCharacter('\t'),
+			wantErr:   false,
+		},
+		{
+			name:      "nul",
+			arguments: arguments{"#\\nul"},
+			want:      instance.NewCharacter(0),
+			wantErr:   false,
+		},
+		{
+			name:      "code point",
+			arguments: arguments{"#\\U+3042"},
+			want:      instance.NewCharacter('あ'),
+			wantErr:   false,
+		},
+		{
+			name:      "non-ascii",
+			arguments: arguments{"#\\あ"},
+			want:      instance.NewCharacter('あ'),
+			wantErr:   false,
+		},
+		{
+			name:      "invalid code point",
+			arguments: arguments{"#\\U+110000"},
+			want:      nil,
+			wantErr:   true,
+		},
 		{
 			name:      "invalid character name",
 			arguments: arguments{"#\\foo"},
 			want:      nil,
 			wantErr:   true,
 		},
+		//
+		// Symbol
+		//
+		{
+			name:      "japanese",
+			arguments: arguments{"規則-1"},
+			want:      instance.NewSymbol("規則-1"),
+			wantErr:   false,
+		},
+		{
+			name:      "non-ascii case folding",
+			arguments: arguments{"größe"},
+			want:      instance.NewSymbol("GRÖßE"),
+			wantErr:   false,
+		},
+		{
+			name:      "keyword",
+			arguments: arguments{":foo-bar"},
+			want:      instance.NewSymbol(":FOO-BAR"),
+			wantErr:   false,
+		},
+		{
+			name:      "lambda list keyword",
+			arguments: arguments{"&rest"},
+			want:      instance.NewSymbol("&REST"),
+			wantErr:   false,
+		},
+		{
+			name:      "dot",
+			arguments: arguments{"a.b"},
+			want:      instance.NewSymbol("A.B"),
+			wantErr:   false,
+		},
+		{
+			name:      "at sign",
+			arguments: arguments{"a@b"},
+			want:      instance.NewSymbol("A@B"),
+			wantErr:   false,
+		},
+		{
+			name:      "inner colon",
+			arguments: arguments{"a:b"},
+			want:      instance.NewSymbol("A:B"),
+			wantErr:   false,
+		},
+		{
+			name:      "vertical bars",
+			arguments: arguments{`|a\|b|`},
+			want:      instance.NewSymbol("a|b"),
+			wantErr:   false,
+		},
+		//
+		// String
+		//
+		{
+			name:      "escapes",
+			arguments: arguments{`"\"日本\\"`},
+			want:      instance.NewString([]rune(`"日本\`)),
+			wantErr:   false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -165,3 +259,179 @@ func Test_parseAtom(t *testing.T) {
 		})
 	}
 }
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		source   string
+		want     string
+		location string
+	}{
+		{"(a #|b|# . (c))", "(A C)", ""},
+		{"; comment\n#2a((1 2) (3 4))", "#2A((1 2) (3 4))", ""},
+		{"(a\n  #3)", "", "2:3"},
+		{"(a . b c)", "", "1:8"},
+		{"(a\n  1.2.3)", "", "2:3"},
+		{"(a \"b", "", "1:4"},
+		{"(a ,@b `(c ,d) #'e #(1 2))", "(A (UNQUOTE-SPLICING B) (QUASIQUOTE (C (UNQUOTE D))) (FUNCTION E) #(1 2))", ""},
+		{`#{a 1 "b" (2 3)}`, `#{A 1 "b" (2 3)}`, ""},
+		{`#r"a\d+\"b"`, `#r"a\d+\"b"`, ""},
+		{"(a\n  #zq)", "", "2:3"},
+		{"#{a 1 b}", "", "1:1"},
+		{"#{a 1)", "", "1:6"},
+		{"(a\n  })", "", "2:3"},
+		{"a)", "A", ""},
+		{")", "", "1:1"},
+		{`#r"a("`, "", "1:3"},
+		{"#a(1 2) #2a(() ())", "#(1 2)", ""},
+		{"#2a(() ())", "#2A(() ())", ""},
+		{"'#ab", "", "1:2"},
+		{"#1a 5", "", "1:1"},
+		{"#2a 5", "", "1:1"},
+		{"#2a((1 2) (3))", "", "1:1"},
+		{"#a(1 . 2)", "", "1:1"},
+		{"(a\n  #a", "", "2:3"},
+		{"#a", "", "1:1"},
+		{"(+ 1", "", "1:1"},
+		{"'(a", "", "1:2"},
+		{"'", "", "1:1"},
+		{"(a (b c) #(1", "", "1:10"},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tokenizer.NewReader(strings.NewReader(tt.source)))
+		if tt.location == "" {
+			if err != nil || fmt.Sprint(got) != tt.want {
+				t.Errorf("Parse(%q) = %v, err = %v, want %v", tt.source, got, err, tt.want)
+			}
+			continue
+		}
+		if err == nil || !ilos.InstanceOf(class.ParseError, err) {
+			t.Errorf("Parse(%q) err = %v, want <parse-error>", tt.source, err)
+			continue
+		}
+		location, _ := err.(instance.Instance).GetSlotValue(instance.NewSymbol("IRIS.LOCATION"), class.SeriousCondition)
+		if got := fmt.Sprint(location); got != `"`+tt.location+`"` {
+			t.Errorf("Parse(%q) location = %v, want %v", tt.source, got, tt.location)
+		}
+	}
+}
+
+func TestParseFloatRange(t *testing.T) {
+	tests := []struct {
+		source string
+		want   ilos.Class
+	}{
+		{"1e400", class.FloatingPointOverflow},
+		{"-1e400", class.FloatingPointOverflow},
+		{"1e-310", class.FloatingPointUnderflow},
+		{"1e-400", class.FloatingPointUnderflow},
+		{"-0.5e-400", class.FloatingPointUnderflow},
+		{"0.0e-400", nil},
+		{"1e-300", nil},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tokenizer.NewReader(strings.NewReader(tt.source)))
+		if tt.want == nil {
+			if err != nil {
+				t.Errorf("Parse(%q) err = %v, want nil", tt.source, err)
+			}
+			continue
+		}
+		if err == nil || !ilos.InstanceOf(tt.want, err) {
+			t.Errorf("Parse(%q) err = %v, want %v", tt.source, err, tt.want)
+		}
+	}
+}
+
+func TestReadtable(t *testing.T) {
+	rt := NewReadtable()
+	rt.SetMacroCharacter('[', func(t *tokenizer.Reader, char rune) (ilos.Instance, ilos.Instance) {
+		list, err := ReadDelimitedList(t, ']')
+		if err != nil {
+			return nil, err
+		}
+		return instance.NewCons(instance.NewSymbol("VECTOR"), list), nil
+	}, false)
+	rt.SetMacroCharacter(']', readUnmatched, false)
+	rt.SetMacroCharacter('!', func(t *tokenizer.Reader, char rune) (ilos.Instance, ilos.Instance) {
+		return nil, nil
+	}, false)
+	rt.MakeDispatchMacroCharacter('$', true)
+	rt.SetDispatchMacroCharacter('$', 'n', func(t *tokenizer.Reader, sub rune, n int) (ilos.Instance, ilos.Instance) {
+		return instance.NewInteger(n), nil
+	})
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"[a [1]b]", "(VECTOR A (VECTOR 1) B)"},
+		{"(! a ! b!)", "(A B)"},
+		{"($12N $N a$b)", "(12 -1 A$B)"},
+		{"'#{}", "(QUOTE #{})"},
+	}
+	for _, tt := range tests {
+		r := tokenizer.NewReader(strings.NewReader(tt.source))
+		r.SetSyntax(rt)
+		if got, err := Parse(r); err != nil || fmt.Sprint(got) != tt.want {
+			t.Errorf("Parse(%q) = %v, err = %v, want %v", tt.source, got, err, tt.want)
+		}
+	}
+	if got, err := Parse(tokenizer.NewReader(strings.NewReader("[a]"))); err != nil || fmt.Sprint(got) != "[A]" {
+		t.Errorf("Parse(%q) with the standard readtable = %v, err = %v, want [A]", "[a]", got, err)
+	}
+}
+
+func TestParseAll(t *testing.T) {
+	tests := []struct {
+		source string
+		forms  []string
+		errors []string
+	}{
+		{
+			"(a 1.2.3 b) c",
+			[]string{"(A B)", "C"},
+			[]string{`1:4: malformed number: "1.2.3"`},
+		},
+		{
+			"a)\n(b",
+			[]string{"A", "(B)"},
+			[]string{`1:2: unmatched parenthesis: ")", expected an object`, `2:1: unclosed parenthesis: "(", expected )`},
+		},
+		{
+			"(a (b\n c",
+			[]string{"(A (B C))"},
+			[]string{`1:4: unclosed parenthesis: "(", expected )`, `1:1: unclosed parenthesis: "(", expected )`},
+		},
+		{
+			"(a . b c d) (. ) (x .)",
+			[]string{"(A . B)", "NIL", "(X)"},
+			[]string{`1:8: extra object after the dotted tail, expected )`, `1:16: missing object after the dot: ")", expected an object`, `1:22: missing object after the dot: ")", expected an object`},
+		},
+		{
+			`'#zq #{a} #r"(" #{a 1)} '`,
+			[]string{"(QUOTE Q)", "#{A 1}"},
+			[]string{`1:2: unknown reader macro: "#z"`, `1:6: odd number of keys and values: "#{"`, "1:13: error parsing regexp: missing closing ): `(`: \"\\\"(\\\"\"", `1:22: unmatched parenthesis: ")", expected an object`, `1:25: unterminated: "'", expected an object`},
+		},
+		{
+			"(a \"b",
+			[]string{"(A)"},
+			[]string{`1:4: unterminated: "\"b"`, `1:1: unclosed parenthesis: "(", expected )`},
+		},
+	}
+	for _, tt := range tests {
+		forms, errs := ParseAll(tokenizer.NewReader(strings.NewReader(tt.source)))
+		got := []string{}
+		for _, form := range forms {
+			got = append(got, fmt.Sprint(form))
+		}
+		if !reflect.DeepEqual(got, tt.forms) {
+			t.Errorf("ParseAll(%q) forms = %q, want %q", tt.source, got, tt.forms)
+		}
+		got = []string{}
+		for _, err := range errs {
+			got = append(got, err.Error())
+		}
+		if !reflect.DeepEqual(got, tt.errors) {
+			t.Errorf("ParseAll(%q) errors = %q, want %q", tt.source, got, tt.errors)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/ta2gch/iris/reader/tokenizer"
+	"github.com/ta2gch/iris/runtime/ilos"
+	"github.com/ta2gch/iris/runtime/ilos/class"
+	"github.com/ta2gch/iris/runtime/ilos/instance"
+)
+
+// MacroFunction reads the object which the macro character char stands for
+// from t, which is just after char. It returns nil and no error if it reads
+// nothing, as a comment does.
+type MacroFunction func(t *tokenizer.Reader, char rune) (ilos.Instance, ilos.Instance)
+
+// DispatchFunction reads the object which a dispatching macro character and
+// its sub-character sub stand for from t, which is just after sub. n is the
+// decimal argument between the two characters, or -1 if there is none.
+type DispatchFunction func(t *tokenizer.Reader, sub rune, n int) (ilos.Instance, ilos.Instance)
+
+type macroCharacter struct {
+	function    MacroFunction
+	terminating bool
+	dispatch    map[rune]DispatchFunction // nil if the character does not dispatch
+}
+
+// Readtable maps the macro characters to the functions which read what they
+// stand for. Parse reads with the readtable which is the syntax of its
+// Reader, or with the standard readtable if the Reader has none.
+type Readtable struct {
+	macros map[rune]*macroCharacter
+}
+
+var standard *Readtable
+
+func init() {
+	standard = &Readtable{map[rune]*macroCharacter{}}
+	standard.SetMacroCharacter('\'', readPrefix("QUOTE"), false)
+	standard.SetMacroCharacter('`', readPrefix("QUASIQUOTE"), false)
+	standard.SetMacroCharacter(',', readUnquote, false)
+	standard.SetMacroCharacter('}', readUnmatched, false)
+	standard.MakeDispatchMacroCharacter('#', true)
+	standard.SetDispatchMacroCharacter('#', '\'', func(t *tokenizer.Reader, sub rune, n int) (ilos.Instance, ilos.Instance) {
+		return readPrefix("FUNCTION")(t, sub)
+	})
+	standard.SetDispatchMacroCharacter('#', '(', readVector)
+	standard.SetDispatchMacroCharacter('#', 'a', readArray)
+	standard.SetDispatchMacroCharacter('#', '{', readHashTable)
+	standard.SetDispatchMacroCharacter('#', 'r', readRegexp)
+}
+
+// NewReadtable returns a copy of the standard readtable.
+func NewReadtable() *Readtable {
+	return standard.Copy()
+}
+
+// Copy returns a readtable with the same macro characters as rt, which the
+// changes to either do not affect.
+func (rt *Readtable) Copy() *Readtable {
+	c := &Readtable{map[rune]*macroCharacter{}}
+	for char, m := range rt.macros {
+		n := *m
+		if m.dispatch != nil {
+			n.dispatch = map[rune]DispatchFunction{}
+			for sub, f := range m.dispatch {
+				n.dispatch[sub] = f
+			}
+		}
+		c.macros[char] = &n
+	}
+	return c
+}
+
+// Macro reports whether ru is a macro character of rt, and if so whether it
+// terminates a number or a symbol in which it appears.
+func (rt *Readtable) Macro(ru rune) (bool, bool) {
+	m, ok := rt.macros[ru]
+	return ok, ok && m.terminating
+}
+
+// Dispatching reports whether ru is a dispatching macro character of rt.
+func (rt *Readtable) Dispatching(ru rune) bool {
+	m, ok := rt.macros[ru]
+	return ok && m.dispatch != nil
+}
+
+// SetMacroCharacter makes char a macro character which f reads. A
+// non-terminating macro character may appear in a symbol, as # does.
+func (rt *Readtable) SetMacroCharacter(char rune, f MacroFunction, nonTerminating bool) {
+	rt.macros[char] = &macroCharacter{f, !nonTerminating, nil}
+}
+
+// MakeDispatchMacroCharacter makes char a dispatching macro character without
+// sub-characters.
+func (rt *Readtable) MakeDispatchMacroCharacter(char rune, nonTerminating bool) {
+	rt.macros[char] = &macroCharacter{nil, !nonTerminating, map[rune]DispatchFunction{}}
+}
+
+// SetDispatchMacroCharacter makes f read the sub-character sub of the
+// dispatching macro character char. Sub-characters are case insensitive. It
+// reports false if char does not dispatch.
+func (rt *Readtable) SetDispatchMacroCharacter(char, sub rune, f DispatchFunction) bool {
+	if !rt.Dispatching(char) {
+		return false
+	}
+	rt.macros[char].dispatch[unicode.ToUpper(sub)] = f
+	return true
+}
+
+// Table returns rt. A type which embeds a *Readtable is thus a syntax with
+// which Parse reads by that readtable.
+func (rt *Readtable) Table() *Readtable {
+	return rt
+}
+
+// readtable returns the readtable t reads with.
+func readtable(t *tokenizer.Reader) *Readtable {
+	return readtableOf(t.Syntax())
+}
+
+// ReadDelimitedList reads objects from t up to the character char and returns
+// them in a list. char must be ) or a terminating macro character.
+func ReadDelimitedList(t *tokenizer.Reader, char rune) (ilos.Instance, ilos.Instance) {
+	objs := []ilos.Instance{}
+	for {
+		obj, err := parse(t, char)
+		if err == eod || err == eop && char == ')' {
+			list := instance.Nil
+			for i := len(objs) - 1; i >= 0; i-- {
+				list = instance.NewCons(objs[i], list)
+			}
+			return list, nil
+		}
+		if err != nil {
+			err = closeError(t, err)
+			if r := recovering(t); r != nil && !ilos.InstanceOf(class.EndOfStream, err) {
+				r.record(err, t.Position(), "")
+				continue
+			}
+			return nil, err
+		}
+		objs = append(objs, obj)
+	}
+}
+
+func readPrefix(name string) MacroFunction {
+	return func(t *tokenizer.Reader, char rune) (ilos.Instance, ilos.Instance) {
+		obj, err := Parse(t)
+		if err != nil {
+			return nil, err
+		}
+		return instance.NewCons(instance.NewSymbol(name), instance.NewCons(obj, instance.Nil)), nil
+	}
+}
+
+func readUnquote(t *tokenizer.Reader, char rune) (ilos.Instance, ilos.Instance) {
+	if ru, _, err := t.PeekRune(); err == nil && ru == '@' {
+		t.ReadRune()
+		return readPrefix("UNQUOTE-SPLICING")(t, char)
+	}
+	return readPrefix("UNQUOTE")(t, char)
+}
+
+func readUnmatched(t *tokenizer.Reader, char rune) (ilos.Instance, ilos.Instance) {
+	return nil, syntaxError(t, &Error{Text: string(char), Message: "unmatched " + string(char), Expected: "an object"}, class.Object)
+}
+
+func readVector(t *tokenizer.Reader, sub rune, n int) (ilos.Instance, ilos.Instance) {
+	list, err := ReadDelimitedList(t, ')')
+	if err != nil {
+		return nil, err
+	}
+	vector, _ := list2vector(list)
+	return vector, nil
+}
+
+// readArray reads an array of rank n, #nA followed by its elements in proper
+// lists nested n levels deep. #A is a vector, as #1A is.
+func readArray(t *tokenizer.Reader, sub rune, n int) (ilos.Instance, ilos.Instance) {
+	list, err := Parse(t)
+	if err != nil {
+		if ilos.InstanceOf(class.EndOfStream, err) {
+			return nil, syntaxError(t, &Error{Message: "unterminated", Expected: "the elements of an array"}, class.List)
+		}
+		return nil, err
+	}
+	if n < 0 || n == 1 {
+		if vector, ok := list2vector(list); ok {
+			return vector, nil
+		}
+		return nil, syntaxError(t, &Error{Text: fmt.Sprint(list), Message: "malformed vector", Expected: "a proper list"}, class.List)
+	}
+	if array, ok := list2array(n, list); ok {
+		return array, nil
+	}
+	expected := fmt.Sprintf("proper lists of the same lengths nested %v levels deep", n)
+	return nil, syntaxError(t, &Error{Text: fmt.Sprint(list), Message: "malformed array", Expected: expected}, class.List)
+}
+
+// readHashTable reads the keys and values of a hash table, #{key value ...}.
+func readHashTable(t *tokenizer.Reader, sub rune, n int) (ilos.Instance, ilos.Instance) {
+	list, err := ReadDelimitedList(t, '}')
+	if err != nil {
+		return nil, err
+	}
+	objs := list.(instance.List).Slice()
+	if len(objs)%2 != 0 {
+		return nil, syntaxError(t, &Error{Message: "odd number of keys and values"}, class.HashTable)
+	}
+	h := instance.NewHashTable()
+	for i := 0; i < len(objs); i += 2 {
+		h.(*instance.HashTable).Set(objs[i], objs[i+1])
+	}
+	return h, nil
+}
+
+// readRegexp reads a regular expression written as a string, #r"pattern".
+// The backslashes of the string are part of the pattern, except the ones
+// which escape a double quote.
+func readRegexp(t *tokenizer.Reader, sub rune, n int) (ilos.Instance, ilos.Instance) {
+	tok, err := t.Next()
+	if err, ok := err.(*tokenizer.Error); ok {
+		return nil, syntaxError(t, &Error{err.Position, err.Text, err.Message, ""}, class.Object)
+	}
+	if err != nil {
+		return nil, instance.CreateBuiltIn(class.EndOfStream)
+	}
+	if tok.Kind != tokenizer.String {
+		return nil, syntaxError(t, &Error{tok.Start, tok.Text, "malformed regular expression", "a string"}, class.String)
+	}
+	pattern := strings.Replace(tok.Text[1:len(tok.Text)-1], `\"`, `"`, -1)
+	r, e := regexp.Compile(pattern)
+	if e != nil {
+		return nil, syntaxError(t, &Error{tok.Start, tok.Text, e.Error(), ""}, class.Regexp)
+	}
+	return instance.NewRegexp(r), nil
+}
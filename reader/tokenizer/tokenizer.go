@@ -1,30 +1,168 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+// Package tokenizer splits ISLisp source into tokens.
+//
+// The lexer reads every rune once and decides the kind of a token from its
+// first runes, so it runs in time linear in the size of the source.
 package tokenizer
 
 import (
 	"bufio"
+	"fmt"
 	"io"
-	"regexp"
 	"strings"
+	"unicode"
 )
 
-// Reader interface type is the interface
-// for reading string with every token
-// Reader is like bufio.Reader but has PeekRune
-// which returns a rune without advancing pointer
+// Position is a location in the source of a Reader. Lines and columns count
+// from 1, and columns count runes.
+type Position struct {
+	File   string
+	Line   int
+	Column int
+}
+
+func (p Position) String() string {
+	if p.File == "" {
+		return fmt.Sprintf("%v:%v", p.Line, p.Column)
+	}
+	return fmt.Sprintf("%v:%v:%v", p.File, p.Line, p.Column)
+}
+
+// Kind is the kind of a token.
+type Kind int
+
+const (
+	Number      Kind = iota // 12, -3/4, 1.5e3, #b101, #o17 or #x1F
+	String                  // "text", with its escapes as written
+	Symbol                  // name, :keyword, &rest or |name|
+	Character               // #\a, #\space or #\U+3042
+	Punctuation             // (, ) or .
+	Comment                 // ; to the end of the line, or #| |#
+	Macro                   // a macro character such as ' or `, or a dispatching one with its argument and sub-character, such as #' or #2a
+)
+
+var kinds = [...]string{"NUMBER", "STRING", "SYMBOL", "CHARACTER", "PUNCTUATION", "COMMENT", "MACRO"}
+
+func (k Kind) String() string {
+	return kinds[k]
+}
+
+// Token is a token and the span of its text in the source.
+type Token struct {
+	Kind  Kind
+	Text  string
+	Start Position
+	End   Position
+}
+
+// Error is a malformed token.
+type Error struct {
+	Text     string
+	Position Position
+	Message  string
+}
+
+func (err *Error) Error() string {
+	return fmt.Sprintf("%v: %v: %q", err.Position, err.Message, err.Text)
+}
+
+// Syntax tells the lexer which characters of a readtable are macro
+// characters. The lexer reads a macro character as a Macro token of its own,
+// and a dispatching macro character together with its decimal argument and
+// its sub-character.
+type Syntax interface {
+	// Macro reports whether ru is a macro character, and if so whether it
+	// terminates a number or a symbol in which it appears.
+	Macro(ru rune) (ok, terminating bool)
+	// Dispatching reports whether ru is a dispatching macro character.
+	Dispatching(ru rune) bool
+}
+
+// standardSyntax is the syntax of the standard readtable, which the lexer
+// uses when a Reader has none.
+type standardSyntax struct{}
+
+func (standardSyntax) Macro(ru rune) (bool, bool) {
+	switch ru {
+	case '\'', '`', ',', '}':
+		return true, true
+	case '#':
+		return true, false
+	}
+	return false, false
+}
+
+func (standardSyntax) Dispatching(ru rune) bool {
+	return ru == '#'
+}
+
+// Reader splits the runes of a source into tokens under a Syntax, keeping
+// the position of every token. It reads ahead by one rune at most.
 type Reader struct {
-	err error
-	ru  rune
-	sz  int
-	rr  *bufio.Reader
+	err   error
+	ru    rune
+	sz    int
+	rr    *bufio.Reader
+	pos   Position // of the next rune
+	start Position // of the last token
+	syn   Syntax
 }
 
-// NewReader creates interal reader from io.RuneReader
+// NewReader creates interal reader from io.RuneReader. If r has a Name
+// method, as *os.File does, the name is the file of the positions.
 func NewReader(r io.Reader) *Reader {
 	b := new(Reader)
 	b.rr = bufio.NewReader(r)
+	b.pos = Position{Line: 1, Column: 1}
+	if f, ok := r.(interface{ Name() string }); ok {
+		b.pos.File = f.Name()
+	}
 	return b
 }
 
+// Position returns the position of the first rune of the token last returned
+// by Next.
+func (r *Reader) Position() Position {
+	return r.start
+}
+
+// End returns the position just after the token last returned by Next.
+func (r *Reader) End() Position {
+	return r.pos
+}
+
+// Syntax returns the syntax of the macro characters the reader lexes with, or
+// nil if it lexes with the standard one.
+func (r *Reader) Syntax() Syntax {
+	return r.syn
+}
+
+// SetSyntax makes the reader lex the macro characters of s. A nil s restores
+// the standard syntax.
+func (r *Reader) SetSyntax(s Syntax) {
+	r.syn = s
+}
+
+func (r *Reader) syntax() Syntax {
+	if r.syn == nil {
+		return standardSyntax{}
+	}
+	return r.syn
+}
+
+// advance moves the position past ru.
+func (r *Reader) advance(ru rune) {
+	if ru == '\n' {
+		r.pos.Line++
+		r.pos.Column = 1
+	} else {
+		r.pos.Column++
+	}
+}
+
 // PeekRune returns a rune without advancing pointer
 func (r *Reader) PeekRune() (rune, int, error) {
 	if r.ru == 0 {
@@ -35,99 +173,190 @@ func (r *Reader) PeekRune() (rune, int, error) {
 
 // ReadRune returns a rune with advancing pointer
 func (r *Reader) ReadRune() (rune, int, error) {
-	if r.ru == 0 {
-		r.ru, r.sz, r.err = r.rr.ReadRune()
+	ru, sz, err := r.PeekRune()
+	if err == nil {
+		r.advance(ru)
 	}
-	ru := r.ru
-	sz := r.sz
-	err := r.err
 	r.ru, r.sz, r.err = r.rr.ReadRune()
 	return ru, sz, err
 }
 
 func (r *Reader) Read(b []byte) (int, error) {
-	ru := r.ru
-	sz := r.sz
-	err := r.err
-	r.ru, r.sz, r.err = r.rr.ReadRune()
+	ru, sz, err := r.ReadRune()
 	copy(b, []byte(string([]rune{ru})))
 	return sz, err
 }
 
-var str = `^1\+$|^1-$|` +
-	`^[-+]?[[:digit:]]+\.[[:digit:]]+$|` +
-	`^[-+]?[[:digit:]]+(?:\.[[:digit:]]+)?[eE][-+]?[[:digit:]]+$|` +
-	`^[-+]?[[:digit:]]+$|` +
-	`^#[bB][-+]?[01]+$|` +
-	`^#[oO][-+]?[0-7]+$|` +
-	`^#[xX][-+]?[[:xdigit:]]+$|` +
-	`^#\\[[:alpha:]]+$|` +
-	`^#\\[[:graph:]]$|` +
-	`^"(?:\\\\|\\"|[^\\"])*"$|` +
-	`^[:&][a-zA-Z]+$|` +
-	`^\+$|^-$|^[a-zA-Z<>/*=?_!$%[\]^{}~][-a-zA-Z0-9+<>/*=?_!$%[\]^{}~]*$|` +
-	`^\|(?:\\\\|\\\||[^\\|])*\|$|` +
-	`^[.()]$|` +
-	"^;.*?\n|$" +
-	`^#\|.*?\|#$|` +
-	"^#'$|^,@?$|^'$|^`$|^#[[:digit:]]*[aA]$|^#$" // TODO: hangs at #ab or #3
-var re = regexp.MustCompile(str)
-
-// ReadToken returns error or string as token
-func (r *Reader) Next() (string, error) {
-	for {
-		ru, _, err := r.PeekRune()
-		if ru == 0 || err != nil {
-			return "", io.EOF
+// peek returns the next rune, or -1 at the end of the source.
+func (r *Reader) peek() rune {
+	ru, _, err := r.PeekRune()
+	if ru == 0 || err != nil {
+		return -1
+	}
+	return ru
+}
+
+// delimiter reports whether ru ends a number or a symbol.
+func (r *Reader) delimiter(ru rune) bool {
+	if ru == -1 || unicode.IsSpace(ru) || strings.ContainsRune(`()";`, ru) {
+		return true
+	}
+	_, terminating := r.syntax().Macro(ru)
+	return terminating
+}
+
+// Next returns the next token. It returns io.EOF at the end of the source and
+// an *Error if the token is malformed.
+func (r *Reader) Next() (Token, error) {
+	for ru := r.peek(); ru != -1 && unicode.IsSpace(ru); ru = r.peek() {
+		r.ReadRune()
+	}
+	r.start = r.pos
+	ru := r.peek()
+	if ru == -1 {
+		return Token{}, io.EOF
+	}
+	var b strings.Builder
+	b.WriteRune(ru)
+	r.ReadRune()
+	kind := Symbol
+	switch ru {
+	case '(', ')':
+		kind = Punctuation
+	case ';':
+		kind = Comment
+		for ru := r.peek(); ru != -1 && ru != '\n'; ru = r.peek() {
+			b.WriteRune(ru)
+			r.ReadRune()
 		}
-		if !strings.ContainsRune(" \t\n\r", ru) {
+	case '"':
+		kind = String
+		if err := r.quoted(&b, '"'); err != nil {
+			return Token{}, err
+		}
+	case '|':
+		if err := r.quoted(&b, '|'); err != nil {
+			return Token{}, err
+		}
+	default:
+		if ok, _ := r.syntax().Macro(ru); ok {
+			var err error
+			if kind, err = r.macro(&b, ru); err != nil {
+				return Token{}, err
+			}
 			break
 		}
+		r.constituents(&b)
+		text := b.String()
+		switch {
+		case text == ".":
+			kind = Punctuation
+		case text == "1+" || text == "1-":
+		case '0' <= ru && ru <= '9':
+			kind = Number
+		case (ru == '+' || ru == '-') && len(text) > 1 && '0' <= text[1] && text[1] <= '9':
+			kind = Number
+		}
+	}
+	return Token{kind, b.String(), r.start, r.pos}, nil
+}
+
+// constituents reads the runes up to the next delimiter into b.
+func (r *Reader) constituents(b *strings.Builder) {
+	for ru := r.peek(); !r.delimiter(ru); ru = r.peek() {
+		b.WriteRune(ru)
 		r.ReadRune()
 	}
-	buf := ""
-	mat := false
-	num := false
-	shp := false
+}
+
+// quoted reads the runes up to the unescaped closing rune into b.
+func (r *Reader) quoted(b *strings.Builder, closing rune) error {
+	escaped := false
 	for {
-		ru, _, err := r.PeekRune()
-		if ru == 0 || err != nil {
-			if mat {
-				return buf, nil
-			}
-			return "", io.EOF
+		ru := r.peek()
+		if ru == -1 {
+			return r.error(b.String(), "unterminated")
 		}
-		if buf == "" && strings.ContainsRune("1234567890", ru) {
-			num = true
+		b.WriteRune(ru)
+		r.ReadRune()
+		switch {
+		case escaped:
+			escaped = false
+		case ru == '\\':
+			escaped = true
+		case ru == closing:
+			return nil
 		}
-		if buf == "" && '#' == ru {
-			shp = true
+	}
+}
+
+// macro reads the rest of the token of the macro character ru into b and
+// returns its kind. The character #, when it dispatches, also begins the
+// tokens of characters, block comments and numbers in binary, octal and
+// hexadecimal, which are not reader macros.
+func (r *Reader) macro(b *strings.Builder, ru rune) (Kind, error) {
+	if !r.syntax().Dispatching(ru) {
+		return Macro, nil
+	}
+	next := r.peek()
+	switch {
+	case ru == '#' && next == '\\':
+		b.WriteRune(next)
+		r.ReadRune()
+		if next = r.peek(); next == -1 {
+			return 0, r.error(b.String(), "unterminated")
 		}
-		if mat && !re.MatchString(buf+string(ru)) {
-			if num && strings.ContainsRune(".Ee", ru) {
-				buf += string(ru)
-				r.ReadRune()
-				continue
-			}
-			if shp {
-				if len(buf) == 1 && ru == '\\' {
-					buf += string(ru)
-					r.ReadRune()
-					continue
-				}
-				if matched, _ := regexp.MatchString(`^#[0123456789]*$`, buf+string(ru)); matched {
-					buf += string(ru)
-					r.ReadRune()
-					continue
-				}
-			}
-			break
+		b.WriteRune(next)
+		r.ReadRune()
+		if !unicode.IsSpace(next) {
+			r.constituents(b)
 		}
-		buf += string(ru)
-		if re.MatchString(buf) {
-			mat = true
+		return Character, nil
+	case ru == '#' && next == '|':
+		b.WriteRune(next)
+		r.ReadRune()
+		return Comment, r.blockComment(b)
+	case ru == '#' && strings.ContainsRune("bBoOxX", next):
+		r.constituents(b)
+		return Number, nil
+	}
+	for ; '0' <= next && next <= '9'; next = r.peek() {
+		b.WriteRune(next)
+		r.ReadRune()
+	}
+	if next == -1 {
+		return 0, r.error(b.String(), "unterminated")
+	}
+	b.WriteRune(next)
+	r.ReadRune()
+	return Macro, nil
+}
+
+// blockComment reads the rest of a #| |# comment, which may nest, into b.
+func (r *Reader) blockComment(b *strings.Builder) error {
+	depth := 1
+	for depth > 0 {
+		ru := r.peek()
+		if ru == -1 {
+			return r.error(b.String(), "unterminated")
+		}
+		b.WriteRune(ru)
+		r.ReadRune()
+		next := r.peek()
+		switch {
+		case ru == '|' && next == '#':
+			depth--
+		case ru == '#' && next == '|':
+			depth++
+		default:
+			continue
 		}
+		b.WriteRune(next)
 		r.ReadRune()
 	}
-	return buf, nil
+	return nil
+}
+
+func (r *Reader) error(text, message string) error {
+	return &Error{text, r.start, message}
 }
@@ -5,6 +5,7 @@
 package tokenizer
 
 import (
+	"io"
 	"strings"
 	"testing"
 
@@ -42,9 +43,104 @@ func TestTokenizer_Next(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			got, _ := tokenizer.Next()
-			if got != tt.want {
+			if got.Text != tt.want {
 				t.Errorf("Tokenizer.Next() got = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
+
+func TestTokenizer_NextUnicode(t *testing.T) {
+	tokenizer := NewReader(strings.NewReader(`(規則 #\あ #\U+3042 #\tab "日本\"語" :キー)`))
+	for _, want := range []string{"(", "規則", `#\あ`, `#\U+3042`, `#\tab`, `"日本\"語"`, ":キー", ")"} {
+		if got, _ := tokenizer.Next(); got.Text != want {
+			t.Errorf("Tokenizer.Next() got = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestTokenizer_Position(t *testing.T) {
+	tokenizer := NewReader(strings.NewReader("(a\n  \"b\nc\" 日本 d)"))
+	for _, want := range []string{"1:1", "1:2", "2:3", "3:4", "3:7"} {
+		tokenizer.Next()
+		if got := tokenizer.Position().String(); got != want {
+			t.Errorf("Tokenizer.Position() got = %v, want %v", got, want)
+		}
+	}
+	if got := tokenizer.End().String(); got != "3:8" {
+		t.Errorf("Tokenizer.End() got = %v, want 3:8", got)
+	}
+}
+
+func TestTokenizer_NextKind(t *testing.T) {
+	tokenizer := NewReader(strings.NewReader("(1+ -3/4 1.5e3 #x1F |a b| #\\( #\\space #\\ 1 . ,x #'f #2a() #(1) ; c\n #| a #| b |# |# \"s\")"))
+	tests := []struct {
+		kind Kind
+		text string
+	}{
+		{Punctuation, "("},
+		{Symbol, "1+"},
+		{Number, "-3/4"},
+		{Number, "1.5e3"},
+		{Number, "#x1F"},
+		{Symbol, "|a b|"},
+		{Character, "#\\("},
+		{Character, "#\\space"},
+		{Character, "#\\ "},
+		{Number, "1"},
+		{Punctuation, "."},
+		{Macro, ","},
+		{Symbol, "x"},
+		{Macro, "#'"},
+		{Symbol, "f"},
+		{Macro, "#2a"},
+		{Punctuation, "("},
+		{Punctuation, ")"},
+		{Macro, "#("},
+		{Number, "1"},
+		{Punctuation, ")"},
+		{Comment, "; c"},
+		{Comment, "#| a #| b |# |#"},
+		{String, `"s"`},
+		{Punctuation, ")"},
+	}
+	for _, tt := range tests {
+		got, err := tokenizer.Next()
+		if err != nil || got.Kind != tt.kind || got.Text != tt.text {
+			t.Errorf("Tokenizer.Next() got = %v %q, err = %v, want %v %q", got.Kind, got.Text, err, tt.kind, tt.text)
+		}
+	}
+	if _, err := tokenizer.Next(); err != io.EOF {
+		t.Errorf("Tokenizer.Next() err = %v, want EOF", err)
+	}
+}
+
+func TestTokenizer_NextError(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"#ab\n  #12", `2:3: unterminated: "#12"`},
+		{"x \"abc", `1:3: unterminated: "\"abc"`},
+		{"|abc", `1:1: unterminated: "|abc"`},
+		{"#| a #| b |#", `1:1: unterminated: "#| a #| b |#"`},
+	}
+	for _, tt := range tests {
+		tokenizer := NewReader(strings.NewReader(tt.source))
+		var err error
+		for err == nil {
+			_, err = tokenizer.Next()
+		}
+		if got := err.Error(); got != tt.want {
+			t.Errorf("Tokenizer.Next() err = %v, want %v", got, tt.want)
+		}
+	}
+}
+
+func TestTokenizer_NextLong(t *testing.T) {
+	text := `"` + strings.Repeat("a", 1<<20) + `"`
+	tokenizer := NewReader(strings.NewReader(text + " x"))
+	if got, err := tokenizer.Next(); err != nil || got.Text != text {
+		t.Errorf("Tokenizer.Next() got %v runes, err = %v, want %v runes", len(got.Text), err, len(text))
+	}
+}
@@ -57,7 +57,7 @@ func CreateArray(e env.Environment, dimensions ilos.Instance, initialElement ...
 		if err != nil {
 			return nil, err
 		}
-		if err := ensure(e, class.Integer, elt); err != nil {
+		if err := ensureFixnum(e, elt); err != nil {
 			return nil, err
 		}
 	}
@@ -130,7 +130,7 @@ func Aref(e env.Environment, basicArray ilos.Instance, dimensions ...ilos.Instan
 	if err := ensure(e, class.BasicArray, basicArray); err != nil {
 		return nil, err
 	}
-	if err := ensure(e, class.Integer, dimensions...); err != nil {
+	if err := ensureFixnum(e, dimensions...); err != nil {
 		return nil, err
 	}
 	switch {
@@ -164,7 +164,7 @@ func Garef(e env.Environment, generalArray ilos.Instance, dimensions ...ilos.Ins
 	if err := ensure(e, class.GeneralArrayStar, generalArray); err != nil {
 		return nil, err
 	}
-	if err := ensure(e, class.Integer, dimensions...); err != nil {
+	if err := ensureFixnum(e, dimensions...); err != nil {
 		return nil, err
 	}
 	if len(dimensions) == 0 {
@@ -188,7 +188,7 @@ func SetAref(e env.Environment, obj, basicArray ilos.Instance, dimensions ...ilo
 	if err := ensure(e, class.BasicArray, basicArray); err != nil {
 		return nil, err
 	}
-	if err := ensure(e, class.Integer, dimensions...); err != nil {
+	if err := ensureFixnum(e, dimensions...); err != nil {
 		return nil, err
 	}
 	switch {
@@ -227,7 +227,7 @@ func SetGaref(e env.Environment, obj, generalArray ilos.Instance, dimensions ...
 	if err := ensure(e, class.GeneralArrayStar, generalArray); err != nil {
 		return nil, err
 	}
-	if err := ensure(e, class.Integer, dimensions...); err != nil {
+	if err := ensureFixnum(e, dimensions...); err != nil {
 		return nil, err
 	}
 	if len(dimensions) == 0 {
@@ -263,6 +263,9 @@ func ArrayDimensions(e env.Environment, basicArray ilos.Instance) (ilos.Instance
 		dimensions := []ilos.Instance{}
 		for array.Vector != nil {
 			dimensions = append(dimensions, instance.NewInteger(len(array.Vector)))
+			if len(array.Vector) == 0 {
+				break
+			}
 			array = array.Vector[0]
 		}
 		return List(e, dimensions...)
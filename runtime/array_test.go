@@ -83,6 +83,11 @@ func TestAref(t *testing.T) {
 
 func TestSetAref(t *testing.T) {
 	execTests(t, CreateArray, []test{
+		{
+			exp:     `(defglobal array1 (create-array '(3 3 3) 0))`,
+			want:    `'array1`,
+			wantErr: false,
+		},
 		{
 			exp:     `(setf (aref array1 0 1 2) 3.15)`,
 			want:    `3.15`,
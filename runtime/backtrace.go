@@ -0,0 +1,133 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package runtime
+
+import (
+	"fmt"
+
+	"github.com/ta2gch/iris/reader/parser"
+	"github.com/ta2gch/iris/runtime/env"
+	"github.com/ta2gch/iris/runtime/ilos"
+	"github.com/ta2gch/iris/runtime/ilos/class"
+	"github.com/ta2gch/iris/runtime/ilos/instance"
+)
+
+// Frame is a call of a function in a backtrace.
+type Frame struct {
+	Function  ilos.Instance // the name of the function, or the function if it has none
+	Arguments []ilos.Instance
+	Location  string // the source location of the call, or "" if it is unknown
+	call      *env.CallFrame
+}
+
+func (f Frame) String() string {
+	arguments := ilos.Instance(instance.Nil)
+	for i := len(f.Arguments) - 1; i >= 0; i-- {
+		arguments = instance.NewCons(f.Arguments[i], arguments)
+	}
+	call := instance.NewCons(f.Function, arguments)
+	if f.Location == "" {
+		return fmt.Sprint(call)
+	}
+	return fmt.Sprintf("%v at %v", call, f.Location)
+}
+
+// callFrames is the innermost call frame where a condition was signaled,
+// which the condition keeps.
+type callFrames struct {
+	*env.CallFrame
+}
+
+func (callFrames) Class() ilos.Class {
+	return class.Object
+}
+
+func (callFrames) String() string {
+	return "#<BACKTRACE>"
+}
+
+// functionName returns the symbol by which the call form calls function, or
+// function itself if the call is of a lambda form or a computed function.
+func functionName(form, function ilos.Instance) ilos.Instance {
+	if cons, ok := form.(*instance.Cons); ok && ilos.InstanceOf(class.Symbol, cons.Car) {
+		return cons.Car
+	}
+	return function
+}
+
+// frames returns the frames from f to the outermost, innermost first.
+func frames(f *env.CallFrame) []Frame {
+	fs := []Frame{}
+	for ; f != nil; f = f.Parent {
+		location := ""
+		if span, ok := parser.Location(f.Form); ok {
+			location = span.String()
+		}
+		fs = append(fs, Frame{f.Function, f.Arguments, location, f})
+	}
+	return fs
+}
+
+// recordBacktrace keeps the call frames of e in condition, unless it has
+// those of an earlier signal.
+func recordBacktrace(e env.Environment, condition ilos.Instance) {
+	c := condition.(instance.Instance)
+	if _, ok := c.GetSlotValue(instance.NewSymbol("IRIS.BACKTRACE"), class.SeriousCondition); ok || e.Frame == nil {
+		return
+	}
+	c.SetSlotValue(instance.NewSymbol("IRIS.BACKTRACE"), callFrames{e.Frame}, class.SeriousCondition)
+}
+
+// ConditionBacktrace returns the calls in progress where condition was
+// signaled, innermost first.
+func ConditionBacktrace(condition ilos.Instance) []Frame {
+	c, ok := condition.(instance.Instance)
+	if !ok {
+		return []Frame{}
+	}
+	f, ok := c.GetSlotValue(instance.NewSymbol("IRIS.BACKTRACE"), class.SeriousCondition)
+	if !ok {
+		return []Frame{}
+	}
+	return frames(f.(callFrames).CallFrame)
+}
+
+// Backtrace returns a list of the calls in progress, or of those where
+// condition was signaled, innermost first. Each call is a list
+// (function arguments location), where function is the name of the function
+// called, arguments the list of its arguments and location the source
+// location of the call as a string, or nil if it is unknown.
+func Backtrace(e env.Environment, condition ...ilos.Instance) (ilos.Instance, ilos.Instance) {
+	if len(condition) > 1 {
+		return SignalCondition(e, instance.NewArityError(e), Nil)
+	}
+	var fs []Frame
+	if len(condition) == 1 {
+		if err := ensure(e, class.SeriousCondition, condition[0]); err != nil {
+			return nil, err
+		}
+		fs = ConditionBacktrace(condition[0])
+	} else if e.Frame != nil {
+		// The call of backtrace itself is not shown
+		fs = frames(e.Frame.Parent)
+	}
+	calls := []ilos.Instance{}
+	for _, f := range fs {
+		arguments, err := List(e, f.Arguments...)
+		if err != nil {
+			return nil, err
+		}
+		location := Nil
+		if f.Location != "" {
+			location = instance.NewString([]rune(f.Location))
+		}
+		call, err := List(e, f.Function, arguments, location)
+		if err != nil {
+			return nil, err
+		}
+		calls = append(calls, call)
+	}
+	return List(e, calls...)
+}
@@ -0,0 +1,83 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package runtime
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBacktrace(t *testing.T) {
+	execTests(t, Backtrace, []test{
+		{
+			exp:     `(defun inner (x) (car x))`,
+			want:    `'inner`,
+			wantErr: false,
+		},
+		{
+			exp:     `(defun outer (x) (+ 1 (inner x)))`,
+			want:    `'outer`,
+			wantErr: false,
+		},
+		{
+			exp:     `(catch 'c (with-handler (lambda (c) (throw 'c (mapcar #'car (backtrace c)))) (outer 5)))`,
+			want:    `'(car inner outer)`,
+			wantErr: false,
+		},
+		{
+			exp:     `(catch 'c (with-handler (lambda (c) (throw 'c (mapcar (lambda (f) (car (cdr f))) (backtrace c)))) (outer 5)))`,
+			want:    `'((5) (5) (5))`,
+			wantErr: false,
+		},
+		{
+			exp:     `(catch 'c (with-handler (lambda (c) (throw 'c (stringp (elt (car (backtrace c)) 2)))) (outer 5)))`,
+			want:    `t`,
+			wantErr: false,
+		},
+		{
+			exp:     `(defun where () (mapcar #'car (backtrace)))`,
+			want:    `'where`,
+			wantErr: false,
+		},
+		{
+			exp:     `(defun caller () (list (where)))`,
+			want:    `'caller`,
+			wantErr: false,
+		},
+		{
+			exp:     `(caller)`,
+			want:    `'((where caller))`,
+			wantErr: false,
+		},
+		{
+			exp:     `(backtrace)`,
+			want:    `nil`,
+			wantErr: false,
+		},
+		{
+			exp:     `(backtrace 1)`,
+			want:    `nil`,
+			wantErr: true,
+		},
+	})
+}
+
+func TestConditionBacktrace(t *testing.T) {
+	for _, backend := range []Backend{BackendCompiler, BackendVM} {
+		i := New(Options{Backend: backend})
+		_, err := i.EvalString("(defun rule (x) (car x))\n(defun rules (x) (list (rule x)))\n(rules 1)")
+		if err == nil {
+			t.Fatalf("%v: no error", backend)
+		}
+		calls := []string{}
+		for _, f := range ConditionBacktrace(err) {
+			calls = append(calls, f.String())
+		}
+		want := "(CAR 1) at 1:17; (RULE 1) at 2:24; (RULES 1) at 3:1"
+		if got := strings.Join(calls, "; "); got != want {
+			t.Errorf("%v: ConditionBacktrace() = %v, want %v", backend, got, want)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package runtime
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ta2gch/iris/runtime/env"
+	"github.com/ta2gch/iris/runtime/ilos"
+	"github.com/ta2gch/iris/runtime/ilos/instance"
+)
+
+// Capability selects what a script may do outside of the interpreter.
+type Capability int
+
+const (
+	// CapabilityFull gives scripts the access of the host process.
+	CapabilityFull Capability = iota
+	// CapabilityReadOnly lets scripts open files for input only, and only
+	// below Options.Root.
+	CapabilityReadOnly
+	// CapabilityPure denies every file access.
+	CapabilityPure
+)
+
+var errAccessDenied = errors.New("access denied")
+
+// fileSystem opens the named file like os.OpenFile, or returns
+// errAccessDenied if the capability of the interpreter does not allow it.
+type fileSystem func(name string, flag int) (*os.File, error)
+
+func fullAccess(name string, flag int) (*os.File, error) {
+	return os.OpenFile(name, flag, 0666)
+}
+
+func noAccess(name string, flag int) (*os.File, error) {
+	return nil, errAccessDenied
+}
+
+// readOnlyAccess returns a fileSystem that opens files for input below root.
+// Relative names are resolved from root. A name is checked against root both
+// as written and after its symbolic links are followed, so no link can lead a
+// script out of root. Any failure for a name outside root is reported as
+// errAccessDenied, so that scripts cannot probe which host files exist.
+func readOnlyAccess(root string) fileSystem {
+	return func(name string, flag int) (*os.File, error) {
+		if flag != os.O_RDONLY || root == "" {
+			return nil, errAccessDenied
+		}
+		if !filepath.IsAbs(name) {
+			name = filepath.Join(root, name)
+		}
+		if !within(filepath.Clean(root), filepath.Clean(name)) {
+			return nil, errAccessDenied
+		}
+		base, err := filepath.EvalSymlinks(root)
+		if err != nil {
+			return nil, errAccessDenied
+		}
+		path, err := filepath.EvalSymlinks(name)
+		if err != nil {
+			if resolved, ok := resolvePrefix(name); !ok || !within(base, resolved) {
+				return nil, errAccessDenied
+			}
+			return nil, err
+		}
+		if !within(base, path) {
+			return nil, errAccessDenied
+		}
+		return os.Open(path)
+	}
+}
+
+// within reports whether path names root or a file below it.
+func within(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// resolvePrefix follows the symbolic links of the longest existing directory
+// which contains path, and joins the rest of path to it. It reports false if
+// no directory containing path can be resolved.
+func resolvePrefix(path string) (string, bool) {
+	dir, rest := filepath.Dir(path), filepath.Base(path)
+	for {
+		if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+			return filepath.Join(resolved, rest), true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir, rest = parent, filepath.Join(filepath.Base(dir), rest)
+	}
+}
+
+// restrict replaces the file stream builtins of e with ones that open files
+// through fs. A denied access signals an <access-denied> condition.
+func restrict(e env.Environment, fs fileSystem) {
+	open := func(flag int) interface{} {
+		return func(e env.Environment, filename ilos.Instance, elementClass ...ilos.Instance) (ilos.Instance, ilos.Instance) {
+			s, _, err := openFile(e, fs, filename, flag)
+			return s, err
+		}
+	}
+	with := func(flag int) interface{} {
+		return func(e env.Environment, fileSpec ilos.Instance, forms ...ilos.Instance) (ilos.Instance, ilos.Instance) {
+			return withOpenFile(e, fs, flag, fileSpec, forms...)
+		}
+	}
+	defun(e, "OPEN-INPUT-FILE", open(os.O_RDONLY))
+	defun(e, "OPEN-OUTPUT-FILE", open(os.O_WRONLY|os.O_CREATE|os.O_TRUNC))
+	defun(e, "OPEN-IO-FILE", open(os.O_RDWR|os.O_CREATE))
+	for name, flag := range map[string]int{
+		"WITH-OPEN-INPUT-FILE":  os.O_RDONLY,
+		"WITH-OPEN-OUTPUT-FILE": os.O_WRONLY | os.O_CREATE | os.O_TRUNC,
+		"WITH-OPEN-IO-FILE":     os.O_RDWR | os.O_CREATE,
+	} {
+		symbol := instance.NewSymbol(name)
+		e.Special.Define(symbol, instance.NewFunction(symbol, with(flag)))
+	}
+}
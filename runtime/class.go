@@ -33,7 +33,7 @@ func Subclassp(e env.Environment, class1, class2 ilos.Class) (ilos.Instance, ilo
 }
 
 func Class(e env.Environment, className ilos.Instance) (ilos.Class, ilos.Instance) {
-	if v, ok := e.Class[:1].Get(className); ok {
+	if v, ok := e.Class.Global().Get(className); ok {
 		return v.(ilos.Class), nil
 	}
 	_, err := SignalCondition(e, instance.NewUndefinedClass(e, className), Nil)
@@ -121,7 +121,7 @@ func Defclass(e env.Environment, className, scNames, slotSpecs ilos.Instance, cl
 		}
 	}
 	classObject := instance.NewStandardClass(className, supers, slots, initforms, initargs, metaclass, abstractp)
-	e.Class[:1].Define(className, classObject)
+	e.Class.Global().Define(className, classObject)
 	for _, slotSpec := range slotSpecs.(instance.List).Slice() {
 		if ilos.InstanceOf(class.Symbol, slotSpec) {
 			continue
@@ -242,7 +242,7 @@ func Defmethod(e env.Environment, arguments ...ilos.Instance) (ilos.Instance, il
 		if ilos.InstanceOf(class.Symbol, pp) {
 			classList = append(classList, class.Object)
 		} else {
-			class, ok := e.Class[:1].Get(pp.(instance.List).Nth(1))
+			class, ok := e.Class.Global().Get(pp.(instance.List).Nth(1))
 			if !ok {
 				return SignalCondition(e, instance.NewUndefinedClass(e, pp.(instance.List).Nth(1)), Nil)
 
@@ -254,7 +254,7 @@ func Defmethod(e env.Environment, arguments ...ilos.Instance) (ilos.Instance, il
 	if err != nil {
 		return nil, err
 	}
-	gen, ok := e.Function[:1].Get(name)
+	gen, ok := e.Function.Global().Get(name)
 	if !ok {
 		return SignalCondition(e, instance.NewUndefinedFunction(e, name), Nil)
 	}
@@ -273,7 +273,7 @@ func Defgeneric(e env.Environment, funcSpec, lambdaList ilos.Instance, optionsOr
 		case instance.NewSymbol(":METHOD-COMBINATION"):
 			methodCombination = optionOrMethodDesc.(instance.List).Nth(1)
 		case instance.NewSymbol(":GENERIC-FUNCTION-CLASS"):
-			class, ok := e.Class[:1].Get(optionOrMethodDesc.(instance.List).Nth(1))
+			class, ok := e.Class.Global().Get(optionOrMethodDesc.(instance.List).Nth(1))
 			if !ok {
 				return SignalCondition(e, instance.NewUndefinedClass(e, optionOrMethodDesc.(instance.List).Nth(1)), Nil)
 			}
@@ -282,10 +282,12 @@ func Defgeneric(e env.Environment, funcSpec, lambdaList ilos.Instance, optionsOr
 			forms = append(forms, instance.NewCons(instance.NewSymbol("DEFMETHOD"), optionOrMethodDesc.(instance.List).NthCdr(1)))
 		}
 	}
-	e.Function[:1].Define(
-		instance.NewSymbol(
-			fmt.Sprint(funcSpec),
-		),
+	name := funcSpec
+	if !ilos.InstanceOf(class.Symbol, funcSpec) {
+		name = instance.NewSymbol(fmt.Sprint(funcSpec)) // (SETF NAME)
+	}
+	e.Function.Global().Define(
+		name,
 		instance.NewGenericFunction(
 			funcSpec,
 			lambdaList,
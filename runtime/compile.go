@@ -0,0 +1,390 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package runtime
+
+import (
+	"github.com/ta2gch/iris/runtime/env"
+	"github.com/ta2gch/iris/runtime/ilos"
+	"github.com/ta2gch/iris/runtime/ilos/class"
+	"github.com/ta2gch/iris/runtime/ilos/instance"
+)
+
+// code is a compiled form. Running it in an environment evaluates the form
+// there. Code compiled in tail position may return a *tailCall.
+type code func(e env.Environment) (ilos.Instance, ilos.Instance)
+
+// scope is the list of variables bound lexically around a form being
+// compiled.
+type scope struct {
+	variable ilos.Instance
+	next     *scope
+}
+
+func (s *scope) bind(variables ...ilos.Instance) *scope {
+	for _, v := range variables {
+		s = &scope{v, s}
+	}
+	return s
+}
+
+func (s *scope) bound(variable ilos.Instance) bool {
+	for ; s != nil; s = s.next {
+		if s.variable == variable {
+			return true
+		}
+	}
+	return false
+}
+
+// compilers compile the special forms that are worth it. The others are
+// compiled to a call of the special form, which evaluates its arguments as
+// Eval does.
+var compilers map[ilos.Instance]func(e env.Environment, s *scope, arguments []ilos.Instance, tail bool) (code, bool)
+
+func init() {
+	compilers = map[ilos.Instance]func(env.Environment, *scope, []ilos.Instance, bool) (code, bool){
+		instance.NewSymbol("QUOTE"):  compileQuote,
+		instance.NewSymbol("IF"):     compileIf,
+		instance.NewSymbol("PROGN"):  compileProgn,
+		instance.NewSymbol("LET"):    compileLet,
+		instance.NewSymbol("LET*"):   compileLetStar,
+		instance.NewSymbol("SETQ"):   compileSetq,
+		instance.NewSymbol("AND"):    compileAnd,
+		instance.NewSymbol("OR"):     compileOr,
+		instance.NewSymbol("LAMBDA"): compileLambda,
+	}
+}
+
+// compile converts obj to code once, so that running it does not dispatch on
+// the form again. Macro calls are expanded with the macros defined in e at
+// compile time. Malformed forms are compiled to their evaluation by Eval, so
+// they signal the same errors at run time.
+func compile(e env.Environment, s *scope, obj ilos.Instance, tail bool) code {
+	switch {
+	case obj == Nil:
+		return constant(Nil)
+	case ilos.InstanceOf(class.Symbol, obj):
+		return compileVariable(e, s, obj)
+	case ilos.InstanceOf(class.Cons, obj):
+		return located(obj, compileCons(e, s, obj.(*instance.Cons), tail))
+	}
+	return constant(obj)
+}
+
+// evalCompiled evaluates obj like Eval after compiling it.
+func evalCompiled(e env.Environment, obj ilos.Instance) (ilos.Instance, ilos.Instance) {
+	return force(compile(e, nil, obj, true)(e))
+}
+
+// compileBody compiles forms to code that evaluates them in order like
+// progn.
+func compileBody(e env.Environment, s *scope, forms []ilos.Instance, tail bool) code {
+	if len(forms) == 0 {
+		return constant(Nil)
+	}
+	codes := make([]code, len(forms))
+	for i, form := range forms {
+		codes[i] = compile(e, s, form, tail && i == len(forms)-1)
+	}
+	if len(codes) == 1 {
+		return codes[0]
+	}
+	return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+		for _, c := range codes[:len(codes)-1] {
+			if _, err := c(e); err != nil {
+				return nil, err
+			}
+		}
+		return codes[len(codes)-1](e)
+	}
+}
+
+func constant(obj ilos.Instance) code {
+	return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+		return obj, nil
+	}
+}
+
+// interpreted is the code of obj evaluated by Eval.
+func interpreted(obj ilos.Instance, tail bool) code {
+	if tail {
+		return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+			return evalTail(e, obj)
+		}
+	}
+	return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+		return Eval(e, obj)
+	}
+}
+
+// compileVariable compiles a reference to obj. A variable bound around the
+// reference is found by the address of its binding, which is looked up once
+// and kept while the binding there is still of obj.
+func compileVariable(e env.Environment, s *scope, obj ilos.Instance) code {
+	if !s.bound(obj) {
+		if _, ok := e.Variable.Get(obj); !ok {
+			if val, ok := e.Constant.Get(obj); ok {
+				return constant(val)
+			}
+		}
+		return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+			return evalVariable(e, obj)
+		}
+	}
+	var address env.Address
+	cached := false
+	return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+		if cached {
+			if val, ok := e.Variable.Ref(address, obj); ok {
+				return val, nil
+			}
+		}
+		if address, cached = e.Variable.Lookup(obj); cached {
+			val, _ := e.Variable.Ref(address, obj)
+			return val, nil
+		}
+		return evalVariable(e, obj)
+	}
+}
+
+func compileCons(e env.Environment, s *scope, obj *instance.Cons, tail bool) code {
+	arguments, ok := instance.ProperList(obj.Cdr)
+	if !ok {
+		return interpreted(obj, tail)
+	}
+	if f, ok := obj.Car.(*instance.Cons); ok && f.Car == instance.NewSymbol("LAMBDA") {
+		return compileCall(e, s, obj, compile(e, s, f, false), arguments, tail)
+	}
+	if spl, ok := e.Special.Get(obj.Car); ok {
+		if compiler, ok := compilers[obj.Car]; ok {
+			if c, ok := compiler(e, s, arguments, tail); ok {
+				return c
+			}
+		}
+		return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+			ret, err := spl.(instance.Applicable).Apply(e.NewLexical(), arguments...)
+			if tail {
+				return ret, err
+			}
+			return force(ret, err)
+		}
+	}
+	if mac, ok := e.Macro.Get(obj.Car); ok {
+		expansion, err := mac.(instance.Applicable).Apply(e.NewDynamic(), arguments...)
+		if err != nil {
+			return interpreted(obj, tail)
+		}
+		return compile(e, s, expansion, tail)
+	}
+	return compileCall(e, s, obj, nil, arguments, tail)
+}
+
+// compileCall compiles the call obj of the function computed by function, or
+// of the function named by the car of obj if function is nil.
+func compileCall(e env.Environment, s *scope, obj *instance.Cons, function code, arguments []ilos.Instance, tail bool) code {
+	codes := make([]code, len(arguments))
+	for i, argument := range arguments {
+		codes[i] = compile(e, s, argument, false)
+	}
+	return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+		if err := checkBudget(e); err != nil {
+			return nil, err
+		}
+		var fun ilos.Instance
+		if function != nil {
+			f, err := function(e)
+			if err != nil {
+				return nil, err
+			}
+			fun = f
+		} else if f, ok := e.Function.Get(obj.Car); ok {
+			fun = f
+		} else {
+			// The function may be a macro defined after compilation
+			return interpreted(obj, tail)(e)
+		}
+		argv := make([]ilos.Instance, len(codes))
+		for i, c := range codes {
+			a, err := c(e)
+			if err != nil {
+				return nil, err
+			}
+			argv[i] = a
+		}
+		l, ok := fun.(*lambda)
+		callee := e.NewDynamic()
+		callee.Call(&e, functionName(obj, fun), argv, obj, ok && tail)
+		if ok && tail {
+			return &tailCall{l, callee, argv}, nil
+		}
+		return fun.(instance.Applicable).Apply(callee, argv...)
+	}
+}
+
+func compileQuote(e env.Environment, s *scope, arguments []ilos.Instance, tail bool) (code, bool) {
+	if len(arguments) != 1 {
+		return nil, false
+	}
+	return constant(arguments[0]), true
+}
+
+func compileIf(e env.Environment, s *scope, arguments []ilos.Instance, tail bool) (code, bool) {
+	if len(arguments) < 2 || len(arguments) > 3 {
+		return nil, false
+	}
+	test := compile(e, s, arguments[0], false)
+	then := compile(e, s, arguments[1], tail)
+	otherwise := compileBody(e, s, arguments[2:], tail)
+	return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+		tf, err := test(e)
+		if err != nil {
+			return nil, err
+		}
+		if tf != Nil {
+			return then(e)
+		}
+		return otherwise(e)
+	}, true
+}
+
+func compileProgn(e env.Environment, s *scope, arguments []ilos.Instance, tail bool) (code, bool) {
+	return compileBody(e, s, arguments, tail), true
+}
+
+func compileLet(e env.Environment, s *scope, arguments []ilos.Instance, tail bool) (code, bool) {
+	if len(arguments) < 1 {
+		return nil, false
+	}
+	variables, forms, ok := instance.Bindings(arguments[0])
+	if !ok {
+		return nil, false
+	}
+	codes := make([]code, len(forms))
+	for i, form := range forms {
+		codes[i] = compile(e, s, form, false)
+	}
+	body := compileBody(e, s.bind(variables...), arguments[1:], tail)
+	return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+		e = e.NewLexical()
+		values := make([]ilos.Instance, len(codes))
+		for i, c := range codes {
+			v, err := c(e)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = v
+		}
+		for i, v := range variables {
+			if !e.Variable.Define(v, values[i]) {
+				return SignalCondition(e, instance.NewImmutableBinding(e), Nil)
+			}
+		}
+		return body(e)
+	}, true
+}
+
+func compileLetStar(e env.Environment, s *scope, arguments []ilos.Instance, tail bool) (code, bool) {
+	if len(arguments) < 1 {
+		return nil, false
+	}
+	variables, forms, ok := instance.Bindings(arguments[0])
+	if !ok {
+		return nil, false
+	}
+	codes := make([]code, len(forms))
+	for i, form := range forms {
+		codes[i] = compile(e, s.bind(variables[:i]...), form, false)
+	}
+	body := compileBody(e, s.bind(variables...), arguments[1:], tail)
+	return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+		e = e.NewLexical()
+		for i, c := range codes {
+			v, err := c(e)
+			if err != nil {
+				return nil, err
+			}
+			if !e.Variable.Define(variables[i], v) {
+				return SignalCondition(e, instance.NewImmutableBinding(e), Nil)
+			}
+		}
+		return body(e)
+	}, true
+}
+
+func compileSetq(e env.Environment, s *scope, arguments []ilos.Instance, tail bool) (code, bool) {
+	if len(arguments) != 2 || !ilos.InstanceOf(class.Symbol, arguments[0]) {
+		return nil, false
+	}
+	variable := arguments[0]
+	form := compile(e, s, arguments[1], false)
+	return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+		ret, err := form(e)
+		if err != nil {
+			return nil, err
+		}
+		if e.Variable.Set(variable, ret) {
+			return ret, nil
+		}
+		return SignalCondition(e, instance.NewUndefinedVariable(e, variable), Nil)
+	}, true
+}
+
+func compileAnd(e env.Environment, s *scope, arguments []ilos.Instance, tail bool) (code, bool) {
+	if len(arguments) == 0 {
+		return constant(T), true
+	}
+	codes := make([]code, len(arguments))
+	for i, argument := range arguments {
+		codes[i] = compile(e, s, argument, false)
+	}
+	return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+		var ret ilos.Instance
+		for _, c := range codes {
+			var err ilos.Instance
+			if ret, err = c(e); err != nil {
+				return nil, err
+			}
+			if ret == Nil {
+				return Nil, nil
+			}
+		}
+		return ret, nil
+	}, true
+}
+
+func compileOr(e env.Environment, s *scope, arguments []ilos.Instance, tail bool) (code, bool) {
+	codes := make([]code, len(arguments))
+	for i, argument := range arguments {
+		codes[i] = compile(e, s, argument, false)
+	}
+	return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+		for _, c := range codes {
+			ret, err := c(e)
+			if err != nil {
+				return nil, err
+			}
+			if ret != Nil {
+				return ret, nil
+			}
+		}
+		return Nil, nil
+	}, true
+}
+
+func compileLambda(e env.Environment, s *scope, arguments []ilos.Instance, tail bool) (code, bool) {
+	if len(arguments) < 1 {
+		return nil, false
+	}
+	parameters, variadic, ok := instance.ParseLambdaList(arguments[0])
+	if !ok {
+		return nil, false
+	}
+	forms := arguments[1:]
+	body := compileBody(e, s.bind(parameters...), forms, true)
+	name := instance.NewSymbol("ANONYMOUS-FUNCTION")
+	return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+		return &lambda{name, e.NewLexical(), parameters, variadic, forms, body}, nil
+	}, true
+}
@@ -0,0 +1,126 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package runtime
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ta2gch/iris/runtime/env"
+	"github.com/ta2gch/iris/runtime/ilos"
+	"github.com/ta2gch/iris/runtime/ilos/class"
+	"github.com/ta2gch/iris/runtime/ilos/instance"
+)
+
+func TestCompile(t *testing.T) {
+	tests := []test{
+		{
+			exp:     "(defmacro twice (x) `(* 2 ,x))",
+			want:    `'twice`,
+			wantErr: false,
+		},
+		{
+			exp:     `(defun quadruple (x) (twice (twice x)))`,
+			want:    `'quadruple`,
+			wantErr: false,
+		},
+		{
+			exp:     `(quadruple 3)`,
+			want:    `12`,
+			wantErr: false,
+		},
+		{
+			exp:     `(defun later () (not-yet-defined 1))`,
+			want:    `'later`,
+			wantErr: false,
+		},
+		{
+			exp:     `(defun not-yet-defined (x) (+ x 1))`,
+			want:    `'not-yet-defined`,
+			wantErr: false,
+		},
+		{
+			exp:     `(later)`,
+			want:    `2`,
+			wantErr: false,
+		},
+		{
+			exp:     `(let* ((x 1) (y (+ x 1))) (and (or nil y) (list x y)))`,
+			want:    `'(1 2)`,
+			wantErr: false,
+		},
+		{
+			exp: `
+			(let ((n 0))
+			  (let ((inc (lambda () (setq n (+ n 1)))))
+			    (funcall inc)
+			    (funcall inc)
+			    n))`,
+			want:    `2`,
+			wantErr: false,
+		},
+		{
+			exp:     `((lambda (x &rest y) (cons x y)) 1 2 3)`,
+			want:    `'(1 2 3)`,
+			wantErr: false,
+		},
+		{
+			exp: `
+			(defun adder (n)
+			  (lambda (x) (if (< x 0) (let ((n 0)) (+ n x)) (+ n x))))`,
+			want:    `'adder`,
+			wantErr: false,
+		},
+		{
+			exp:     `(let ((f (adder 10)) (g (adder 20))) (list (funcall f 1) (funcall g -1) (funcall g 2) (funcall f -2)))`,
+			want:    `'(11 -1 22 -2)`,
+			wantErr: false,
+		},
+		{
+			exp:     `(let ((x 1)) (flet ((f () x)) (let ((x 2)) (list x (f)))))`,
+			want:    `'(2 1)`,
+			wantErr: false,
+		},
+		{
+			exp:     `(if nil (let x) 'skipped)`,
+			want:    `'skipped`,
+			wantErr: false,
+		},
+		{
+			exp:     `(let x)`,
+			want:    `nil`,
+			wantErr: true,
+		},
+		{
+			exp:     `(setq undefined-variable 1)`,
+			want:    `nil`,
+			wantErr: true,
+		},
+	}
+	execTests(t, Eval, tests)
+}
+
+func TestImmutableBinding(t *testing.T) {
+	forms := []string{
+		`(list 1 (let ((x 1) (x 2)) x) 3)`,
+		`(list 1 (let* ((x 1) (x 2)) x) 3)`,
+		`(list 1 (flet ((f () 1) (f () 2)) (f)) 3)`,
+		`(list 1 (labels ((f () 1) (f () 2)) (f)) 3)`,
+		`(list 1 (dynamic-let ((x 1) (x 2)) x) 3)`,
+		`(list 1 (tagbody a a) 3)`,
+	}
+	eachEvaluator(t, func(t *testing.T, interpreter *Interpreter) {
+		// The handler continues the condition, which only Go code can do
+		interpreter.Environment.Handler = instance.NewFunction(instance.NewSymbol("HANDLER"), func(e env.Environment, condition ilos.Instance) (ilos.Instance, ilos.Instance) {
+			return nil, instance.Create(e, class.Continue, instance.NewSymbol("IRIS.OBJECT"), instance.NewInteger(5))
+		})
+		for _, form := range forms {
+			got, err := interpreter.EvalString(form)
+			if err != nil || fmt.Sprint(got) != "(1 5 3)" {
+				t.Errorf("%v = %v, %v, want (1 5 3)", form, got, err)
+			}
+		}
+	})
+}
@@ -16,7 +16,17 @@ func SignalCondition(e env.Environment, condition, continuable ilos.Instance) (i
 		return nil, err
 	}
 	condition.(instance.Instance).SetSlotValue(instance.NewSymbol("IRIS.CONTINUABLE"), continuable, class.SeriousCondition)
+	recordBacktrace(e, condition)
+	recordEnvironment(e, condition)
+	if len(e.Restart) > 0 {
+		rs, _ := List(e, e.Restart...)
+		condition.(instance.Instance).SetSlotValue(instance.NewSymbol("IRIS.RESTARTS"), rs, class.SeriousCondition)
+	}
 	_, c := e.Handler.(instance.Applicable).Apply(e, condition)
+	if c == nil {
+		// Every handler declined the condition
+		return nil, condition
+	}
 	if ilos.InstanceOf(class.Continue, c) {
 		o, _ := c.(instance.Instance).GetSlotValue(instance.NewSymbol("IRIS.OBJECT"), class.Continue)
 		return o, nil
@@ -29,7 +39,7 @@ func Cerror(e env.Environment, continueString, errorString ilos.Instance, objs .
 	if err != nil {
 		return nil, err
 	}
-	condition := instance.Create(e, class.SimpleError, instance.NewSymbol("FORMAT-STRING"), errorString, instance.NewSymbol("FORAMT-OBJECTS"), arguments)
+	condition := instance.NewSimpleError(e, errorString, arguments)
 	ss, err := CreateStringOutputStream(e)
 	if err != nil {
 		return nil, err
@@ -44,16 +54,26 @@ func Cerror(e env.Environment, continueString, errorString ilos.Instance, objs .
 	return SignalCondition(e, condition, continuable)
 }
 
-func Error(e env.Environment, continueString, errorString ilos.Instance, objs ...ilos.Instance) (ilos.Instance, ilos.Instance) {
+func Error(e env.Environment, errorString ilos.Instance, objs ...ilos.Instance) (ilos.Instance, ilos.Instance) {
 	arguments, err := List(e, objs...)
 	if err != nil {
 		return nil, err
 	}
-	condition := instance.Create(e, class.SimpleError, instance.NewSymbol("FORMAT-STRING"), errorString, instance.NewSymbol("FORAMT-OBJECTS"), arguments)
+	condition := instance.NewSimpleError(e, errorString, arguments)
 	return SignalCondition(e, condition, Nil)
 }
 
+// IgnoreError evaluates the forms like progn, but returns nil if an error is
+// signaled in them. The handlers outside of ignore-errors are not called for
+// errors, only for the other conditions.
 func IgnoreError(e env.Environment, forms ...ilos.Instance) (ilos.Instance, ilos.Instance) {
+	outer := e.Handler
+	e.Handler = instance.NewFunction(instance.NewSymbol("IGNORE-ERRORS"), func(e env.Environment, condition ilos.Instance) (ilos.Instance, ilos.Instance) {
+		if ilos.InstanceOf(class.Error, condition) {
+			return nil, condition
+		}
+		return outer.(instance.Applicable).Apply(e, condition)
+	})
 	ret, err := Progn(e, forms...)
 	if err != nil && ilos.InstanceOf(class.Error, err) {
 		return Nil, nil
@@ -61,8 +81,79 @@ func IgnoreError(e env.Environment, forms ...ilos.Instance) (ilos.Instance, ilos
 	return ret, err
 }
 
+// ReportCondition writes a message which describes condition to stream.
 func ReportCondition(e env.Environment, condition, stream ilos.Instance) (ilos.Instance, ilos.Instance) {
-	return Format(e, e.StandardOutput, instance.NewString([]rune("~A")), condition)
+	if err := ensure(e, class.SeriousCondition, condition); err != nil {
+		return nil, err
+	}
+	if ok, _ := OutputStreamP(e, stream); ok == Nil {
+		return SignalCondition(e, instance.NewDomainError(e, stream, class.Stream), Nil)
+	}
+	message, err := conditionMessage(e, condition)
+	if err != nil {
+		return nil, err
+	}
+	return Format(e, stream, instance.NewString([]rune("~A")), message)
+}
+
+// conditionMessage returns a message which describes condition, made from its
+// class and slots.
+func conditionMessage(e env.Environment, condition ilos.Instance) (ilos.Instance, ilos.Instance) {
+	slot := func(c ilos.Class, name string) ilos.Instance {
+		if v, ok := condition.(instance.Instance).GetSlotValue(instance.NewSymbol(name), c); ok {
+			return v
+		}
+		return Nil
+	}
+	message := func(control string, objs ...ilos.Instance) (ilos.Instance, ilos.Instance) {
+		return Format(e, Nil, instance.NewString([]rune(control)), objs...)
+	}
+	if _, ok := condition.(instance.Instance); !ok {
+		return message("~A", condition)
+	}
+	switch {
+	case ilos.InstanceOf(class.SimpleError, condition):
+		arguments := slot(class.SimpleError, "FORMAT-ARGUMENTS")
+		if !ilos.InstanceOf(class.List, arguments) {
+			arguments = Nil
+		}
+		return Format(e, Nil, slot(class.SimpleError, "FORMAT-STRING"), arguments.(instance.List).Slice()...)
+	case ilos.InstanceOf(class.DivisionByZero, condition):
+		return message("Division by zero in ~A of ~S", slot(class.ArithmeticError, "OPERATION"), slot(class.ArithmeticError, "OPERANDS"))
+	case ilos.InstanceOf(class.FloatingPointOverflow, condition):
+		return message("Floating point overflow in ~A of ~S", slot(class.ArithmeticError, "OPERATION"), slot(class.ArithmeticError, "OPERANDS"))
+	case ilos.InstanceOf(class.FloatingPointUnderflow, condition):
+		return message("Floating point underflow in ~A of ~S", slot(class.ArithmeticError, "OPERATION"), slot(class.ArithmeticError, "OPERANDS"))
+	case ilos.InstanceOf(class.ArithmeticError, condition):
+		return message("Arithmetic error in ~A of ~S", slot(class.ArithmeticError, "OPERATION"), slot(class.ArithmeticError, "OPERANDS"))
+	case ilos.InstanceOf(class.DomainError, condition):
+		return message("~S is not an instance of ~A", slot(class.DomainError, "IRIS.OBJECT"), slot(class.DomainError, "EXPECTED-CLASS"))
+	case ilos.InstanceOf(class.UndefinedVariable, condition):
+		return message("The variable ~A is unbound", slot(class.UndefinedEntity, "NAME"))
+	case ilos.InstanceOf(class.UndefinedFunction, condition):
+		return message("The function ~A is undefined", slot(class.UndefinedEntity, "NAME"))
+	case ilos.InstanceOf(class.UndefinedEntity, condition):
+		return message("~A is undefined in the namespace ~A", slot(class.UndefinedEntity, "NAME"), slot(class.UndefinedEntity, "NAMESPACE"))
+	case ilos.InstanceOf(class.ParseError, condition):
+		return message("Cannot parse ~S as ~A", slot(class.ParseError, "STRING"), slot(class.ParseError, "EXPECTED-CLASS"))
+	case ilos.InstanceOf(class.AccessDenied, condition):
+		return message("Access to the file ~S is denied", slot(class.AccessDenied, "FILENAME"))
+	case ilos.InstanceOf(class.EndOfStream, condition):
+		return message("Unexpected end of stream")
+	case ilos.InstanceOf(class.StreamError, condition):
+		return message("Stream error on ~A", slot(class.StreamError, "STREAM"))
+	case ilos.InstanceOf(class.PrintNotReadable, condition):
+		return message("~A cannot be printed readably", slot(class.PrintNotReadable, "IRIS.OBJECT"))
+	case ilos.InstanceOf(class.ControlError, condition):
+		return message("Control was transferred to an exit point which is not active")
+	case ilos.InstanceOf(class.ProgramError, condition):
+		return message("Program error")
+	case ilos.InstanceOf(class.EvaluationAborted, condition):
+		return message("Evaluation aborted: ~A", slot(class.EvaluationAborted, "REASON"))
+	case ilos.InstanceOf(class.StorageExhausted, condition):
+		return message("Storage exhausted")
+	}
+	return message("A condition of class ~A was signaled", condition.Class())
 }
 
 func ConditionContinuable(e env.Environment, condition ilos.Instance) (ilos.Instance, ilos.Instance) {
@@ -90,10 +181,100 @@ func WithHandler(e env.Environment, handler ilos.Instance, forms ...ilos.Instanc
 	if err != nil {
 		return nil, err
 	}
-	e.Handler = fun
+	if err := ensure(e, class.Function, fun); err != nil {
+		return nil, err
+	}
+	outer := e.Handler
+	e.Handler = instance.NewFunction(instance.NewSymbol("HANDLER"), func(e env.Environment, condition ilos.Instance) (ilos.Instance, ilos.Instance) {
+		// The handler runs with the handler outside of with-handler active.
+		// If it returns, it declines the condition and the outer one is called.
+		e.Handler = outer
+		if _, err := fun.(instance.Applicable).Apply(e.NewDynamic(), condition); err != nil {
+			return nil, err
+		}
+		return outer.(instance.Applicable).Apply(e, condition)
+	})
 	ret, err := Progn(e, forms...)
 	if err != nil {
 		return nil, err
 	}
 	return ret, err
 }
+
+// conditionSlot returns the value of the slot name of condition, which must
+// be an instance of c, or nil if the slot is unbound.
+func conditionSlot(e env.Environment, c ilos.Class, condition ilos.Instance, name string) (ilos.Instance, ilos.Instance) {
+	if err := ensure(e, c, condition); err != nil {
+		return nil, err
+	}
+	if i, ok := condition.(instance.Instance); ok {
+		if v, ok := i.GetSlotValue(instance.NewSymbol(name), c); ok {
+			return v, nil
+		}
+	}
+	return Nil, nil
+}
+
+// ArithmeticErrorOperation returns the operation which signaled the
+// arithmetic error. Unlike ISLisp, which returns the function object, this
+// returns the name of the operation as a symbol, such as DIV, or READ for a
+// float literal out of range, so that it can be printed in a message.
+func ArithmeticErrorOperation(e env.Environment, arithmeticError ilos.Instance) (ilos.Instance, ilos.Instance) {
+	return conditionSlot(e, class.ArithmeticError, arithmeticError, "OPERATION")
+}
+
+// ArithmeticErrorOperands returns the list of the operands of the operation
+// which signaled the arithmetic error.
+func ArithmeticErrorOperands(e env.Environment, arithmeticError ilos.Instance) (ilos.Instance, ilos.Instance) {
+	return conditionSlot(e, class.ArithmeticError, arithmeticError, "OPERANDS")
+}
+
+// DomainErrorObject returns the object which was not in the domain.
+func DomainErrorObject(e env.Environment, domainError ilos.Instance) (ilos.Instance, ilos.Instance) {
+	return conditionSlot(e, class.DomainError, domainError, "IRIS.OBJECT")
+}
+
+// DomainErrorExpectedClass returns the class of which the object was expected
+// to be an instance.
+func DomainErrorExpectedClass(e env.Environment, domainError ilos.Instance) (ilos.Instance, ilos.Instance) {
+	return conditionSlot(e, class.DomainError, domainError, "EXPECTED-CLASS")
+}
+
+// ParseErrorString returns the string which could not be parsed.
+func ParseErrorString(e env.Environment, parseError ilos.Instance) (ilos.Instance, ilos.Instance) {
+	return conditionSlot(e, class.ParseError, parseError, "STRING")
+}
+
+// ParseErrorExpectedClass returns the class of the object which the string
+// was expected to stand for.
+func ParseErrorExpectedClass(e env.Environment, parseError ilos.Instance) (ilos.Instance, ilos.Instance) {
+	return conditionSlot(e, class.ParseError, parseError, "EXPECTED-CLASS")
+}
+
+// SimpleErrorFormatString returns the format string of the simple error.
+func SimpleErrorFormatString(e env.Environment, simpleError ilos.Instance) (ilos.Instance, ilos.Instance) {
+	return conditionSlot(e, class.SimpleError, simpleError, "FORMAT-STRING")
+}
+
+// SimpleErrorFormatArguments returns the list of the format arguments of the
+// simple error.
+func SimpleErrorFormatArguments(e env.Environment, simpleError ilos.Instance) (ilos.Instance, ilos.Instance) {
+	return conditionSlot(e, class.SimpleError, simpleError, "FORMAT-ARGUMENTS")
+}
+
+// StreamErrorStream returns the stream on which the error occurred, or nil if
+// it occurred while opening one.
+func StreamErrorStream(e env.Environment, streamError ilos.Instance) (ilos.Instance, ilos.Instance) {
+	return conditionSlot(e, class.StreamError, streamError, "STREAM")
+}
+
+// UndefinedEntityName returns the name of the undefined entity.
+func UndefinedEntityName(e env.Environment, undefinedEntity ilos.Instance) (ilos.Instance, ilos.Instance) {
+	return conditionSlot(e, class.UndefinedEntity, undefinedEntity, "NAME")
+}
+
+// UndefinedEntityNamespace returns the namespace in which the entity was
+// undefined, variable or function.
+func UndefinedEntityNamespace(e env.Environment, undefinedEntity ilos.Instance) (ilos.Instance, ilos.Instance) {
+	return conditionSlot(e, class.UndefinedEntity, undefinedEntity, "NAMESPACE")
+}
@@ -0,0 +1,229 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package runtime
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ta2gch/iris/runtime/env"
+	"github.com/ta2gch/iris/runtime/ilos"
+	"github.com/ta2gch/iris/runtime/ilos/class"
+	"github.com/ta2gch/iris/runtime/ilos/instance"
+)
+
+// ConditionError is a condition returned by an evaluation as a Go error, so
+// that Go code can tell conditions apart with errors.As and the predicates
+// below instead of matching their printed form.
+type ConditionError struct {
+	Condition ilos.Instance
+	// Environment is the environment where the condition was signaled, in
+	// which Message writes the report of the condition.
+	Environment env.Environment
+}
+
+// AsError returns condition, the second value returned by an evaluation, as a
+// *ConditionError, or nil if condition is nil. A condition which was never
+// signaled, such as one of the reader, is reported in an environment of its
+// own.
+func AsError(condition ilos.Instance) error {
+	if condition == nil {
+		return nil
+	}
+	if c, ok := condition.(instance.Instance); ok {
+		if s, ok := c.GetSlotValue(instance.NewSymbol("IRIS.ENVIRONMENT"), class.SeriousCondition); ok {
+			return &ConditionError{condition, s.(signalEnvironment).Environment}
+		}
+	}
+	return &ConditionError{condition, env.NewEnvironment(nil, nil, nil, nil)}
+}
+
+// signalEnvironment is the environment where a condition was signaled, which
+// the condition keeps.
+type signalEnvironment struct {
+	env.Environment
+}
+
+func (signalEnvironment) Class() ilos.Class {
+	return class.Object
+}
+
+func (signalEnvironment) String() string {
+	return "#<ENVIRONMENT>"
+}
+
+// recordEnvironment keeps e in condition, unless it has the environment of
+// an earlier signal.
+func recordEnvironment(e env.Environment, condition ilos.Instance) {
+	c := condition.(instance.Instance)
+	if _, ok := c.GetSlotValue(instance.NewSymbol("IRIS.ENVIRONMENT"), class.SeriousCondition); ok {
+		return
+	}
+	c.SetSlotValue(instance.NewSymbol("IRIS.ENVIRONMENT"), signalEnvironment{e}, class.SeriousCondition)
+}
+
+// Error returns the message of the condition, preceded by the source location
+// where it was signaled if it is known.
+func (err *ConditionError) Error() string {
+	if location, ok := err.Location(); ok {
+		return fmt.Sprintf("%v: %v", location, err.Message())
+	}
+	return err.Message()
+}
+
+// Class returns the class of the condition.
+func (err *ConditionError) Class() ilos.Class {
+	return err.Condition.Class()
+}
+
+// Message returns the message which report-condition writes for the
+// condition in the environment where it was signaled. The evaluation is over
+// by then, so the report is not bounded by its budget and a condition in it
+// reaches none of its handlers.
+func (err *ConditionError) Message() string {
+	e := err.Environment
+	e.Budget = nil
+	e.Handler = instance.NewFunction(instance.NewSymbol("TOP-LEVEL-HANDLER"), TopLevelHander)
+	message, c := conditionMessage(e, err.Condition)
+	if c != nil {
+		return fmt.Sprint(err.Condition)
+	}
+	return string(message.(instance.String))
+}
+
+// Slot returns the value of the slot name of the condition, such as
+// "FORMAT-STRING" of a <simple-error>, and whether the slot is bound.
+func (err *ConditionError) Slot(name string) (ilos.Instance, bool) {
+	i, ok := err.Condition.(instance.Instance)
+	if !ok {
+		return nil, false
+	}
+	for _, c := range classes(err.Condition.Class()) {
+		if v, ok := i.GetSlotValue(instance.NewSymbol(name), c); ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// Slots returns the values of the bound slots of the condition by name,
+// except the internal ones of the implementation.
+func (err *ConditionError) Slots() map[string]ilos.Instance {
+	slots := map[string]ilos.Instance{}
+	for _, c := range classes(err.Condition.Class()) {
+		for _, name := range c.Slots() {
+			if instance.Internal(name) {
+				continue
+			}
+			if v, ok := err.Slot(string(name.(instance.Symbol))); ok {
+				slots[string(name.(instance.Symbol))] = v
+			}
+		}
+	}
+	return slots
+}
+
+// Backtrace returns the calls in progress where the condition was signaled,
+// innermost first.
+func (err *ConditionError) Backtrace() []Frame {
+	return ConditionBacktrace(err.Condition)
+}
+
+// Location returns the source location of the innermost form whose
+// evaluation signaled the condition (see the function Location).
+func (err *ConditionError) Location() (string, bool) {
+	return Location(err.Condition)
+}
+
+// classes returns c and its superclasses.
+func classes(c ilos.Class) []ilos.Class {
+	cs := []ilos.Class{c}
+	for _, super := range c.Supers() {
+		cs = append(cs, classes(super)...)
+	}
+	return cs
+}
+
+// IsCondition reports whether err is a *ConditionError, or wraps one, whose
+// condition is an instance of c.
+func IsCondition(err error, c ilos.Class) bool {
+	var ce *ConditionError
+	return errors.As(err, &ce) && ilos.InstanceOf(c, ce.Condition)
+}
+
+// IsError reports whether err is an <error>.
+func IsError(err error) bool {
+	return IsCondition(err, class.Error)
+}
+
+// IsArithmeticError reports whether err is an <arithmetic-error>.
+func IsArithmeticError(err error) bool {
+	return IsCondition(err, class.ArithmeticError)
+}
+
+// IsDivisionByZero reports whether err is a <division-by-zero>.
+func IsDivisionByZero(err error) bool {
+	return IsCondition(err, class.DivisionByZero)
+}
+
+// IsControlError reports whether err is a <control-error>.
+func IsControlError(err error) bool {
+	return IsCondition(err, class.ControlError)
+}
+
+// IsParseError reports whether err is a <parse-error>.
+func IsParseError(err error) bool {
+	return IsCondition(err, class.ParseError)
+}
+
+// IsProgramError reports whether err is a <program-error>.
+func IsProgramError(err error) bool {
+	return IsCondition(err, class.ProgramError)
+}
+
+// IsDomainError reports whether err is a <domain-error>.
+func IsDomainError(err error) bool {
+	return IsCondition(err, class.DomainError)
+}
+
+// IsUndefinedEntity reports whether err is an <undefined-entity>.
+func IsUndefinedEntity(err error) bool {
+	return IsCondition(err, class.UndefinedEntity)
+}
+
+// IsUndefinedFunction reports whether err is an <undefined-function>.
+func IsUndefinedFunction(err error) bool {
+	return IsCondition(err, class.UndefinedFunction)
+}
+
+// IsUndefinedVariable reports whether err is an <undefined-variable>.
+func IsUndefinedVariable(err error) bool {
+	return IsCondition(err, class.UndefinedVariable)
+}
+
+// IsSimpleError reports whether err is a <simple-error>.
+func IsSimpleError(err error) bool {
+	return IsCondition(err, class.SimpleError)
+}
+
+// IsStreamError reports whether err is a <stream-error>.
+func IsStreamError(err error) bool {
+	return IsCondition(err, class.StreamError)
+}
+
+// IsEndOfStream reports whether err is an <end-of-stream>.
+func IsEndOfStream(err error) bool {
+	return IsCondition(err, class.EndOfStream)
+}
+
+// IsAccessDenied reports whether err is an <access-denied>.
+func IsAccessDenied(err error) bool {
+	return IsCondition(err, class.AccessDenied)
+}
+
+// IsEvaluationAborted reports whether err is an <evaluation-aborted>.
+func IsEvaluationAborted(err error) bool {
+	return IsCondition(err, class.EvaluationAborted)
+}
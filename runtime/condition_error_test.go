@@ -0,0 +1,97 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ta2gch/iris/runtime/ilos/class"
+)
+
+func TestConditionError(t *testing.T) {
+	tests := []struct {
+		exp       string
+		is        func(error) bool
+		message   string
+		slot      string
+		slotValue string
+		backtrace string
+	}{
+		{
+			exp:       `(no-such-function 1)`,
+			is:        IsUndefinedFunction,
+			message:   "The function NO-SUCH-FUNCTION is undefined",
+			slot:      "NAME",
+			slotValue: "NO-SUCH-FUNCTION",
+			backtrace: "[]",
+		},
+		{
+			exp:       `(f 1)`,
+			is:        IsDomainError,
+			slot:      "EXPECTED-CLASS",
+			slotValue: "<CONS>",
+			backtrace: "[(CAR 1) at 1:14 (F 1) at 1:1]",
+		},
+		{
+			exp:       `(error "bad ~A" 1)`,
+			is:        IsSimpleError,
+			message:   "bad 1",
+			slot:      "FORMAT-STRING",
+			slotValue: `"bad ~A"`,
+			backtrace: `[(ERROR "bad ~A" 1) at 1:1]`,
+		},
+		{
+			exp:       `(dynamic-let ((*print-pretty* t) (*print-right-margin* 12)) (error "~S" '(aaaa bbbb cccc)))`,
+			is:        IsSimpleError,
+			message:   "(AAAA BBBB\n CCCC)",
+			slot:      "FORMAT-STRING",
+			slotValue: `"~S"`,
+			backtrace: `[(ERROR "~S" (AAAA BBBB CCCC)) at 1:61]`,
+		},
+	}
+	for _, backend := range []Backend{BackendCompiler, BackendVM} {
+		for _, tt := range tests {
+			i := New(Options{Backend: backend})
+			if _, err := i.EvalString(`(defun f (x) (car x))`); err != nil {
+				t.Fatal(err)
+			}
+			_, c := i.EvalString(tt.exp)
+			err := fmt.Errorf("wrapped: %w", AsError(c))
+			var ce *ConditionError
+			if !errors.As(err, &ce) {
+				t.Fatalf("%v: %v errors.As() = false", backend, tt.exp)
+			}
+			if !tt.is(err) || !IsError(err) || IsEndOfStream(err) {
+				t.Errorf("%v: %v predicates do not match %v", backend, tt.exp, ce.Class())
+			}
+			if tt.message != "" && ce.Message() != tt.message {
+				t.Errorf("%v: %v Message() = %v, want %v", backend, tt.exp, ce.Message(), tt.message)
+			}
+			if v, ok := ce.Slot(tt.slot); !ok || fmt.Sprint(v) != tt.slotValue {
+				t.Errorf("%v: %v Slot(%v) = %v, %v, want %v", backend, tt.exp, tt.slot, v, ok, tt.slotValue)
+			}
+			if v := ce.Slots()[tt.slot]; fmt.Sprint(v) != tt.slotValue {
+				t.Errorf("%v: %v Slots()[%v] = %v, want %v", backend, tt.exp, tt.slot, v, tt.slotValue)
+			}
+			for name := range ce.Slots() {
+				if strings.HasPrefix(name, "IRIS.") {
+					t.Errorf("%v: %v Slots() has the internal slot %v", backend, tt.exp, name)
+				}
+			}
+			if got := fmt.Sprint(ce.Backtrace()); got != tt.backtrace {
+				t.Errorf("%v: %v Backtrace() = %v, want %v", backend, tt.exp, got, tt.backtrace)
+			}
+		}
+	}
+	if AsError(nil) != nil {
+		t.Errorf("AsError(nil) != nil")
+	}
+	if IsCondition(errors.New("not a condition"), class.Error) {
+		t.Errorf("IsCondition() = true for a Go error")
+	}
+}
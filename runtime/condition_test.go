@@ -35,3 +35,158 @@ func TestSignalCondition(t *testing.T) {
 	}
 	execTests(t, SignalCondition, tests)
 }
+
+func TestWithHandler(t *testing.T) {
+	execTests(t, WithHandler, []test{
+		{
+			exp:     `(defun first-of (x) (car x))`,
+			want:    `'first-of`,
+			wantErr: false,
+		},
+		{
+			exp:     `(catch 'c (with-handler (lambda (c) (throw 'c 'handled)) (first-of 1)))`,
+			want:    `'handled`,
+			wantErr: false,
+		},
+		{
+			exp:     `(catch 'c (with-handler (lambda (c) (throw 'c 'outer)) (with-handler (lambda (c) nil) (first-of 1))))`,
+			want:    `'outer`,
+			wantErr: false,
+		},
+		{
+			exp:     `(catch 'c (with-handler (lambda (c) (throw 'c 'outer)) (with-handler (lambda (c) (car c)) (first-of 1))))`,
+			want:    `'outer`,
+			wantErr: false,
+		},
+		{
+			exp:     `(with-handler (lambda (c) nil) (first-of 1))`,
+			want:    `nil`,
+			wantErr: true,
+		},
+		{
+			exp:     `(with-handler (lambda (c) (continue-condition c 'inner)) (with-handler (lambda (c) nil) (cerror "cont" "err")))`,
+			want:    `'inner`,
+			wantErr: false,
+		},
+	})
+}
+
+func TestConditionAccessors(t *testing.T) {
+	execTests(t, SimpleErrorFormatString, []test{
+		{
+			exp:     `(defmacro condition-of (form) (list 'catch ''c (list 'with-handler '(lambda (c) (throw 'c c)) form)))`,
+			want:    `'condition-of`,
+			wantErr: false,
+		},
+		{
+			exp:     `(let ((c (condition-of (error "bad ~A" 1 2)))) (list (simple-error-format-string c) (simple-error-format-arguments c)))`,
+			want:    `'("bad ~A" (1 2))`,
+			wantErr: false,
+		},
+		{
+			exp:     `(simple-error-format-arguments (condition-of (cerror "go on" "bad")))`,
+			want:    `nil`,
+			wantErr: false,
+		},
+		{
+			exp:     `(let ((c (condition-of (car 1)))) (list (domain-error-object c) (eq (domain-error-expected-class c) (class <cons>))))`,
+			want:    `'(1 t)`,
+			wantErr: false,
+		},
+		{
+			exp:     `(let ((c (condition-of (div 1 0)))) (list (arithmetic-error-operation c) (arithmetic-error-operands c)))`,
+			want:    `'(div (1 0))`,
+			wantErr: false,
+		},
+		{
+			exp:     `(let ((c (condition-of (no-such-function 1)))) (list (undefined-entity-name c) (undefined-entity-namespace c)))`,
+			want:    `'(no-such-function function)`,
+			wantErr: false,
+		},
+		{
+			exp:     `(let ((c (condition-of no-such-variable))) (list (undefined-entity-name c) (undefined-entity-namespace c)))`,
+			want:    `'(no-such-variable variable)`,
+			wantErr: false,
+		},
+		{
+			exp:     `(let ((c (condition-of (parse-number "1x")))) (list (parse-error-string c) (eq (parse-error-expected-class c) (class <number>))))`,
+			want:    `'("1x" t)`,
+			wantErr: false,
+		},
+		{
+			exp:     `(let ((c (condition-of (read (create-string-input-stream "1e400"))))) (list (instancep c (class <floating-point-overflow>)) (arithmetic-error-operands c)))`,
+			want:    `'(t ("1e400"))`,
+			wantErr: false,
+		},
+		{
+			exp:     `(let* ((s (create-string-input-stream "")) (c (condition-of (read-char s)))) (eq (stream-error-stream c) s))`,
+			want:    `t`,
+			wantErr: false,
+		},
+		{
+			exp:     `(domain-error-object (condition-of (error "x")))`,
+			want:    `nil`,
+			wantErr: true,
+		},
+	})
+}
+
+func TestReportCondition(t *testing.T) {
+	execTests(t, ReportCondition, []test{
+		{
+			exp:     `(defun report (c) (let ((s (create-string-output-stream))) (report-condition c s) (get-output-stream-string s)))`,
+			want:    `'report`,
+			wantErr: false,
+		},
+		{
+			exp:     `(catch 'c (with-handler (lambda (c) (throw 'c (report c))) (error "bad ~A and ~S" 1 "two")))`,
+			want:    `"bad 1 and \"two\""`,
+			wantErr: false,
+		},
+		{
+			exp:     `(catch 'c (with-handler (lambda (c) (throw 'c (report c))) (car "x")))`,
+			want:    `"\"x\" is not an instance of <CONS>"`,
+			wantErr: false,
+		},
+		{
+			exp:     `(catch 'c (with-handler (lambda (c) (throw 'c (report c))) (div 1 0)))`,
+			want:    `"Division by zero in DIV of (1 0)"`,
+			wantErr: false,
+		},
+		{
+			exp:     `(catch 'c (with-handler (lambda (c) (throw 'c (report c))) (no-such-function)))`,
+			want:    `"The function NO-SUCH-FUNCTION is undefined"`,
+			wantErr: false,
+		},
+		{
+			exp:     `(catch 'c (with-handler (lambda (c) (throw 'c (report-condition c 1))) (car 1)))`,
+			want:    `nil`,
+			wantErr: true,
+		},
+	})
+}
+
+func TestIgnoreError(t *testing.T) {
+	execTests(t, IgnoreError, []test{
+		{
+			exp:     `(ignore-errors 1 2)`,
+			want:    `2`,
+			wantErr: false,
+		},
+		{
+			exp:     `(ignore-errors (car 1) 2)`,
+			want:    `nil`,
+			wantErr: false,
+		},
+		{
+			exp:     `(catch 'c (with-handler (lambda (c) (throw 'c 'outer)) (ignore-errors (error "x"))))`,
+			want:    `nil`,
+			wantErr: false,
+		},
+		{
+			exp:     `(catch 'c (ignore-errors (throw 'c 1)))`,
+			want:    `1`,
+			wantErr: false,
+		},
+	})
+}
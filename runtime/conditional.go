@@ -21,7 +21,7 @@ func If(e env.Environment, testForm, thenForm ilos.Instance, elseForm ...ilos.In
 		return nil, err
 	}
 	if tf != Nil {
-		return Eval(e, thenForm)
+		return evalTail(e, thenForm)
 	}
 	if len(elseForm) > 1 {
 		return SignalCondition(e, instance.NewArityError(e), Nil)
@@ -29,7 +29,7 @@ func If(e env.Environment, testForm, thenForm ilos.Instance, elseForm ...ilos.In
 	if len(elseForm) == 0 {
 		return Nil, nil
 	}
-	return Eval(e, elseForm[0])
+	return evalTail(e, elseForm[0])
 }
 
 // Cond the clauses (test form*) are scanned sequentially and in each case the
@@ -51,8 +51,8 @@ func Cond(e env.Environment, testFrom ...ilos.Instance) (ilos.Instance, ilos.Ins
 		if err != nil {
 			return nil, err
 		}
-		if ret == T {
-			return Progn(e, s[1:]...)
+		if ret != Nil {
+			return progn(e, s[1:]...)
 		}
 	}
 	return Nil, nil
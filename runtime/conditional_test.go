@@ -66,6 +66,11 @@ func TestCond(t *testing.T) {
 			want:    `'equal`,
 			wantErr: false,
 		},
+		{
+			exp:     `(list (cond (1 'a)) (cond ("" 'b)) (cond (nil 'c)))`,
+			want:    `'(a b nil)`,
+			wantErr: false,
+		},
 	})
 }
 
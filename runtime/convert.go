@@ -30,7 +30,7 @@ func Convert(e env.Environment, object, class1 ilos.Instance) (ilos.Instance, il
 		case class.Float.String():
 		case class.Symbol.String():
 		case class.String.String():
-			return instance.NewString([]rune(object.String()[2:])), nil
+			return instance.NewString([]rune{rune(object.(instance.Character))}), nil
 		case class.GeneralVector.String():
 		case class.List.String():
 		}
@@ -48,11 +48,37 @@ func Convert(e env.Environment, object, class1 ilos.Instance) (ilos.Instance, il
 		case class.GeneralVector.String():
 		case class.List.String():
 		}
+	case class.Bignum.String():
+		switch class1.String() {
+		case class.Character.String():
+		case class.Integer.String():
+			return object, nil
+		case class.Float.String():
+			return instance.NewFloat(toFloat64(object)), nil
+		case class.Symbol.String():
+		case class.String.String():
+			return instance.NewString([]rune(object.String())), nil
+		case class.GeneralVector.String():
+		case class.List.String():
+		}
+	case class.Ratio.String():
+		switch class1.String() {
+		case class.Character.String():
+		case class.Integer.String():
+			return Truncate(e, object)
+		case class.Float.String():
+			return instance.NewFloat(toFloat64(object)), nil
+		case class.Symbol.String():
+		case class.String.String():
+			return instance.NewString([]rune(object.String())), nil
+		case class.GeneralVector.String():
+		case class.List.String():
+		}
 	case class.Float.String():
 		switch class1.String() {
 		case class.Character.String():
 		case class.Integer.String():
-			return instance.NewInteger(int(float64(object.(instance.Float)))), nil
+			return Truncate(e, object)
 		case class.Float.String():
 			return object, nil
 		case class.Symbol.String():
@@ -69,7 +95,7 @@ func Convert(e env.Environment, object, class1 ilos.Instance) (ilos.Instance, il
 		case class.Symbol.String():
 			return object, nil
 		case class.String.String():
-			return instance.NewString([]rune(object.String())), nil
+			return instance.NewString([]rune(string(object.(instance.Symbol)))), nil
 		case class.GeneralVector.String():
 		case class.List.String():
 		}
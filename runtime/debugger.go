@@ -0,0 +1,70 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package runtime
+
+import (
+	"github.com/ta2gch/iris/runtime/env"
+	"github.com/ta2gch/iris/runtime/ilos"
+)
+
+// Break is an error which no handler handled, given to the debugger of an
+// interpreter where it was signaled. The calls in progress are still there,
+// so the debugger can look into them and evaluate forms in them. What the
+// debugger returns is what a handler returns: Continue or Abort of the break,
+// or the escape of a form evaluated by Eval, such as that of invoke-restart.
+type Break struct {
+	Condition ilos.Instance
+	e         env.Environment
+	eval      func(e env.Environment, obj ilos.Instance) (ilos.Instance, ilos.Instance)
+}
+
+// Frames returns the calls in progress, innermost first.
+func (b *Break) Frames() []Frame {
+	return frames(b.e.Frame)
+}
+
+// environment returns the environment of the call n of Frames where it made
+// the call n-1, which is where the condition was signaled for the call 0, or
+// that one if n is out of range.
+func (b *Break) environment(n int) env.Environment {
+	fs := b.Frames()
+	if n <= 0 || n >= len(fs) || fs[n-1].call.Caller == nil {
+		return b.e
+	}
+	return *fs[n-1].call.Caller
+}
+
+// Locals returns the names and values of the local variables of the call n of
+// Frames, innermost first, including those bound by let and the like around
+// where it made the call n-1.
+func (b *Break) Locals(n int) ([]ilos.Instance, []ilos.Instance) {
+	return b.environment(n).Variable.Bindings()
+}
+
+// Eval evaluates form in the environment of the call n of Frames, or where
+// the condition was signaled if n is -1.
+func (b *Break) Eval(n int, form ilos.Instance) (ilos.Instance, ilos.Instance) {
+	e := b.environment(n)
+	return b.eval(e.NewLexical(), form)
+}
+
+// Continuable reports whether the condition was signaled by cerror or by
+// signal-condition with a continuable argument, so that Continue can go on.
+func (b *Break) Continuable() bool {
+	continuable, _ := ConditionContinuable(b.e, b.Condition)
+	return continuable != Nil
+}
+
+// Continue makes the function which signaled the condition, which must be
+// continuable, return value.
+func (b *Break) Continue(value ilos.Instance) (ilos.Instance, ilos.Instance) {
+	return ContinueCondition(b.e, b.Condition, value)
+}
+
+// Abort unwinds the evaluation with the condition, as if there were no
+// debugger.
+func (b *Break) Abort() (ilos.Instance, ilos.Instance) {
+	return nil, b.Condition
+}
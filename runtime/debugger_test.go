@@ -0,0 +1,104 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package runtime
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ta2gch/iris/runtime/ilos"
+)
+
+func TestDebugger(t *testing.T) {
+	tests := []struct {
+		exp      string
+		debugger func(t *testing.T, b *Break) (ilos.Instance, ilos.Instance)
+		want     string
+		wantErr  bool
+	}{
+		{
+			exp: `(f 1 2)`,
+			debugger: func(t *testing.T, b *Break) (ilos.Instance, ilos.Instance) {
+				if got := fmt.Sprint(b.Frames()); got != "[(CAR 1) at 1:29 (F 1 2) at 1:1]" {
+					t.Errorf("Frames() = %v", got)
+				}
+				names, values := b.Locals(1)
+				if got := fmt.Sprint(names, values); got != "[Z X Y] [3 1 2]" {
+					t.Errorf("Locals() = %v", got)
+				}
+				if b.Continuable() {
+					t.Errorf("Continuable() = true")
+				}
+				return b.Abort()
+			},
+			want:    "",
+			wantErr: true,
+		},
+		{
+			exp: `(f 1 2)`,
+			debugger: func(t *testing.T, b *Break) (ilos.Instance, ilos.Instance) {
+				ret, err := b.Eval(1, readFromStringOrPanic("(+ x y z)"))
+				if fmt.Sprint(ret) != "6" || err != nil {
+					t.Errorf("Eval() = %v, %v", ret, err)
+				}
+				return b.Abort()
+			},
+			want:    "",
+			wantErr: true,
+		},
+		{
+			exp: `(+ 1 (cerror "use a value" "bad"))`,
+			debugger: func(t *testing.T, b *Break) (ilos.Instance, ilos.Instance) {
+				if !b.Continuable() {
+					t.Errorf("Continuable() = false")
+				}
+				return b.Continue(readFromStringOrPanic("41"))
+			},
+			want:    "42",
+			wantErr: false,
+		},
+		{
+			exp: `(restart-case (f 1 2) (use-value (v) v))`,
+			debugger: func(t *testing.T, b *Break) (ilos.Instance, ilos.Instance) {
+				return b.Eval(-1, readFromStringOrPanic("(invoke-restart 'use-value 'restarted)"))
+			},
+			want:    "RESTARTED",
+			wantErr: false,
+		},
+		{
+			exp: `(ignore-errors (f 1 2))`,
+			debugger: func(t *testing.T, b *Break) (ilos.Instance, ilos.Instance) {
+				t.Errorf("Debugger() called for a handled error")
+				return b.Abort()
+			},
+			want:    "NIL",
+			wantErr: false,
+		},
+	}
+	for _, backend := range []Backend{BackendCompiler, BackendVM} {
+		for _, tt := range tests {
+			debugger := func(b *Break) (ilos.Instance, ilos.Instance) { return tt.debugger(t, b) }
+			i := New(Options{Backend: backend, Debugger: debugger})
+			if _, err := i.EvalString(`(defun f (x y) (let ((z 3)) (car x)))`); err != nil {
+				t.Fatal(err)
+			}
+			ret, err := i.EvalString(tt.exp)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%v: %v err = %v, wantErr %v", backend, tt.exp, err, tt.wantErr)
+			}
+			if err == nil && fmt.Sprint(ret) != tt.want {
+				t.Errorf("%v: %v = %v, want %v", backend, tt.exp, ret, tt.want)
+			}
+		}
+	}
+}
+
+func readFromStringOrPanic(s string) ilos.Instance {
+	obj, err := readFromString(s)
+	if err != nil {
+		panic(err)
+	}
+	return obj
+}
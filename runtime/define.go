@@ -22,14 +22,14 @@ func Defconstant(e env.Environment, name, form ilos.Instance) (ilos.Instance, il
 	if err := ensure(e, class.Symbol, name); err != nil {
 		return nil, err
 	}
-	if _, ok := e.Constant[:1].Get(name); ok {
+	if _, ok := e.Constant.Global().Get(name); ok {
 		return SignalCondition(e, instance.NewImmutableBinding(e), Nil)
 	}
 	ret, err := Eval(e, form)
 	if err != nil {
 		return nil, err
 	}
-	e.Constant[:1].Define(name, ret)
+	e.Constant.Global().Define(name, ret)
 	return name, nil
 }
 
@@ -45,14 +45,14 @@ func Defglobal(e env.Environment, name, form ilos.Instance) (ilos.Instance, ilos
 	if err := ensure(e, class.Symbol, name); err != nil {
 		return nil, err
 	}
-	if _, ok := e.Constant[:1].Get(name); ok {
+	if _, ok := e.Constant.Global().Get(name); ok {
 		return SignalCondition(e, instance.NewImmutableBinding(e), Nil)
 	}
 	ret, err := Eval(e, form)
 	if err != nil {
 		return nil, err
 	}
-	e.Variable[:1].Define(name, ret)
+	e.Variable.Global().Define(name, ret)
 	return name, nil
 }
 
@@ -63,14 +63,14 @@ func Defdynamic(e env.Environment, name, form ilos.Instance) (ilos.Instance, ilo
 	if err := ensure(e, class.Symbol, name); err != nil {
 		return nil, err
 	}
-	if _, ok := e.Constant[:1].Get(name); ok {
+	if _, ok := e.Constant.Global().Get(name); ok {
 		return SignalCondition(e, instance.NewImmutableBinding(e), Nil)
 	}
 	ret, err := Eval(e, form)
 	if err != nil {
 		return nil, err
 	}
-	e.DynamicVariable[:1].Define(name, ret)
+	e.DynamicVariable.Global().Define(name, ret)
 	return name, nil
 }
 
@@ -91,6 +91,6 @@ func Defun(e env.Environment, functionName, lambdaList ilos.Instance, forms ...i
 	if err != nil {
 		return nil, err
 	}
-	e.Function[:1].Define(functionName, ret)
+	e.Function.Global().Define(functionName, ret)
 	return functionName, nil
 }
@@ -0,0 +1,48 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package runtime
+
+import "testing"
+
+func TestDynamicLet(t *testing.T) {
+	tests := []test{
+		{
+			exp:     `(defdynamic *depth* 0)`,
+			want:    `'*depth*`,
+			wantErr: false,
+		},
+		{
+			exp:     `(defun depth () (dynamic *depth*))`,
+			want:    `'depth`,
+			wantErr: false,
+		},
+		{
+			exp:     `(dynamic-let ((*depth* 1)) (list (depth) (dynamic-let ((*depth* 2)) (depth)) (depth)))`,
+			want:    `'(1 2 1)`,
+			wantErr: false,
+		},
+		{
+			exp:     `(depth)`,
+			want:    `0`,
+			wantErr: false,
+		},
+		{
+			exp:     `(catch 'c (dynamic-let ((*depth* 1)) (throw 'c (depth))))`,
+			want:    `1`,
+			wantErr: false,
+		},
+		{
+			exp:     `(depth)`,
+			want:    `0`,
+			wantErr: false,
+		},
+		{
+			exp:     `(dynamic *undefined*)`,
+			want:    `nil`,
+			wantErr: true,
+		},
+	}
+	execTests(t, DynamicLet, tests)
+}
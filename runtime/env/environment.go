@@ -5,10 +5,23 @@
 package env
 
 import (
+	"context"
+
 	"github.com/ta2gch/iris/runtime/ilos"
 )
 
-// Environment struct is the struct for keeping functions and variables
+// Budget bounds an evaluation. It is shared by every environment derived
+// during the evaluation.
+type Budget struct {
+	Context  context.Context
+	MaxSteps int // zero means no limit
+	Steps    int
+}
+
+// Environment struct is the struct for keeping functions and variables. Each
+// namespace is a chain of frames in front of a global table. The lexical and
+// dynamic namespaces get a new frame per scope; the global ones have only the
+// table, which every environment derived from the same one shares.
 type Environment struct {
 	// Lexical
 	BlockTag   stack
@@ -30,6 +43,13 @@ type Environment struct {
 	StandardOutput  ilos.Instance
 	ErrorOutput     ilos.Instance
 	Handler         ilos.Instance
+	Restart         []ilos.Instance // innermost first
+	Frame           *CallFrame      // the innermost call
+
+	// Evaluation
+	Budget   *Budget
+	IEEE     bool // float operations return infinities and NaN instead of signaling
+	Debugged bool // call frames keep the environments of their callers for a debugger
 }
 
 // New creates new eironment
@@ -59,70 +79,86 @@ func NewEnvironment(stdin, stdout, stderr, handler ilos.Instance) Environment {
 	return *e
 }
 
+// MergeLexical puts the lexical bindings of e inside of those of before, the
+// environment where a function was made, so that e is the environment of a
+// call of the function. The dynamic bindings of e, and its handler, restarts
+// and frames, are kept.
 func (e *Environment) MergeLexical(before Environment) {
-	e.BlockTag = before.BlockTag.Append(e.BlockTag[1:])
-	e.TagbodyTag = before.TagbodyTag.Append(e.TagbodyTag[1:])
-	e.Variable = before.Variable.Append(e.Variable[1:])
-	e.Function = before.Function.Append(e.Function[1:])
-
-	e.Macro = before.Macro.Append(e.Macro[1:])
-	e.Class = before.Class.Append(e.Class[1:])
-	e.Special = before.Special.Append(e.Special[1:])
-	e.Constant = before.Constant.Append(e.Constant[1:])
-	e.Property = before.Property
-
-	e.CatchTag = before.CatchTag.Append(e.CatchTag[1:])
-	e.DynamicVariable = before.DynamicVariable.Append(e.DynamicVariable[1:])
+	e.BlockTag = e.BlockTag.merge(before.BlockTag)
+	e.TagbodyTag = e.TagbodyTag.merge(before.TagbodyTag)
+	e.Variable = e.Variable.merge(before.Variable)
+	e.Function = e.Function.merge(before.Function)
 	e.StandardInput = before.StandardInput
 	e.StandardOutput = before.StandardOutput
 	e.ErrorOutput = before.ErrorOutput
-	e.Handler = before.Handler
+	// Budget is kept from the caller, not from where the closure was made
 }
 
-func (before *Environment) NewLexical() Environment {
-	e := NewEnvironment(before.StandardInput, before.StandardOutput, before.ErrorOutput, before.Handler)
-
-	e.BlockTag = before.BlockTag.Append(e.BlockTag)
-	e.TagbodyTag = before.TagbodyTag.Append(e.TagbodyTag)
-	e.Variable = before.Variable.Append(e.Variable)
-	e.Function = before.Function.Append(e.Function)
+// CallFrame is a call of a function, which backtraces show. Frames are never
+// changed once made, so a condition can keep the frames where it was
+// signaled.
+type CallFrame struct {
+	Function  ilos.Instance // the name of the function, or the function if it has none
+	Arguments []ilos.Instance
+	Form      ilos.Instance // the form of the call
+	Parent    *CallFrame    // the caller
+	Caller    *Environment  // of the caller where it made the call, kept only if it is Debugged
+}
 
-	e.Macro = before.Macro.Append(e.Macro)
-	e.Class = before.Class.Append(e.Class)
-	e.Special = before.Special.Append(e.Special)
-	e.Constant = before.Constant.Append(e.Constant)
-	e.Property = before.Property
+// Call makes a frame for the call form of function with arguments from caller
+// the innermost frame of e. A tail call replaces the innermost frame, as the
+// caller has nothing left to do.
+func (e *Environment) Call(caller *Environment, function ilos.Instance, arguments []ilos.Instance, form ilos.Instance, tail bool) {
+	f := &CallFrame{Function: function, Arguments: arguments, Form: form, Parent: e.Frame}
+	if tail && e.Frame != nil {
+		f.Parent, f.Caller = e.Frame.Parent, e.Frame.Caller
+	} else if e.Debugged {
+		// The frames of the lexical namespaces are shared with the copy, so
+		// it sees the bindings the caller makes in them later
+		c := *caller
+		f.Caller = &c
+	}
+	e.Frame = f
+}
 
-	e.CatchTag = before.CatchTag.Append(e.CatchTag)
-	e.DynamicVariable = before.DynamicVariable.Append(e.DynamicVariable)
-	e.StandardInput = before.StandardInput
-	e.StandardOutput = before.StandardOutput
-	e.ErrorOutput = before.ErrorOutput
-	e.Handler = before.Handler
+// merge returns s inside of before. The outermost frame of s is moved into
+// before, so s must be made for the call by NewDynamic.
+func (s stack) merge(before stack) stack {
+	if s.frame == nil {
+		return stack{&frame{parent: before.frame}, s.global}
+	}
+	f := s.frame
+	for f.parent != nil {
+		f = f.parent
+	}
+	f.parent = before.frame
+	return s
+}
 
+// NewLexical returns an environment with a new scope inside of before. It
+// allocates the frames of the scope at once, and shares the global tables.
+func (before *Environment) NewLexical() Environment {
+	e := *before
+	f := new([6]frame)
+	e.BlockTag = before.BlockTag.extend(&f[0], before.BlockTag.frame)
+	e.TagbodyTag = before.TagbodyTag.extend(&f[1], before.TagbodyTag.frame)
+	e.Variable = before.Variable.extend(&f[2], before.Variable.frame)
+	e.Function = before.Function.extend(&f[3], before.Function.frame)
+	e.CatchTag = before.CatchTag.extend(&f[4], before.CatchTag.bound())
+	e.DynamicVariable = before.DynamicVariable.extend(&f[5], before.DynamicVariable.bound())
 	return e
 }
 
+// NewDynamic returns an environment for a call from before: it has the
+// dynamic bindings of before but only the global lexical ones.
 func (before *Environment) NewDynamic() Environment {
-	e := NewEnvironment(before.StandardInput, before.StandardOutput, before.ErrorOutput, before.Handler)
-
-	e.BlockTag = stack{before.BlockTag[0]}.Append(e.BlockTag)
-	e.TagbodyTag = stack{before.TagbodyTag[0]}.Append(e.TagbodyTag)
-	e.Variable = stack{before.Variable[0]}.Append(e.Variable)
-	e.Function = stack{before.Function[0]}.Append(e.Function)
-
-	e.Macro = stack{before.Macro[0]}.Append(e.Macro)
-	e.Class = stack{before.Class[0]}.Append(e.Class)
-	e.Special = stack{before.Special[0]}.Append(e.Special)
-	e.Constant = stack{before.Constant[0]}.Append(e.Constant)
-	e.Property = before.Property
-
-	e.CatchTag = before.CatchTag.Append(e.CatchTag)
-	e.DynamicVariable = before.DynamicVariable.Append(e.DynamicVariable)
-	e.StandardInput = before.StandardInput
-	e.StandardOutput = before.StandardOutput
-	e.ErrorOutput = before.ErrorOutput
-	e.Handler = before.Handler
-
+	e := *before
+	f := new([6]frame)
+	e.BlockTag = before.BlockTag.extend(&f[0], nil)
+	e.TagbodyTag = before.TagbodyTag.extend(&f[1], nil)
+	e.Variable = before.Variable.extend(&f[2], nil)
+	e.Function = before.Function.extend(&f[3], nil)
+	e.CatchTag = before.CatchTag.extend(&f[4], before.CatchTag.bound())
+	e.DynamicVariable = before.DynamicVariable.extend(&f[5], before.DynamicVariable.bound())
 	return e
 }
@@ -8,43 +8,141 @@ import (
 	"github.com/ta2gch/iris/runtime/ilos"
 )
 
-type stack []map[ilos.Instance]ilos.Instance
+// frame holds the bindings of one scope. Bindings are only ever added to a
+// frame, so the offset of a binding never changes.
+type frame struct {
+	parent *frame
+	keys   []ilos.Instance
+	values []ilos.Instance
+}
+
+// stack is a namespace: a chain of frames, innermost first, in front of the
+// global table of the namespace.
+type stack struct {
+	frame  *frame
+	global map[ilos.Instance]ilos.Instance
+}
+
+// Address locates a binding in the frames of a namespace: Offset in the frame
+// Depth frames out from the innermost one.
+type Address struct {
+	Depth, Offset int
+}
 
 func NewStack() stack {
-	return []map[ilos.Instance]ilos.Instance{map[ilos.Instance]ilos.Instance{}}
+	return stack{global: map[ilos.Instance]ilos.Instance{}}
 }
 
 func (s stack) Get(key ilos.Instance) (ilos.Instance, bool) {
-	for i := len(s) - 1; i >= 0; i-- {
-		if v, ok := s[i][key]; ok {
-			return v, true
+	for f := s.frame; f != nil; f = f.parent {
+		for i, k := range f.keys {
+			if k == key {
+				return f.values[i], true
+			}
 		}
 	}
-	return nil, false
+	v, ok := s.global[key]
+	return v, ok
 }
 
 func (s stack) Set(key, value ilos.Instance) bool {
-	for i := len(s) - 1; i >= 0; i-- {
-		if _, ok := s[i][key]; ok {
-			s[i][key] = value
-			return true
+	for f := s.frame; f != nil; f = f.parent {
+		for i, k := range f.keys {
+			if k == key {
+				f.values[i] = value
+				return true
+			}
 		}
 	}
+	if _, ok := s.global[key]; ok {
+		s.global[key] = value
+		return true
+	}
 	return false
 }
 
+// Define binds key to value in the innermost frame, or in the global table if
+// s has no frame. It returns false if key was already bound there.
 func (s stack) Define(key, value ilos.Instance) bool {
-	if _, ok := s[len(s)-1][key]; !ok {
-		s[len(s)-1][key] = value
-		return true
+	if s.frame == nil {
+		_, ok := s.global[key]
+		s.global[key] = value
+		return !ok
 	}
-	s[len(s)-1][key] = value
-	return false
+	f := s.frame
+	for i, k := range f.keys {
+		if k == key {
+			f.values[i] = value
+			return false
+		}
+	}
+	f.keys = append(f.keys, key)
+	f.values = append(f.values, value)
+	return true
+}
+
+// Bindings returns the keys bound in the frames of s, innermost first, and
+// their values. A binding shadowed by an inner one is left out.
+func (s stack) Bindings() ([]ilos.Instance, []ilos.Instance) {
+	keys, values := []ilos.Instance{}, []ilos.Instance{}
+	seen := map[ilos.Instance]bool{}
+	for f := s.frame; f != nil; f = f.parent {
+		for i, k := range f.keys {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+				values = append(values, f.values[i])
+			}
+		}
+	}
+	return keys, values
+}
+
+// Global returns the global table of s as a namespace of its own.
+func (s stack) Global() stack {
+	return stack{global: s.global}
+}
+
+// Lookup returns the address of the innermost binding of key, or false if key
+// is bound globally or not at all.
+func (s stack) Lookup(key ilos.Instance) (Address, bool) {
+	depth := 0
+	for f := s.frame; f != nil; f = f.parent {
+		for i, k := range f.keys {
+			if k == key {
+				return Address{depth, i}, true
+			}
+		}
+		depth++
+	}
+	return Address{}, false
 }
 
-func (s stack) Append(t stack) stack {
-	u := stack{}
-	u = append(u, s...)
-	u = append(u, t...)
-	return u
+// Ref returns the value at a if the binding there is of key. The caller must
+// know that no binding of key lies inside of a, as Lookup does.
+func (s stack) Ref(a Address, key ilos.Instance) (ilos.Instance, bool) {
+	f := s.frame
+	for i := 0; i < a.Depth && f != nil; i++ {
+		f = f.parent
+	}
+	if f == nil || a.Offset >= len(f.keys) || f.keys[a.Offset] != key {
+		return nil, false
+	}
+	return f.values[a.Offset], true
+}
+
+// extend returns s with a new empty frame inside of parent.
+func (s stack) extend(f *frame, parent *frame) stack {
+	f.parent = parent
+	return stack{f, s.global}
+}
+
+// bound returns the innermost frame of s that binds anything, so that a long
+// chain of calls does not pile up frames that bind nothing.
+func (s stack) bound() *frame {
+	f := s.frame
+	for f != nil && len(f.keys) == 0 {
+		f = f.parent
+	}
+	return f
 }
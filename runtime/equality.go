@@ -10,6 +10,7 @@ import (
 	"github.com/ta2gch/iris/runtime/env"
 	"github.com/ta2gch/iris/runtime/ilos"
 	"github.com/ta2gch/iris/runtime/ilos/class"
+	"github.com/ta2gch/iris/runtime/ilos/instance"
 )
 
 func isComparable(t reflect.Type) bool {
@@ -46,6 +47,18 @@ func Eq(e env.Environment, obj1, obj2 ilos.Instance) (ilos.Instance, ilos.Instan
 // same if there is no operation that could distinguish them (without modifying
 // them), and if modifying one would modify the other the same way.
 func Eql(e env.Environment, obj1, obj2 ilos.Instance) (ilos.Instance, ilos.Instance) {
+	if b1, ok := obj1.(instance.Bignum); ok {
+		if b2, ok := obj2.(instance.Bignum); ok && b1.Int().Cmp(b2.Int()) == 0 {
+			return T, nil
+		}
+		return Nil, nil
+	}
+	if r1, ok := obj1.(instance.Ratio); ok {
+		if r2, ok := obj2.(instance.Ratio); ok && r1.Rat().Cmp(r2.Rat()) == 0 {
+			return T, nil
+		}
+		return Nil, nil
+	}
 	t1, t2 := reflect.TypeOf(obj1), reflect.TypeOf(obj2)
 	if isComparable(t1) || isComparable(t2) {
 		if obj1 == obj2 {
@@ -5,6 +5,8 @@
 package runtime
 
 import (
+	"context"
+
 	"github.com/ta2gch/iris/runtime/env"
 	"github.com/ta2gch/iris/runtime/ilos"
 	"github.com/ta2gch/iris/runtime/ilos/class"
@@ -33,7 +35,7 @@ func evalArguments(e env.Environment, arguments ilos.Instance) (ilos.Instance, i
 
 }
 
-func evalLambda(e env.Environment, car, cdr ilos.Instance) (ilos.Instance, ilos.Instance, bool) {
+func evalLambda(e env.Environment, form, car, cdr ilos.Instance) (ilos.Instance, ilos.Instance, bool) {
 	// eval if lambda form
 	if ilos.InstanceOf(class.Cons, car) {
 		caar := car.(*instance.Cons).Car // Checked at the top of// This sentence
@@ -47,7 +49,12 @@ func evalLambda(e env.Environment, car, cdr ilos.Instance) (ilos.Instance, ilos.
 			if err != nil {
 				return nil, err, true
 			}
-			ret, err := fun.(instance.Applicable).Apply(e.NewDynamic(), arguments.(instance.List).Slice()...)
+			callee := e.NewDynamic()
+			callee.Call(&e, fun, arguments.(instance.List).Slice(), form, false)
+			if l, ok := fun.(*lambda); ok {
+				return &tailCall{l, callee, arguments.(instance.List).Slice()}, nil, true
+			}
+			ret, err := fun.(instance.Applicable).Apply(callee, arguments.(instance.List).Slice()...)
 			if err != nil {
 				return nil, err, true
 			}
@@ -84,7 +91,7 @@ func evalMacro(e env.Environment, car, cdr ilos.Instance) (ilos.Instance, ilos.I
 		if err != nil {
 			return nil, err, true
 		}
-		ret, err = Eval(e, ret)
+		ret, err = evalTail(e, ret)
 		if err != nil {
 			return nil, err, true
 		}
@@ -93,7 +100,7 @@ func evalMacro(e env.Environment, car, cdr ilos.Instance) (ilos.Instance, ilos.I
 	return nil, nil, false
 }
 
-func evalFunction(e env.Environment, car, cdr ilos.Instance) (ilos.Instance, ilos.Instance, bool) {
+func evalFunction(e env.Environment, form, car, cdr ilos.Instance) (ilos.Instance, ilos.Instance, bool) {
 	// get special instance has value of Function interface
 	var fun ilos.Instance
 	if f, ok := e.Function.Get(car); ok {
@@ -104,7 +111,12 @@ func evalFunction(e env.Environment, car, cdr ilos.Instance) (ilos.Instance, ilo
 		if err != nil {
 			return nil, err, true
 		}
-		ret, err := fun.(instance.Applicable).Apply(e.NewDynamic(), arguments.(instance.List).Slice()...)
+		callee := e.NewDynamic()
+		callee.Call(&e, car, arguments.(instance.List).Slice(), form, false)
+		if l, ok := fun.(*lambda); ok {
+			return &tailCall{l, callee, arguments.(instance.List).Slice()}, nil, true
+		}
+		ret, err := fun.(instance.Applicable).Apply(callee, arguments.(instance.List).Slice()...)
 		if err != nil {
 			return nil, err, true
 		}
@@ -121,7 +133,7 @@ func evalCons(e env.Environment, obj ilos.Instance) (ilos.Instance, ilos.Instanc
 	cdr := obj.(*instance.Cons).Cdr // Checked at the top of// This function
 
 	// eval if lambda form
-	if a, b, c := evalLambda(e, car, cdr); c {
+	if a, b, c := evalLambda(e, obj, car, cdr); c {
 		return a, b
 	}
 	// get special instance has value of Function interface
@@ -133,7 +145,7 @@ func evalCons(e env.Environment, obj ilos.Instance) (ilos.Instance, ilos.Instanc
 		return a, b
 	}
 	// get function instance has value of Function interface
-	if a, b, c := evalFunction(e, car, cdr); c {
+	if a, b, c := evalFunction(e, obj, car, cdr); c {
 		return a, b
 	}
 	return SignalCondition(e, instance.NewUndefinedFunction(e, car), Nil)
@@ -149,11 +161,53 @@ func evalVariable(e env.Environment, obj ilos.Instance) (ilos.Instance, ilos.Ins
 	return SignalCondition(e, instance.NewUndefinedVariable(e, obj), Nil)
 }
 
+// checkBudget counts one evaluation step and returns an
+// <evaluation-aborted> condition if the context of the evaluation is done or
+// its step budget is exhausted. The condition is not signaled, so handlers
+// can not resume an aborted evaluation.
+func checkBudget(e env.Environment) ilos.Instance {
+	b := e.Budget
+	if b == nil {
+		return nil
+	}
+	b.Steps++
+	if b.MaxSteps > 0 && b.Steps > b.MaxSteps {
+		return instance.NewEvaluationAborted(e, instance.NewSymbol(":STEP-LIMIT"))
+	}
+	select {
+	case <-b.Context.Done():
+		if b.Context.Err() == context.DeadlineExceeded {
+			return instance.NewEvaluationAborted(e, instance.NewSymbol(":TIMEOUT"))
+		}
+		return instance.NewEvaluationAborted(e, instance.NewSymbol(":CANCELED"))
+	default:
+		return nil
+	}
+}
+
+// EvalContext evaluates obj like Eval, but aborts with an <evaluation-aborted>
+// condition when ctx is done or when more than maxSteps forms have been
+// evaluated. A maxSteps of zero means no step limit.
+func EvalContext(ctx context.Context, e env.Environment, obj ilos.Instance, maxSteps int) (ilos.Instance, ilos.Instance) {
+	e.Budget = &env.Budget{Context: ctx, MaxSteps: maxSteps}
+	return Eval(e, obj)
+}
+
 // Eval evaluates any classs
 func Eval(e env.Environment, obj ilos.Instance) (ilos.Instance, ilos.Instance) {
+	return force(evalTail(e, obj))
+}
+
+// evalTail evaluates obj in tail position. A call of a function defined in
+// ISLisp is not made but returned as a *tailCall, which the caller has to
+// return in turn or make with force.
+func evalTail(e env.Environment, obj ilos.Instance) (ilos.Instance, ilos.Instance) {
 	if obj == Nil {
 		return Nil, nil
 	}
+	if err := checkBudget(e); err != nil {
+		return nil, err
+	}
 	if ilos.InstanceOf(class.Symbol, obj) {
 		ret, err := evalVariable(e, obj)
 		if err != nil {
@@ -164,7 +218,7 @@ func Eval(e env.Environment, obj ilos.Instance) (ilos.Instance, ilos.Instance) {
 	if ilos.InstanceOf(class.Cons, obj) {
 		ret, err := evalCons(e, obj)
 		if err != nil {
-			return nil, err
+			return nil, locate(obj, err)
 		}
 		return ret, nil
 	}
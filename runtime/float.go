@@ -6,6 +6,7 @@ package runtime
 
 import (
 	"math"
+	"math/big"
 
 	"github.com/ta2gch/iris/runtime/env"
 	"github.com/ta2gch/iris/runtime/ilos"
@@ -38,49 +39,115 @@ func Float(e env.Environment, x ilos.Instance) (ilos.Instance, ilos.Instance) {
 	if err != nil {
 		return nil, err
 	}
-	return instance.NewFloat(f), nil
+	return newFloat(e, f, false, "FLOAT", x)
 }
 
 // Floor returns the greatest integer less than or equal to x . That is, x is
 // truncated towards negative infinity. An error shall be signaled if x is not a
 // number (error-id. domain-error).
 func Floor(e env.Environment, x ilos.Instance) (ilos.Instance, ilos.Instance) {
-	f, _, err := convFloat64(e,x)
+	f, flt, err := convFloat64(e,x)
 	if err != nil {
 		return nil, err
 	}
-	return instance.NewInteger(int(math.Floor(f))), nil
+	if r, ok := x.(instance.Ratio); ok {
+		return floorRatio(r.Rat()), nil
+	}
+	if !flt {
+		return x, nil
+	}
+	return floatToInteger(e, x, math.Floor(f))
 }
 
 // Ceiling Returns the smallest integer that is not smaller than x. That is, x
 // is truncated towards positive infinity. An error shall be signaled if x is
 // not a number (error-id. domain-error).
 func Ceiling(e env.Environment, x ilos.Instance) (ilos.Instance, ilos.Instance) {
-	f, _, err := convFloat64(e,x)
+	f, flt, err := convFloat64(e,x)
 	if err != nil {
 		return nil, err
 	}
-	return instance.NewInteger(int(math.Ceil(f))), nil
+	if r, ok := x.(instance.Ratio); ok {
+		return ceilingRatio(r.Rat()), nil
+	}
+	if !flt {
+		return x, nil
+	}
+	return floatToInteger(e, x, math.Ceil(f))
 }
 
 // Truncate returns the integer between 0 and x (inclusive) that is nearest to
 // x. That is, x is truncated towards zero. An error shall be signaled if x is
 // not a number (error-id. domain-error).
 func Truncate(e env.Environment, x ilos.Instance) (ilos.Instance, ilos.Instance) {
-	f, _, err := convFloat64(e,x)
+	f, flt, err := convFloat64(e,x)
 	if err != nil {
 		return nil, err
 	}
-	return instance.NewInteger(int(math.Trunc(f))), nil
+	if r, ok := x.(instance.Ratio); ok {
+		return truncateRatio(r.Rat()), nil
+	}
+	if !flt {
+		return x, nil
+	}
+	return floatToInteger(e, x, math.Trunc(f))
 }
 
 // Round returns the integer nearest to x. If x is exactly halfway between two
 // integers, the even one is chosen. An error shall be signaled if x is not a
 // number (error-id. domain-error).
 func Round(e env.Environment, x ilos.Instance) (ilos.Instance, ilos.Instance) {
-	f, _, err := convFloat64(e,x)
+	f, flt, err := convFloat64(e,x)
+	if err != nil {
+		return nil, err
+	}
+	if r, ok := x.(instance.Ratio); ok {
+		return roundRatio(r.Rat()), nil
+	}
+	if !flt {
+		return x, nil
+	}
+	return floatToInteger(e, x, math.RoundToEven(f))
+}
+
+// floatToInteger returns the integral float f, computed from x, as an integer,
+// which is a bignum if f does not fit in an int.
+func floatToInteger(e env.Environment, x ilos.Instance, f float64) (ilos.Instance, ilos.Instance) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return SignalCondition(e, instance.NewDomainError(e, x, class.Number), Nil)
+	}
+	if f >= math.MinInt64 && f < math.MaxInt64 {
+		return instance.NewInteger(int(f)), nil
+	}
+	i, _ := big.NewFloat(f).Int(nil)
+	return instance.NewBigInteger(i), nil
+}
+
+// newFloat returns f, the result of operation on operands, as a float. Unless
+// e is in IEEE mode, an infinity signals <floating-point-overflow>, a denormal
+// signals <floating-point-underflow>, as does a zero if underflow is true, and
+// NaN signals <arithmetic-error>.
+func newFloat(e env.Environment, f float64, underflow bool, operation string, operands ...ilos.Instance) (ilos.Instance, ilos.Instance) {
+	if e.IEEE {
+		return instance.NewFloat(f), nil
+	}
+	var condition func(env.Environment, ilos.Instance, ilos.Instance) ilos.Instance
+	switch {
+	case math.IsNaN(f):
+		condition = instance.NewArithmeticError
+	case math.IsInf(f, 0):
+		condition = instance.NewFloatingPointOverflow
+	case f == 0 && underflow, f != 0 && math.Abs(f) < minNormalFloat:
+		condition = instance.NewFloatingPointUnderflow
+	default:
+		return instance.NewFloat(f), nil
+	}
+	arguments, err := List(e, operands...)
 	if err != nil {
 		return nil, err
 	}
-	return instance.NewInteger(int(math.Floor(f + .5))), nil
+	return SignalCondition(e, condition(e, instance.NewSymbol(operation), arguments), Nil)
 }
+
+// minNormalFloat is the least positive normal float64.
+const minNormalFloat = 0x1p-1022
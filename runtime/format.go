@@ -2,8 +2,11 @@ package runtime
 
 import (
 	"fmt"
-	"regexp"
+	"math"
+	"math/big"
 	"strconv"
+	"strings"
+	"unicode"
 
 	"github.com/ta2gch/iris/runtime/env"
 	"github.com/ta2gch/iris/runtime/ilos"
@@ -16,21 +19,71 @@ func FormatObject(e env.Environment, stream, object, escapep ilos.Instance) (ilo
 		return SignalCondition(e, instance.NewDomainError(e, stream, class.Stream), Nil)
 	}
 	if escapep == T {
-		fmt.Fprint(stream.(instance.Stream), object)
-		return Nil, nil
-	}
-	if ok, _ := Stringp(e, object); ok == T {
-		fmt.Fprint(stream.(instance.Stream), string(object.(instance.String)))
+		if v, ok := e.DynamicVariable.Get(instance.NewSymbol("*PRINT-READABLY*")); ok && v != Nil {
+			if unreadable := unreadableObject(object); unreadable != nil {
+				return SignalCondition(e, instance.NewPrintNotReadable(e, unreadable), Nil)
+			}
+		}
+		if err := printObject(e, stream, object, true); err != nil {
+			return nil, err
+		}
 		return Nil, nil
 	}
-	if ok, _ := Characterp(e, object); ok == T {
-		fmt.Fprint(stream.(instance.Stream), string(object.(instance.Character)))
-		return Nil, nil
+	if err := printObject(e, stream, object, false); err != nil {
+		return nil, err
 	}
-	fmt.Fprint(stream.(instance.Stream), object)
 	return Nil, nil
 }
 
+// unreadableObject returns the first object in object which the reader cannot
+// read back from its printed representation, or nil if there is none.
+func unreadableObject(object ilos.Instance) ilos.Instance {
+	switch object := object.(type) {
+	case instance.Integer, instance.Bignum, instance.Ratio, instance.Character, instance.String, instance.Symbol, *instance.Null:
+		return nil
+	case instance.Float:
+		if math.IsInf(float64(object), 0) || math.IsNaN(float64(object)) {
+			return object
+		}
+		return nil
+	case *instance.Cons:
+		if unreadable := unreadableObject(object.Car); unreadable != nil {
+			return unreadable
+		}
+		return unreadableObject(object.Cdr)
+	case instance.GeneralVector:
+		for _, elt := range object {
+			if unreadable := unreadableObject(elt); unreadable != nil {
+				return unreadable
+			}
+		}
+		return nil
+	case *instance.HashTable:
+		var unreadable ilos.Instance
+		object.Each(func(key, value ilos.Instance) {
+			if unreadable == nil {
+				if unreadable = unreadableObject(key); unreadable == nil {
+					unreadable = unreadableObject(value)
+				}
+			}
+		})
+		return unreadable
+	case instance.Regexp:
+		return nil
+	case *instance.GeneralArrayStar:
+		if object.Vector == nil {
+			return unreadableObject(object.Scalar)
+		}
+		for _, elt := range object.Vector {
+			if unreadable := unreadableObject(elt); unreadable != nil {
+				return unreadable
+			}
+		}
+		return nil
+	}
+	return object
+}
+
 func FormatChar(e env.Environment, stream, object ilos.Instance) (ilos.Instance, ilos.Instance) {
 	if ok, _ := OpenStreamP(e, stream); ok == Nil {
 		return SignalCondition(e, instance.NewDomainError(e, stream, class.Stream), Nil)
@@ -49,7 +102,7 @@ func FormatFloat(e env.Environment, stream, object ilos.Instance) (ilos.Instance
 	if ok, _ := Floatp(e, object); ok == Nil {
 		return SignalCondition(e, instance.NewDomainError(e, object, class.Float), Nil)
 	}
-	fmt.Fprint(stream.(instance.Stream), float64(object.(instance.Float)))
+	fmt.Fprint(stream.(instance.Stream), object)
 	return Nil, nil
 }
 
@@ -60,16 +113,17 @@ func FormatInteger(e env.Environment, stream, object, radix ilos.Instance) (ilos
 	if ok, _ := Integerp(e, object); ok == Nil {
 		return SignalCondition(e, instance.NewDomainError(e, object, class.Integer), Nil)
 	}
-	if ok, _ := Integerp(e, radix); ok == Nil {
+	if r, ok := radix.(instance.Integer); !ok || r < 2 || r > 36 {
 		return SignalCondition(e, instance.NewDomainError(e, radix, class.Integer), Nil)
 	}
-	i := int(object.(instance.Integer))
-	r := int(radix.(instance.Integer))
-	fmt.Fprint(stream.(instance.Stream), strconv.FormatInt(int64(i), r))
+	fmt.Fprint(stream.(instance.Stream), bigInt(object).Text(int(radix.(instance.Integer))))
 	return Nil, nil
 }
 
 func FormatTab(e env.Environment, stream, num ilos.Instance) (ilos.Instance, ilos.Instance) {
+	if err := ensureFixnum(e, num); err != nil {
+		return nil, err
+	}
 	n := int(num.(instance.Integer))
 	if *stream.(instance.Stream).Column < n {
 		for i := *stream.(instance.Stream).Column; i < n; i++ {
@@ -89,112 +143,839 @@ func FormatFreshLine(e env.Environment, stream ilos.Instance) (ilos.Instance, il
 	return Nil, nil
 }
 
+// Format outputs formatArguments to stream under the control of formatString.
+// A directive is a tilde, optional prefix parameters separated by commas,
+// optional : and @ modifiers and a character. A parameter is an integer, a
+// character quoted by ', V for the next argument or # for the number of
+// arguments left.
+//
+//	~mincol,colinc,minpad,padcharA  ~S  the object, padded on the right or,
+//	                                    with @, on the left
+//	~mincol,padchar,commachar,intervalD  ~B ~O ~X  the integer, with : in
+//	                                    groups and with @ signed
+//	~radix,mincol,padchar,commachar,intervalR
+//	~C                                  the character
+//	~w,d,k,overflowchar,padcharF        the fixed-format float
+//	~w,d,e,k,overflowchar,padchar,exptcharE  the exponential float
+//	~w,d,e,k,overflowchar,padchar,exptcharG  ~F or ~E, as fits; with no
+//	                                    parameters, as by format-float
+//	~colnum,colincT ~colrel,colinc@T    tabulation
+//	~n% ~n& ~n~                         newlines, a fresh line and tildes
+//	~newline                            ignores the newline and the following
+//	                                    whitespace
+//	~[clause0~;clause1~:;default~]      the clause chosen by the argument;
+//	                                    ~:[false~;true~] and ~@[if true~]
+//	~n{body~}                           body for each element of the list;
+//	                                    ~:{ for sublists, ~@{ for the remaining
+//	                                    arguments, ~:} at least once; an empty
+//	                                    body is the next argument
+//	~^                                  ends ~{ or the format if no arguments
+//	                                    remain
+//	~(text~)                            text in lower case, ~:( capitalized,
+//	                                    ~@( with the first word capitalized
+//	                                    and ~:@( in upper case
+//	~n* ~n:* ~n@*                       skips, backs up or goes to arguments
 func Format(e env.Environment, stream, formatString ilos.Instance, formatArguments ...ilos.Instance) (ilos.Instance, ilos.Instance) {
+	if stream == Nil {
+		// The output goes to a string, which format returns
+		var b strings.Builder
+		if _, err := Format(e, instance.NewStream(nil, &b), formatString, formatArguments...); err != nil {
+			return nil, err
+		}
+		return instance.NewString([]rune(b.String())), nil
+	}
+	if ok, _ := OpenStreamP(e, stream); ok == Nil {
+		return SignalCondition(e, instance.NewDomainError(e, stream, class.Stream), Nil)
+	}
 	if ok, _ := Stringp(e, formatString); ok == Nil {
 		return SignalCondition(e, instance.NewDomainError(e, formatString, class.String), Nil)
 	}
-	str := string(formatString.(instance.String))
-	re := regexp.MustCompile(`~(?:[0-9]+[RT]|.)`)
-	start, end, index := 0, 0, 0
-	for loc := re.FindStringIndex(str[start:]); loc != nil; loc = re.FindStringIndex(str[start:]) {
-		base := start
-		start = base + 0
-		end = base + loc[0]
-		if _, err := FormatObject(e, stream, instance.NewString([]rune(str[start:end])), Nil); err != nil {
-			return nil, err
+	p := &formatParser{e: e, control: formatString.(instance.String)}
+	directives, end, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	if end != nil {
+		return nil, p.error("~" + string(end.char) + " without its opening directive")
+	}
+	f := &formatter{e: e, stream: stream, arguments: formatArguments}
+	if _, err := f.format(directives); err != nil {
+		return nil, err
+	}
+	return Nil, nil
+}
+
+// directive is a directive of a format string, or literal text if char is 0.
+type directive struct {
+	text       string
+	char       rune
+	parameters []formatParameter
+	colon, at  bool
+	clauses    [][]*directive // the clauses of ~[ and the bodies of ~{ and ~(
+	defaults   bool           // the last clause of ~[ follows ~:;
+	once       bool           // the body of ~{ ends with ~:}
+}
+
+// formatParameter is a prefix parameter of a directive: a value if kind is
+// 'n', V or #, or omitted if kind is 0.
+type formatParameter struct {
+	kind  rune
+	value int
+}
+
+type formatParser struct {
+	e       env.Environment
+	control instance.String
+	pos     int
+}
+
+// parse parses directives up to the end of the control string or up to a
+// ~;, ~], ~} or ~), which it returns too.
+func (p *formatParser) parse() ([]*directive, *directive, ilos.Instance) {
+	directives := []*directive{}
+	for p.pos < len(p.control) {
+		if p.control[p.pos] != '~' {
+			i := p.pos
+			for p.pos < len(p.control) && p.control[p.pos] != '~' {
+				p.pos++
+			}
+			directives = append(directives, &directive{text: string(p.control[i:p.pos])})
+			continue
+		}
+		d, err := p.directive()
+		if err != nil {
+			return nil, nil, err
+		}
+		switch d.char {
+		case ';', ']', '}', ')':
+			return directives, d, nil
+		case '[', '{', '(':
+			if err := p.clauses(d); err != nil {
+				return nil, nil, err
+			}
+		case '\n':
+			for p.pos < len(p.control) && p.control[p.pos] != '\n' && unicode.IsSpace(p.control[p.pos]) {
+				if d.colon {
+					d.text += string(p.control[p.pos])
+				}
+				p.pos++
+			}
+			if d.at {
+				d.text = "\n" + d.text
+			}
 		}
-		start = base + loc[0]
-		end = base + loc[1]
-		var err ilos.Instance
-		switch str[start:end] {
-		case "~A":
-			if index >= len(formatArguments) {
-				_, err = SignalCondition(e, instance.NewArityError(e), Nil)
-			} else {
-				_, err = FormatObject(e, stream, formatArguments[index], Nil)
-				index++
-			}
-		case "~B":
-			if index >= len(formatArguments) {
-				_, err = SignalCondition(e, instance.NewArityError(e), Nil)
-			} else {
-				_, err = FormatInteger(e, stream, formatArguments[index], instance.NewInteger(2))
-				index++
-			}
-		case "~C":
-			if index >= len(formatArguments) {
-				_, err = SignalCondition(e, instance.NewArityError(e), Nil)
-			} else {
-				_, err = FormatChar(e, stream, formatArguments[index])
-				index++
-			}
-		case "~D":
-			if index >= len(formatArguments) {
-				_, err = SignalCondition(e, instance.NewArityError(e), Nil)
-			} else {
-				_, err = FormatInteger(e, stream, formatArguments[index], instance.NewInteger(10))
-				index++
-			}
-		case "~G":
-			if index >= len(formatArguments) {
-				_, err = SignalCondition(e, instance.NewArityError(e), Nil)
-			} else {
-				_, err = FormatFloat(e, stream, formatArguments[index])
-				index++
-			}
-		case "~O":
-			if index >= len(formatArguments) {
-				_, err = SignalCondition(e, instance.NewArityError(e), Nil)
-			} else {
-				_, err = FormatInteger(e, stream, formatArguments[index], instance.NewInteger(8))
-				index++
-			}
-		case "~S":
-			if index >= len(formatArguments) {
-				_, err = SignalCondition(e, instance.NewArityError(e), Nil)
-			} else {
-				_, err = FormatObject(e, stream, formatArguments[index], T)
-				index++
-			}
-		case "~X":
-			if index >= len(formatArguments) {
-				_, err = SignalCondition(e, instance.NewArityError(e), Nil)
-			} else {
-				_, err = FormatInteger(e, stream, formatArguments[index], instance.NewInteger(16))
-				index++
-			}
-		case "~%":
-			_, err = FormatChar(e, stream, instance.NewCharacter('\n'))
-		case "~&":
-			_, err = FormatFreshLine(e, stream)
-		case "~~":
-			_, err = FormatChar(e, stream, instance.NewCharacter('~'))
+		directives = append(directives, d)
+	}
+	return directives, nil, nil
+}
+
+// clauses parses the clauses of d up to its closing directive.
+func (p *formatParser) clauses(d *directive) ilos.Instance {
+	close := map[rune]rune{'[': ']', '{': '}', '(': ')'}[d.char]
+	for {
+		clause, end, err := p.parse()
+		if err != nil {
+			return err
+		}
+		if end == nil {
+			return p.error("~" + string(d.char) + " without ~" + string(close))
+		}
+		d.clauses = append(d.clauses, clause)
+		switch {
+		case end.char == close:
+			d.once = end.colon
+			return nil
+		case end.char == ';' && d.char == '[':
+			if d.defaults {
+				return p.error("~:; before the last clause of ~[")
+			}
+			d.defaults = end.colon
 		default:
-			s := str[start:end]
-			if len(s) > 2 {
-				if s[len(s)-1] == 'R' {
-					if index >= len(formatArguments) {
-						_, err = SignalCondition(e, instance.NewArityError(e), Nil)
-					} else {
-						n, _ := strconv.Atoi(s[1 : len(s)-1])
-						if n < 2 || 36 < n {
-							_, err = SignalCondition(e, instance.NewDomainError(e, instance.NewInteger(n), class.Integer), Nil)
-						} else {
-							_, err = FormatInteger(e, stream, formatArguments[index], instance.NewInteger(n))
-							index++
-						}
-					}
+			return p.error("~" + string(end.char) + " in ~" + string(d.char))
+		}
+	}
+}
+
+// directive parses the directive at the tilde at p.pos.
+func (p *formatParser) directive() (*directive, ilos.Instance) {
+	d := &directive{}
+	p.pos++
+	for {
+		param := formatParameter{}
+		switch c := p.peek(); {
+		case c == '\'':
+			if p.pos+1 >= len(p.control) {
+				return nil, p.error("~ at the end")
+			}
+			param = formatParameter{'n', int(p.control[p.pos+1])}
+			p.pos += 2
+		case c == 'v' || c == 'V' || c == '#':
+			param.kind = unicode.ToUpper(c)
+			p.pos++
+		case c == '-' || c == '+' || '0' <= c && c <= '9':
+			i := p.pos
+			p.pos++
+			for '0' <= p.peek() && p.peek() <= '9' {
+				p.pos++
+			}
+			n, err := strconv.Atoi(string(p.control[i:p.pos]))
+			if err != nil {
+				return nil, p.error("bad parameter " + string(p.control[i:p.pos]))
+			}
+			param = formatParameter{'n', n}
+		}
+		if p.peek() != ',' {
+			if param.kind != 0 {
+				d.parameters = append(d.parameters, param)
+			}
+			break
+		}
+		d.parameters = append(d.parameters, param)
+		p.pos++
+	}
+	for {
+		if p.peek() == ':' {
+			d.colon = true
+		} else if p.peek() == '@' {
+			d.at = true
+		} else {
+			break
+		}
+		p.pos++
+	}
+	if p.pos >= len(p.control) {
+		return nil, p.error("~ at the end")
+	}
+	d.char = unicode.ToUpper(p.control[p.pos])
+	p.pos++
+	if !strings.ContainsRune("ASDBOXRCFEGT%&~\n[;]{}()^*|", d.char) {
+		return nil, p.error("unknown directive ~" + string(d.char))
+	}
+	return d, nil
+}
+
+func (p *formatParser) peek() rune {
+	if p.pos < len(p.control) {
+		return p.control[p.pos]
+	}
+	return 0
+}
+
+// error signals that the control string is malformed.
+func (p *formatParser) error(message string) ilos.Instance {
+	arguments := instance.NewCons(instance.NewString([]rune(message)), instance.NewCons(p.control, Nil))
+	_, err := SignalCondition(p.e, instance.NewSimpleError(p.e, instance.NewString([]rune("~A in ~S")), arguments), Nil)
+	return err
+}
+
+// formatter executes directives with the arguments from index on.
+type formatter struct {
+	e         env.Environment
+	stream    ilos.Instance
+	arguments []ilos.Instance
+	index     int
+}
+
+// next returns the next argument, or signals that there is none.
+func (f *formatter) next() (ilos.Instance, ilos.Instance) {
+	if f.index >= len(f.arguments) {
+		return SignalCondition(f.e, instance.NewArityError(f.e), Nil)
+	}
+	f.index++
+	return f.arguments[f.index-1], nil
+}
+
+func (f *formatter) write(s string) {
+	fmt.Fprint(f.stream.(instance.Stream), s)
+}
+
+// parameters returns the values of the parameters of d, taking V from the
+// arguments. An omitted parameter is nil.
+func (f *formatter) parameters(d *directive) ([]*int, ilos.Instance) {
+	values := make([]*int, len(d.parameters))
+	for i, p := range d.parameters {
+		switch p.kind {
+		case 'n':
+			n := p.value
+			values[i] = &n
+		case '#':
+			n := len(f.arguments) - f.index
+			values[i] = &n
+		case 'V':
+			arg, err := f.next()
+			if err != nil {
+				return nil, err
+			}
+			switch arg := arg.(type) {
+			case instance.Integer:
+				n := int(arg)
+				values[i] = &n
+			case instance.Character:
+				n := int(arg)
+				values[i] = &n
+			default:
+				if arg != Nil {
+					_, err := SignalCondition(f.e, instance.NewDomainError(f.e, arg, class.Integer), Nil)
+					return nil, err
 				}
-				if s[len(s)-1] == 'T' {
-					n, _ := strconv.Atoi(s[1 : len(s)-1])
-					_, err = FormatTab(e, stream, instance.NewInteger(n))
+			}
+		}
+	}
+	return values, nil
+}
+
+// parameter returns the i-th of ps, or def if it is omitted.
+func parameter(ps []*int, i int, def int) int {
+	if i < len(ps) && ps[i] != nil {
+		return *ps[i]
+	}
+	return def
+}
+
+// format executes directives. It returns true if ~^ ended them.
+func (f *formatter) format(directives []*directive) (bool, ilos.Instance) {
+	for _, d := range directives {
+		if d.char == 0 {
+			f.write(d.text)
+			continue
+		}
+		ps, err := f.parameters(d)
+		if err != nil {
+			return false, err
+		}
+		switch d.char {
+		case 'A', 'S':
+			err = f.formatObject(d, ps)
+		case 'D', 'B', 'O', 'X', 'R':
+			err = f.formatInteger(d, ps)
+		case 'C':
+			var arg ilos.Instance
+			if arg, err = f.next(); err == nil {
+				_, err = FormatChar(f.e, f.stream, arg)
+			}
+		case 'F', 'E', 'G':
+			err = f.formatFloat(d, ps)
+		case 'T':
+			err = f.formatTab(d, ps)
+		case '%':
+			f.write(strings.Repeat("\n", parameter(ps, 0, 1)))
+		case '&':
+			if n := parameter(ps, 0, 1); n > 0 {
+				if _, err = FormatFreshLine(f.e, f.stream); err == nil {
+					f.write(strings.Repeat("\n", n-1))
 				}
 			}
+		case '~':
+			f.write(strings.Repeat("~", parameter(ps, 0, 1)))
+		case '|':
+			f.write(strings.Repeat("\f", parameter(ps, 0, 1)))
+		case '\n':
+			f.write(d.text)
+		case '*':
+			err = f.formatGoto(d, ps)
+		case '^':
+			if f.escape(ps) {
+				return true, nil
+			}
+		case '[':
+			var escaped bool
+			if escaped, err = f.formatConditional(d, ps); escaped {
+				return true, err
+			}
+		case '{':
+			err = f.formatIteration(d, ps)
+		case '(':
+			var escaped bool
+			if escaped, err = f.formatCase(d); escaped {
+				return true, err
+			}
 		}
 		if err != nil {
-			return nil, err
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// pad pads s with padchar to at least mincol characters, adding minpad
+// characters and then colinc characters at a time, on the left if left is
+// true.
+func pad(s string, mincol, colinc, minpad int, padchar rune, left bool) string {
+	n := minpad
+	for colinc > 0 && len([]rune(s))+n < mincol {
+		n += colinc
+	}
+	padding := strings.Repeat(string(padchar), n)
+	if left {
+		return padding + s
+	}
+	return s + padding
+}
+
+// print returns the text FormatObject writes for object.
+func (f *formatter) print(object, escapep ilos.Instance) (string, ilos.Instance) {
+	var b strings.Builder
+	if _, err := FormatObject(f.e, instance.NewStream(nil, &b), object, escapep); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func (f *formatter) formatObject(d *directive, ps []*int) ilos.Instance {
+	arg, err := f.next()
+	if err != nil {
+		return err
+	}
+	escapep := Nil
+	if d.char == 'S' {
+		escapep = T
+	}
+	s, err := f.print(arg, escapep)
+	if err != nil {
+		return err
+	}
+	f.write(pad(s, parameter(ps, 0, 0), parameter(ps, 1, 1), parameter(ps, 2, 0), rune(parameter(ps, 3, ' ')), d.at))
+	return nil
+}
+
+func (f *formatter) formatInteger(d *directive, ps []*int) ilos.Instance {
+	radix := map[rune]int{'D': 10, 'B': 2, 'O': 8, 'X': 16}[d.char]
+	if d.char == 'R' {
+		if len(ps) == 0 || ps[0] == nil {
+			return f.parameterError(d)
 		}
-		start = end
+		radix, ps = *ps[0], ps[1:]
+		if radix < 2 || 36 < radix {
+			_, err := SignalCondition(f.e, instance.NewDomainError(f.e, instance.NewInteger(radix), class.Integer), Nil)
+			return err
+		}
+	}
+	arg, err := f.next()
+	if err != nil {
+		return err
+	}
+	if ok, _ := Integerp(f.e, arg); ok == Nil {
+		_, err := SignalCondition(f.e, instance.NewDomainError(f.e, arg, class.Integer), Nil)
+		return err
+	}
+	z := bigInt(arg)
+	digits := new(big.Int).Abs(z).Text(radix)
+	if d.colon {
+		comma, interval := string(rune(parameter(ps, 2, ','))), parameter(ps, 3, 3)
+		for i := len(digits) - interval; interval > 0 && i > 0; i -= interval {
+			digits = digits[:i] + comma + digits[i:]
+		}
+	}
+	if z.Sign() < 0 {
+		digits = "-" + digits
+	} else if d.at {
+		digits = "+" + digits
+	}
+	f.write(pad(digits, parameter(ps, 0, 0), 1, 0, rune(parameter(ps, 1, ' ')), true))
+	return nil
+}
+
+func (f *formatter) formatFloat(d *directive, ps []*int) ilos.Instance {
+	arg, err := f.next()
+	if err != nil {
+		return err
+	}
+	if d.char == 'G' && len(ps) == 0 {
+		_, err := FormatFloat(f.e, f.stream, arg)
+		return err
+	}
+	x, _, err := convFloat64(f.e, arg)
+	if err != nil {
+		return err
+	}
+	var s string
+	switch d.char {
+	case 'F':
+		s = fixedFloat(x, ps[:min(len(ps), 5)], d.at)
+	case 'E':
+		s = exponentialFloat(x, ps, d.at)
+	case 'G':
+		s = generalFloat(x, ps, d.at)
+	}
+	f.write(s)
+	return nil
+}
+
+// fixedFloat formats x for ~w,d,k,overflowchar,padcharF.
+func fixedFloat(x float64, ps []*int, sign bool) string {
+	x *= math.Pow10(parameter(ps, 2, 0))
+	w := parameter(ps, 0, -1)
+	var s string
+	switch {
+	case len(ps) > 1 && ps[1] != nil:
+		s = strconv.FormatFloat(math.Abs(x), 'f', *ps[1], 64)
+		if *ps[1] == 0 {
+			s += "."
+		}
+	default:
+		s = strconv.FormatFloat(math.Abs(x), 'f', -1, 64)
+		if !strings.Contains(s, ".") {
+			s += ".0"
+		}
+		if w >= 0 && len(s)+signWidth(x, sign) > w {
+			n := w - signWidth(x, sign) - strings.Index(s, ".") - 1
+			s = strconv.FormatFloat(math.Abs(x), 'f', max(n, 1), 64)
+		}
+	}
+	return fitFloat(signed(s, x, sign), w, ps, 3)
+}
+
+// exponentialFloat formats x for ~w,d,e,k,overflowchar,padchar,exptcharE.
+func exponentialFloat(x float64, ps []*int, sign bool) string {
+	k := parameter(ps, 3, 1)
+	prec := -1
+	if len(ps) > 1 && ps[1] != nil {
+		prec = *ps[1]
+		if k <= 0 {
+			prec += k - 1
+		}
+		prec = max(prec, 0)
+	}
+	// m is d.ddd, so x is 0.dddd times 10 to the exponent+1
+	m := strconv.FormatFloat(math.Abs(x), 'e', prec, 64)
+	i := strings.IndexByte(m, 'e')
+	exponent, _ := strconv.Atoi(m[i+1:])
+	digits := strings.Replace(m[:i], ".", "", 1)
+	var s string
+	if k > 0 {
+		for len(digits) < k+1 {
+			digits += "0"
+		}
+		s = digits[:k] + "." + digits[k:]
+		exponent -= k - 1
+	} else {
+		s = "0." + strings.Repeat("0", -k) + digits
+		exponent += 1 - k
+	}
+	if len(ps) > 1 && ps[1] != nil {
+		frac := *ps[1]
+		if k > 0 {
+			frac -= k - 1
+		}
+		if n := strings.IndexByte(s, '.') + 1 + frac; n <= len(s) {
+			s = s[:n]
+		} else {
+			s += strings.Repeat("0", n-len(s))
+		}
+	}
+	if x == 0 {
+		exponent = 0
+	}
+	e := strconv.Itoa(abs(exponent))
+	if n := parameter(ps, 2, 0); len(e) < n {
+		e = strings.Repeat("0", n-len(e)) + e
+	}
+	if exponent < 0 {
+		e = "-" + e
+	} else {
+		e = "+" + e
 	}
-	return FormatObject(e, stream, instance.NewString([]rune(str[start:])), Nil)
+	return fitFloat(signed(s, x, sign)+string(rune(parameter(ps, 6, 'e')))+e, parameter(ps, 0, -1), ps, 4)
+}
+
+// generalFloat formats x for ~w,d,e,k,overflowchar,padchar,exptcharG, with
+// ~F if the digits of x fit without an exponent, or else with ~E.
+func generalFloat(x float64, ps []*int, sign bool) string {
+	n := 0
+	if x != 0 {
+		n = int(math.Floor(math.Log10(math.Abs(x)))) + 1
+	}
+	ee := 4
+	if len(ps) > 2 && ps[2] != nil {
+		ee = *ps[2] + 2
+	}
+	d := parameter(ps, 1, -1)
+	if d < 0 {
+		q := len(strings.Trim(strings.Replace(strconv.FormatFloat(math.Abs(x), 'f', -1, 64), ".", "", 1), "0"))
+		d = max(q, min(n, 7))
+	}
+	if dd := d - n; 0 <= dd && dd <= d {
+		fixed := make([]*int, 5)
+		if w := parameter(ps, 0, -1); w >= 0 {
+			ww := w - ee
+			fixed[0] = &ww
+		}
+		fixed[1] = &dd
+		if len(ps) > 4 {
+			fixed[3] = ps[4]
+		}
+		if len(ps) > 5 {
+			fixed[4] = ps[5]
+		}
+		return fixedFloat(x, fixed, sign) + strings.Repeat(" ", ee)
+	}
+	return exponentialFloat(x, ps, sign)
+}
+
+func signWidth(x float64, sign bool) int {
+	if x < 0 || math.Signbit(x) || sign {
+		return 1
+	}
+	return 0
+}
+
+func signed(s string, x float64, sign bool) string {
+	if math.Signbit(x) {
+		return "-" + s
+	}
+	if sign {
+		return "+" + s
+	}
+	return s
+}
+
+// fitFloat pads s to the width w, or fills the width with the overflow
+// character ps[overflow] if s does not fit.
+func fitFloat(s string, w int, ps []*int, overflow int) string {
+	if w < 0 {
+		return s
+	}
+	if len(s) > w {
+		if i := strings.Index(s, "0."); (i == 0 || i == 1) && len(s)-1 <= w {
+			s = s[:i] + s[i+1:]
+		} else if len(ps) > overflow && ps[overflow] != nil {
+			return strings.Repeat(string(rune(*ps[overflow])), w)
+		}
+	}
+	return pad(s, w, 1, 0, rune(parameter(ps, overflow+1, ' ')), true)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func (f *formatter) formatTab(d *directive, ps []*int) ilos.Instance {
+	column := *f.stream.(instance.Stream).Column
+	if d.at {
+		colrel, colinc := parameter(ps, 0, 1), parameter(ps, 1, 1)
+		n := column + colrel
+		if colinc > 1 && n%colinc != 0 {
+			n += colinc - n%colinc
+		}
+		f.write(strings.Repeat(" ", n-column))
+		return nil
+	}
+	colnum := parameter(ps, 0, 1)
+	if len(ps) < 2 || ps[1] == nil {
+		_, err := FormatTab(f.e, f.stream, instance.NewInteger(colnum))
+		return err
+	}
+	n := colnum
+	if colinc := *ps[1]; column >= colnum {
+		n = column
+		if colinc > 0 {
+			n = colnum + (column-colnum+colinc)/colinc*colinc
+		}
+	}
+	f.write(strings.Repeat(" ", n-column))
+	return nil
+}
+
+func (f *formatter) formatGoto(d *directive, ps []*int) ilos.Instance {
+	n := f.index + parameter(ps, 0, 1)
+	switch {
+	case d.at:
+		n = parameter(ps, 0, 0)
+	case d.colon:
+		n = f.index - parameter(ps, 0, 1)
+	}
+	if n < 0 || n > len(f.arguments) {
+		_, err := SignalCondition(f.e, instance.NewIndexOutOfRange(f.e), Nil)
+		return err
+	}
+	f.index = n
+	return nil
+}
+
+// escape returns true if ~^ with the parameters ps ends the directives.
+func (f *formatter) escape(ps []*int) bool {
+	switch len(ps) {
+	case 0:
+		return f.index >= len(f.arguments)
+	case 1:
+		return parameter(ps, 0, 0) == 0
+	case 2:
+		return parameter(ps, 0, 0) == parameter(ps, 1, 0)
+	}
+	a, b, c := parameter(ps, 0, 0), parameter(ps, 1, 0), parameter(ps, 2, 0)
+	return a <= b && b <= c
+}
+
+func (f *formatter) formatConditional(d *directive, ps []*int) (bool, ilos.Instance) {
+	var clause []*directive
+	switch {
+	case d.at:
+		if len(d.clauses) != 1 {
+			return false, f.parameterError(d)
+		}
+		arg, err := f.next()
+		if err != nil {
+			return false, err
+		}
+		if arg == Nil {
+			return false, nil
+		}
+		f.index--
+		clause = d.clauses[0]
+	case d.colon:
+		if len(d.clauses) != 2 {
+			return false, f.parameterError(d)
+		}
+		arg, err := f.next()
+		if err != nil {
+			return false, err
+		}
+		clause = d.clauses[0]
+		if arg != Nil {
+			clause = d.clauses[1]
+		}
+	default:
+		n := 0
+		if len(ps) > 0 && ps[0] != nil {
+			n = *ps[0]
+		} else {
+			arg, err := f.next()
+			if err != nil {
+				return false, err
+			}
+			if err := ensureFixnum(f.e, arg); err != nil {
+				return false, err
+			}
+			n = int(arg.(instance.Integer))
+		}
+		switch {
+		case 0 <= n && n < len(d.clauses) && !(d.defaults && n == len(d.clauses)-1):
+			clause = d.clauses[n]
+		case d.defaults:
+			clause = d.clauses[len(d.clauses)-1]
+		}
+	}
+	return f.format(clause)
+}
+
+func (f *formatter) formatIteration(d *directive, ps []*int) ilos.Instance {
+	body := d.clauses[0]
+	if len(body) == 0 {
+		arg, err := f.next()
+		if err != nil {
+			return err
+		}
+		if err := ensure(f.e, class.String, arg); err != nil {
+			return err
+		}
+		p := &formatParser{e: f.e, control: arg.(instance.String)}
+		directives, end, err := p.parse()
+		if err != nil {
+			return err
+		}
+		if end != nil {
+			return p.error("~" + string(end.char) + " without its opening directive")
+		}
+		body = directives
+	}
+	arguments := f
+	if !d.at {
+		arg, err := f.next()
+		if err != nil {
+			return err
+		}
+		list, err := f.list(arg)
+		if err != nil {
+			return err
+		}
+		arguments = &formatter{e: f.e, stream: f.stream, arguments: list}
+	}
+	limit := parameter(ps, 0, -1)
+	for i := 0; limit < 0 || i < limit; i++ {
+		if arguments.index >= len(arguments.arguments) && !(i == 0 && d.once) {
+			return nil
+		}
+		g, index := arguments, arguments.index
+		if d.colon {
+			arg, err := arguments.next()
+			if err != nil {
+				return err
+			}
+			list, err := f.list(arg)
+			if err != nil {
+				return err
+			}
+			g = &formatter{e: f.e, stream: f.stream, arguments: list}
+		}
+		escaped, err := g.format(body)
+		if err != nil {
+			return err
+		}
+		if escaped && !d.colon {
+			return nil
+		}
+		// Without a count, a body which uses no arguments would repeat
+		// forever
+		if limit < 0 && arguments.index == index {
+			return nil
+		}
+	}
+	return nil
+}
+
+// list returns the elements of the list arg.
+func (f *formatter) list(arg ilos.Instance) ([]ilos.Instance, ilos.Instance) {
+	if !isProperList(arg) {
+		_, err := SignalCondition(f.e, instance.NewDomainError(f.e, arg, class.List), Nil)
+		return nil, err
+	}
+	list := []ilos.Instance{}
+	for arg != Nil {
+		list = append(list, arg.(*instance.Cons).Car)
+		arg = arg.(*instance.Cons).Cdr
+	}
+	return list, nil
+}
+
+func (f *formatter) formatCase(d *directive) (bool, ilos.Instance) {
+	var b strings.Builder
+	outer := f.stream
+	inner := instance.NewStream(nil, &b).(instance.Stream)
+	*inner.Column = *outer.(instance.Stream).Column
+	f.stream = inner
+	escaped, err := f.format(d.clauses[0])
+	f.stream = outer
+	if err != nil {
+		return false, err
+	}
+	s := strings.ToLower(b.String())
+	switch {
+	case d.colon && d.at:
+		s = strings.ToUpper(s)
+	case d.colon, d.at:
+		word := false
+		r := []rune(s)
+		for i, c := range r {
+			alnum := unicode.IsLetter(c) || unicode.IsDigit(c)
+			if alnum && !word {
+				r[i] = unicode.ToUpper(c)
+				if d.at {
+					break
+				}
+			}
+			word = alnum
+		}
+		s = string(r)
+	}
+	f.write(s)
+	return escaped, nil
+}
+
+// parameterError signals that d has parameters or clauses it cannot have.
+func (f *formatter) parameterError(d *directive) ilos.Instance {
+	p := &formatParser{e: f.e, control: instance.NewString([]rune("~" + string(d.char))).(instance.String)}
+	return p.error("bad parameters or clauses")
 }
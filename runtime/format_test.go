@@ -0,0 +1,147 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package runtime
+
+import "testing"
+
+func TestFormat(t *testing.T) {
+	execTests(t, Format, []test{
+		{
+			exp:     `(defun fmt (control &rest args) (let ((s (create-string-output-stream))) (apply #'format s control args) (get-output-stream-string s)))`,
+			want:    `'fmt`,
+			wantErr: false,
+		},
+		{
+			exp:     `(fmt "~A-~S-~%" "a" 'b)`,
+			want:    "\"a-B-\n\"",
+			wantErr: false,
+		},
+		{
+			exp:     `(fmt "[~10A][~10@A][~5,,,'.A]" 'abc 'abc 'ab)`,
+			want:    `"[ABC       ][       ABC][AB...]"`,
+			wantErr: false,
+		},
+		{
+			exp:     `(fmt "~5D|~5,'0D|~:D|~@D|~,,'.,4:D" 42 42 1234567 42 123456789)`,
+			want:    `"   42|00042|1,234,567|+42|1.2345.6789"`,
+			wantErr: false,
+		},
+		{
+			exp:     `(fmt "~B ~O ~X ~3R ~8,6,'0R" 5 8 255 5 8)`,
+			want:    `"101 10 ff 12 000010"`,
+			wantErr: false,
+		},
+		{
+			exp:     `(fmt "~,2F|~8,3F|~F|~,0F|~4,1,,'*F|~@F" 3.14159 -2.5 1.5 3.2 123.45 1)`,
+			want:    `"3.14|  -2.500|1.5|3.|****|+1.0"`,
+			wantErr: false,
+		},
+		{
+			exp:     `(fmt "~E|~,2E|~10,3,2E|~,2,,2E" 1234.5 0.000123 -1234.5 1234.5)`,
+			want:    `"1.2345e+3|1.23e-4|-1.234e+03|12.3e+2"`,
+			wantErr: false,
+		},
+		{
+			exp:     `(fmt "~G|~,2G|~8,2G|~8,2G|" 1.5 123.456 1e20 1.5)`,
+			want:    `"1.5|1.23e+2|1.00e+20| 1.5    |"`,
+			wantErr: false,
+		},
+		{
+			exp:     `(fmt "~[zero~;one~;two~]|~[zero~;one~:;many~]|~:[no~;yes~]|~@[x=~A~]|~@[~A~]" 1 5 nil 3 nil)`,
+			want:    `"one|many|no|x=3|"`,
+			wantErr: false,
+		},
+		{
+			exp:     `(fmt "~{~A~^, ~}" '(1 2 3))`,
+			want:    `"1, 2, 3"`,
+			wantErr: false,
+		},
+		{
+			exp:     `(fmt "~:{(~A ~A)~}|~@{~A~}|" '((a 1) (b 2)) 'x 'y)`,
+			want:    `"(A 1)(B 2)|XY|"`,
+			wantErr: false,
+		},
+		{
+			exp:     `(fmt "~2{~A~}|~{x~}|~{x~:}|~{~}" '(1 2 3) '() '() "~A" '(1 2))`,
+			want:    `"12||x|12"`,
+			wantErr: false,
+		},
+		{
+			exp:     `(fmt "~A|~S|~A" '("a" ("b" #\c) #("d" e) . "f") '("a" ("b")) "g")`,
+			want:    `"(a (b c) #(d E) . f)|(\"a\" (\"b\"))|g"`,
+			wantErr: false,
+		},
+		{
+			exp:     `(dynamic-let ((*print-pretty* t)) (fmt "~A|~S" '("a" ("b")) '("a" ("b"))))`,
+			want:    `"(a (b))|(\"a\" (\"b\"))"`,
+			wantErr: false,
+		},
+		{
+			exp:     `(fmt "~{~}|~:{~}|~@{~}" "<~A>" '(1 2) "~A=~A " '((a 1) (b 2)) "~A~^, " 3 4)`,
+			want:    `"<1><2>|A=1 B=2 |3, 4"`,
+			wantErr: false,
+		},
+		{
+			exp:     `(fmt "~{~}" 'x '(1 2))`,
+			want:    `nil`,
+			wantErr: true,
+		},
+		{
+			exp:     `(fmt "~{x~}|~3{z~}|~@{y~}" '(1 2) '(1 2 3) 3 4)`,
+			want:    `"x|zzz|y"`,
+			wantErr: false,
+		},
+		{
+			exp:     `(fmt "~(Hello World~)|~:(hello big world~)|~@(hELLO wORLD~)|~:@(loud~)" )`,
+			want:    `"hello world|Hello Big World|Hello world|LOUD"`,
+			wantErr: false,
+		},
+		{
+			exp:     `(fmt "~A ~* ~A ~:* ~A ~0@* ~A" 1 2 3 4)`,
+			want:    `"1  3  3  1"`,
+			wantErr: false,
+		},
+		{
+			exp:     `(fmt "~V,'xD|~#D" 6 42 1 2)`,
+			want:    `"xxxx42| 1"`,
+			wantErr: false,
+		},
+		{
+			exp:     `(fmt "ab~6Tc~2@Td~10,4Te")`,
+			want:    `"ab    c  d    e"`,
+			wantErr: false,
+		},
+		{
+			exp:     "(fmt \"a~\n   b~:\n  c~@\n   d\")",
+			want:    "\"ab  c\nd\"",
+			wantErr: false,
+		},
+		{
+			exp:     `(fmt "~3%|~~~2~")`,
+			want:    "\"\n\n\n|~~~\"",
+			wantErr: false,
+		},
+		{
+			exp:     `(fmt "~A ~A" 1)`,
+			want:    `nil`,
+			wantErr: true,
+		},
+		{
+			exp:     `(fmt "~[a~;b")`,
+			want:    `nil`,
+			wantErr: true,
+		},
+		{
+			exp:     `(fmt "~W" 1)`,
+			want:    `nil`,
+			wantErr: true,
+		},
+		{
+			exp:     `(fmt "~D" 1.5)`,
+			want:    `nil`,
+			wantErr: true,
+		},
+	})
+}
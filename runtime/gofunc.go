@@ -0,0 +1,114 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package runtime
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/ta2gch/iris/runtime/env"
+	"github.com/ta2gch/iris/runtime/ilos"
+	"github.com/ta2gch/iris/runtime/ilos/class"
+	"github.com/ta2gch/iris/runtime/ilos/instance"
+	"github.com/ta2gch/iris/runtime/marshal"
+)
+
+var (
+	instanceType = reflect.TypeOf((*ilos.Instance)(nil)).Elem()
+	errorType    = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// RegisterFunc binds name in the function namespace of the interpreter to fn,
+// an ordinary Go function such as func(int, string) (float64, error).
+// Arguments are converted from ISLisp objects to the parameter types of fn and
+// the result is converted back as package marshal does. fn may return
+// nothing, a value, an error or a value and an error; a non-nil error is
+// signaled as a <simple-error>.
+func (i *Interpreter) RegisterFunc(name string, fn interface{}) error {
+	ft := reflect.TypeOf(fn)
+	if ft == nil || ft.Kind() != reflect.Func {
+		return fmt.Errorf("iris: %v is not a function", fn)
+	}
+	for k := 0; k < ft.NumIn(); k++ {
+		t := ft.In(k)
+		if ft.IsVariadic() && k == ft.NumIn()-1 {
+			t = t.Elem()
+		}
+		if !convertible(t) {
+			return fmt.Errorf("iris: unsupported parameter type %v", ft.In(k))
+		}
+	}
+	switch {
+	case ft.NumOut() == 0:
+	case ft.NumOut() == 1 && (ft.Out(0) == errorType || convertible(ft.Out(0))):
+	case ft.NumOut() == 2 && ft.Out(1) == errorType && convertible(ft.Out(0)):
+	default:
+		return fmt.Errorf("iris: unsupported result types of %v", ft)
+	}
+	fv := reflect.ValueOf(fn)
+	defun(i.Environment, strings.ToUpper(name), func(e env.Environment, arguments ...ilos.Instance) (ilos.Instance, ilos.Instance) {
+		if (!ft.IsVariadic() && len(arguments) != ft.NumIn()) || (ft.IsVariadic() && len(arguments) < ft.NumIn()-1) {
+			return SignalCondition(e, instance.NewArityError(e), Nil)
+		}
+		argv := []reflect.Value{}
+		for k, argument := range arguments {
+			var t reflect.Type
+			if ft.IsVariadic() && k >= ft.NumIn()-1 {
+				t = ft.In(ft.NumIn() - 1).Elem()
+			} else {
+				t = ft.In(k)
+			}
+			v, err := fromInstance(e, argument, t)
+			if err != nil {
+				return nil, err
+			}
+			argv = append(argv, v)
+		}
+		rets := fv.Call(argv)
+		if len(rets) > 0 && ft.Out(len(rets)-1) == errorType {
+			if err, _ := rets[len(rets)-1].Interface().(error); err != nil {
+				return SignalCondition(e, instance.NewSimpleError(e, instance.NewString([]rune(err.Error())), Nil), Nil)
+			}
+			rets = rets[:len(rets)-1]
+		}
+		if len(rets) == 0 {
+			return Nil, nil
+		}
+		return marshal.ToLisp(e, rets[0].Interface()), nil
+	})
+	return nil
+}
+
+func convertible(t reflect.Type) bool {
+	if t == instanceType || t.Kind() == reflect.Interface && t.NumMethod() == 0 {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64, reflect.String, reflect.Bool, reflect.Struct:
+		return true
+	case reflect.Slice, reflect.Array, reflect.Ptr:
+		return convertible(t.Elem())
+	case reflect.Map:
+		return convertible(t.Key()) && convertible(t.Elem())
+	}
+	return false
+}
+
+// fromInstance converts obj to a Go value of type t
+func fromInstance(e env.Environment, obj ilos.Instance, t reflect.Type) (reflect.Value, ilos.Instance) {
+	v := reflect.New(t)
+	if err := marshal.FromLisp(obj, v.Interface()); err != nil {
+		var c ilos.Class = class.Object
+		if err, ok := err.(*marshal.TypeError); ok {
+			c = err.Class
+		}
+		_, err := SignalCondition(e, instance.NewDomainError(e, obj, c), Nil)
+		return reflect.Value{}, err
+	}
+	return v.Elem(), nil
+}
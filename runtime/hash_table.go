@@ -0,0 +1,69 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package runtime
+
+import (
+	"github.com/ta2gch/iris/runtime/env"
+	"github.com/ta2gch/iris/runtime/ilos"
+	"github.com/ta2gch/iris/runtime/ilos/class"
+	"github.com/ta2gch/iris/runtime/ilos/instance"
+)
+
+// CreateHashTable returns a new hash table without entries. The keys of a
+// hash table are compared with equal. #{key value ...} reads as a hash table
+// of the keys and values, which are not evaluated.
+func CreateHashTable(e env.Environment) (ilos.Instance, ilos.Instance) {
+	return instance.NewHashTable(), nil
+}
+
+// Gethash returns the value of key in hash-table, or default, which defaults
+// to nil, if hash-table has no entry of key. An error shall be signaled if
+// hash-table is not a hash table (error-id. domain-error).
+func Gethash(e env.Environment, key, hashTable ilos.Instance, options ...ilos.Instance) (ilos.Instance, ilos.Instance) {
+	if len(options) > 1 {
+		return SignalCondition(e, instance.NewArityError(e), Nil)
+	}
+	if err := ensure(e, class.HashTable, hashTable); err != nil {
+		return nil, err
+	}
+	if v, ok := hashTable.(*instance.HashTable).Get(key); ok {
+		return v, nil
+	}
+	if len(options) > 0 {
+		return options[0], nil
+	}
+	return Nil, nil
+}
+
+// SetGethash sets the value of key in hash-table to obj and returns obj. An
+// error shall be signaled if hash-table is not a hash table (error-id.
+// domain-error).
+func SetGethash(e env.Environment, obj, key, hashTable ilos.Instance) (ilos.Instance, ilos.Instance) {
+	if err := ensure(e, class.HashTable, hashTable); err != nil {
+		return nil, err
+	}
+	hashTable.(*instance.HashTable).Set(key, obj)
+	return obj, nil
+}
+
+// Remhash removes the entry of key from hash-table. It returns t if there was
+// one; otherwise, returns nil.
+func Remhash(e env.Environment, key, hashTable ilos.Instance) (ilos.Instance, ilos.Instance) {
+	if err := ensure(e, class.HashTable, hashTable); err != nil {
+		return nil, err
+	}
+	if hashTable.(*instance.HashTable).Delete(key) {
+		return T, nil
+	}
+	return Nil, nil
+}
+
+// HashTableCount returns the number of entries of hash-table.
+func HashTableCount(e env.Environment, hashTable ilos.Instance) (ilos.Instance, ilos.Instance) {
+	if err := ensure(e, class.HashTable, hashTable); err != nil {
+		return nil, err
+	}
+	return instance.NewInteger(hashTable.(*instance.HashTable).Len()), nil
+}
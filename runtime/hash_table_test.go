@@ -0,0 +1,72 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package runtime
+
+import "testing"
+
+func TestHashTable(t *testing.T) {
+	execTests(t, Gethash, []test{
+		{
+			exp:     `(gethash 'b #{a 1 b (2 3)})`,
+			want:    `'(2 3)`,
+			wantErr: false,
+		},
+		{
+			exp: `(let ((h (create-hash-table)))
+			        (setf (gethash "k" h) 1)
+			        (setf (gethash (list 1 #\a) h) 2)
+			        (list (gethash "k" h) (gethash '(1 #\a) h) (gethash 'z h 'none) (hash-table-count h) (remhash "k" h) (remhash "k" h) (hash-table-count h)))`,
+			want:    `'(1 2 none 2 t nil 1)`,
+			wantErr: false,
+		},
+		{
+			exp:     `(format nil "~S" #{a "b" 1 #{}})`,
+			want:    `"#{A \"b\" 1 #{}}"`,
+			wantErr: false,
+		},
+		{
+			exp: `(let ((h (create-hash-table)))
+			        (setf (gethash #'car h) 1)
+			        (setf (gethash (create-string-output-stream) h) 2)
+			        (list (gethash #'car h) (gethash #'cdr h 'none) (gethash (create-string-output-stream) h 'none)))`,
+			want:    `'(1 none none)`,
+			wantErr: false,
+		},
+		{
+			exp: `(progn
+			        (defclass point () ((x :initarg x)))
+			        (let ((h (create-hash-table))
+			              (p (create (class point) 'x 1)))
+			          (setf (gethash p h) 1)
+			          (list (gethash p h) (gethash (create (class point) 'x 1) h 'none))))`,
+			want:    `'(1 none)`,
+			wantErr: false,
+		},
+		{
+			exp: `(let ((h (create-hash-table))
+			            (k (list 1 2)))
+			        (setf (gethash k h) 'a)
+			        (setf (gethash (vector 1 "x") h) 'b)
+			        (set-car 9 k)
+			        (list (gethash k h) (gethash (vector 1 "x") h)))`,
+			want:    `'(a b)`,
+			wantErr: false,
+		},
+		{
+			exp: `(let ((h #{a 1 b 2 c 3}))
+			        (remhash 'b h)
+			        (setf (gethash 'b h) 4)
+			        (remhash 'a h)
+			        (format nil "~S ~A" h (hash-table-count h)))`,
+			want:    `"#{C 3 B 4} 2"`,
+			wantErr: false,
+		},
+		{
+			exp:     `(gethash 'a '(a 1))`,
+			want:    `nil`,
+			wantErr: true,
+		},
+	})
+}
@@ -28,12 +28,14 @@ var Symbol = instance.SymbolClass
 var Number = instance.NumberClass
 var Integer = instance.IntegerClass
 var Float = instance.FloatClass
+var Bignum = instance.BignumClass
+var Ratio = instance.RatioClass
 
 var SeriousCondition = instance.SeriousConditionClass
 var Error = instance.ErrorClass
 var ArithmeticError = instance.ArithmeticErrorClass
 var DivisionByZero = instance.DivisionByZeroClass
-var FloatingPointOnderflow = instance.FloatingPointOnderflowClass
+var FloatingPointOverflow = instance.FloatingPointOverflowClass
 var FloatingPointUnderflow = instance.FloatingPointUnderflowClass
 var ControlError = instance.ControlErrorClass
 var ParseError = instance.ParseErrorClass
@@ -55,3 +57,11 @@ var CatchTag = instance.CatchTagClass
 var TagbodyTag = instance.TagbodyTagClass
 var BlockTag = instance.BlockTagClass
 var Continue = instance.ContinueClass
+var RestartTag = instance.RestartTagClass
+var EvaluationAborted = instance.EvaluationAbortedClass
+var AccessDenied = instance.AccessDeniedClass
+var PrintNotReadable = instance.PrintNotReadableClass
+var HashTable = instance.HashTableClass
+var Regexp = instance.RegexpClass
+var Readtable = instance.ReadtableClass
+var Restart = instance.RestartClass
@@ -6,6 +6,7 @@ package instance
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/ta2gch/iris/runtime/ilos"
 )
@@ -28,9 +29,12 @@ func (*GeneralArrayStar) Class() ilos.Class {
 func (i *GeneralArrayStar) String() string {
 	var count func(i *GeneralArrayStar) int
 	count = func(i *GeneralArrayStar) int {
-		if i.Vector != nil {
+		if len(i.Vector) > 0 {
 			return 1 + count(i.Vector[0])
 		}
+		if i.Vector != nil {
+			return 1
+		}
 		return 0
 	}
 	var stringify func(i *GeneralArrayStar) string
@@ -81,5 +85,6 @@ func (String) Class() ilos.Class {
 }
 
 func (i String) String() string {
-	return "\"" + string(i) + "\""
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	return "\"" + r.Replace(string(i)) + "\""
 }
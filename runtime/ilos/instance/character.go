@@ -5,6 +5,11 @@
 package instance
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
 	"github.com/ta2gch/iris/runtime/ilos"
 )
 
@@ -20,13 +25,51 @@ func (Character) Class() ilos.Class {
 	return CharacterClass
 }
 
+// characterNames are the names of characters, in the order in which they are
+// looked up. The first name of a character is the one it is printed with.
+var characterNames = []struct {
+	name string
+	char rune
+}{
+	{"NUL", 0},
+	{"BELL", 7},
+	{"BACKSPACE", 8},
+	{"TAB", '\t'},
+	{"NEWLINE", '\n'},
+	{"LINEFEED", '\n'},
+	{"PAGE", '\f'},
+	{"RETURN", '\r'},
+	{"ESCAPE", 27},
+	{"SPACE", ' '},
+	{"RUBOUT", 127},
+	{"DELETE", 127},
+}
+
+// CharacterName returns the character named name, which is one of the names
+// above or U+ followed by a hexadecimal code point, ignoring case.
+func CharacterName(name string) (rune, bool) {
+	name = strings.ToUpper(name)
+	for _, c := range characterNames {
+		if c.name == name {
+			return c.char, true
+		}
+	}
+	if strings.HasPrefix(name, "U+") {
+		if n, err := strconv.ParseUint(name[2:], 16, 32); err == nil && n <= unicode.MaxRune {
+			return rune(n), true
+		}
+	}
+	return 0, false
+}
+
 func (i Character) String() string {
-	switch rune(i) {
-	case ' ':
-		return `#\SPACE`
-	case '\n':
-		return `#\NEWLINE`
-	default:
-		return `#\` + string(i)
+	for _, c := range characterNames {
+		if c.char == rune(i) {
+			return `#\` + c.name
+		}
+	}
+	if !unicode.IsGraphic(rune(i)) || unicode.IsSpace(rune(i)) {
+		return fmt.Sprintf(`#\U+%04X`, rune(i))
 	}
+	return `#\` + string(i)
 }
@@ -28,12 +28,14 @@ var SymbolClass = NewBuiltInClass("<SYMBOL>", ObjectClass)
 var NumberClass = NewBuiltInClass("<NUMBER>", ObjectClass)
 var IntegerClass = NewBuiltInClass("<INTEGER>", NumberClass)
 var FloatClass = NewBuiltInClass("<FLOAT>", NumberClass)
+var BignumClass = NewBuiltInClass("<BIGNUM>", IntegerClass)
+var RatioClass = NewBuiltInClass("<RATIO>", NumberClass)
 
 var SeriousConditionClass = NewBuiltInClass("<SERIOUS-CONDITION>", ObjectClass)
 var ErrorClass = NewBuiltInClass("<ERROR>", SeriousConditionClass)
 var ArithmeticErrorClass = NewBuiltInClass("<ARITHMETIC-ERROR>", ErrorClass, "OPERATION", "OPERANDS")
 var DivisionByZeroClass = NewBuiltInClass("<DIVISION-BY-ZERO>", ArithmeticErrorClass)
-var FloatingPointOnderflowClass = NewBuiltInClass("<FLOATING-POINT-OVERFLOW>", ArithmeticErrorClass)
+var FloatingPointOverflowClass = NewBuiltInClass("<FLOATING-POINT-OVERFLOW>", ArithmeticErrorClass)
 var FloatingPointUnderflowClass = NewBuiltInClass("<FLOATING-POINT-UNDERFLOW>", ArithmeticErrorClass)
 var ControlErrorClass = NewBuiltInClass("<CONTROL-ERROR>", ErrorClass)
 var ParseErrorClass = NewBuiltInClass("<PARSE-ERROR>", ErrorClass, "STRING", "EXPECTED-CLASS")
@@ -43,15 +45,23 @@ var UndefinedEntityClass = NewBuiltInClass("<UNDEFINED-ENTITY>", ProgramErrorCla
 var UndefinedVariableClass = NewBuiltInClass("<UNDEFINED-VARIABLE>", UndefinedEntityClass)
 var UndefinedFunctionClass = NewBuiltInClass("<UNDEFINED-FUNCTION>", UndefinedEntityClass)
 var SimpleErrorClass = NewBuiltInClass("<SIMPLE-ERROR>", ErrorClass, "FORMAT-STRING", "FORMAT-ARGUMENTS")
-var StreamErrorClass = NewBuiltInClass("<STREAM-ERROR>", ErrorClass)
+var StreamErrorClass = NewBuiltInClass("<STREAM-ERROR>", ErrorClass, "STREAM")
 var EndOfStreamClass = NewBuiltInClass("<END-OF-STREAM>", StreamErrorClass)
 var StorageExhaustedClass = NewBuiltInClass("<STORAGE-EXHAUSTED>", SeriousConditionClass)
 var StandardObjectClass = NewBuiltInClass("<STANDARD-OBJECT>", ObjectClass)
 var StreamClass = NewBuiltInClass("<STREAM>", ObjectClass, "STREAM")
 
 // Implementation defined
-var EscapeClass = NewBuiltInClass("<ESCAPE>", ObjectClass, "IRIS.TAG")
+var EscapeClass = NewBuiltInClass("<ESCAPE>", ObjectClass, "IRIS.TAG", "IRIS.UID")
 var CatchTagClass = NewBuiltInClass("<THROW>", EscapeClass, "IRIS.OBJECT")
 var TagbodyTagClass = NewBuiltInClass("<TAGBODY-TAG>", EscapeClass)
 var BlockTagClass = NewBuiltInClass("<BLOCK-TAG>", EscapeClass, "IRIS.OBJECT")
 var ContinueClass = NewBuiltInClass("<CONTINUE>", EscapeClass, "IRIS.OBJECT")
+var RestartTagClass = NewBuiltInClass("<RESTART-TAG>", EscapeClass, "IRIS.OBJECT")
+var EvaluationAbortedClass = NewBuiltInClass("<EVALUATION-ABORTED>", SeriousConditionClass, "REASON")
+var AccessDeniedClass = NewBuiltInClass("<ACCESS-DENIED>", StreamErrorClass, "FILENAME")
+var PrintNotReadableClass = NewBuiltInClass("<PRINT-NOT-READABLE>", ErrorClass, "IRIS.OBJECT")
+var HashTableClass = NewBuiltInClass("<HASH-TABLE>", ObjectClass)
+var RegexpClass = NewBuiltInClass("<REGEXP>", ObjectClass)
+var ReadtableClass = NewBuiltInClass("<READTABLE>", ObjectClass)
+var RestartClass = NewBuiltInClass("<RESTART>", ObjectClass)
@@ -21,6 +21,18 @@ func NewDivisionByZero(e env.Environment, operation, operands ilos.Instance) ilo
 		NewSymbol("OPERANDS"), operands)
 }
 
+func NewFloatingPointOverflow(e env.Environment, operation, operands ilos.Instance) ilos.Instance {
+	return Create(e, FloatingPointOverflowClass,
+		NewSymbol("OPERATION"), operation,
+		NewSymbol("OPERANDS"), operands)
+}
+
+func NewFloatingPointUnderflow(e env.Environment, operation, operands ilos.Instance) ilos.Instance {
+	return Create(e, FloatingPointUnderflowClass,
+		NewSymbol("OPERATION"), operation,
+		NewSymbol("OPERANDS"), operands)
+}
+
 func NewParseError(e env.Environment, str, expectedClass ilos.Instance) ilos.Instance {
 	return Create(e, ParseErrorClass,
 		NewSymbol("STRING"), str,
@@ -74,6 +86,27 @@ func NewControlError(e env.Environment) ilos.Instance {
 	return Create(e, ControlErrorClass)
 }
 
-func NewStreamError(e env.Environment) ilos.Instance {
-	return Create(e, StreamErrorClass)
+func NewStreamError(e env.Environment, stream ilos.Instance) ilos.Instance {
+	return Create(e, StreamErrorClass,
+		NewSymbol("STREAM"), stream)
+}
+
+func NewEndOfStream(e env.Environment, stream ilos.Instance) ilos.Instance {
+	return Create(e, EndOfStreamClass,
+		NewSymbol("STREAM"), stream)
+}
+
+func NewEvaluationAborted(e env.Environment, reason ilos.Instance) ilos.Instance {
+	return Create(e, EvaluationAbortedClass,
+		NewSymbol("REASON"), reason)
+}
+
+func NewAccessDenied(e env.Environment, filename ilos.Instance) ilos.Instance {
+	return Create(e, AccessDeniedClass,
+		NewSymbol("FILENAME"), filename)
+}
+
+func NewPrintNotReadable(e env.Environment, object ilos.Instance) ilos.Instance {
+	return Create(e, PrintNotReadableClass,
+		NewSymbol("IRIS.OBJECT"), object)
 }
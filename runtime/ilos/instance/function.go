@@ -23,7 +23,7 @@ type Function struct {
 }
 
 func NewFunction(name ilos.Instance, function interface{}) ilos.Instance {
-	return Function{name, function}
+	return &Function{name, function}
 }
 
 func (Function) Class() ilos.Class {
@@ -54,7 +54,7 @@ func (f Function) Apply(e env.Environment, arguments ...ilos.Instance) (ilos.Ins
 type method struct {
 	qualifier ilos.Instance
 	classList []ilos.Class
-	function  Function
+	function  Applicable
 }
 
 type GenericFunction struct {
@@ -82,11 +82,11 @@ func (f *GenericFunction) AddMethod(qualifier, lambdaList ilos.Instance, classLi
 	}
 	for i := range f.methods {
 		if f.methods[i].qualifier == qualifier && reflect.DeepEqual(f.methods[i].classList, classList) {
-			f.methods[i].function = function.(Function)
+			f.methods[i].function = function.(Applicable)
 			return true
 		}
 	}
-	f.methods = append(f.methods, method{qualifier, classList, function.(Function)})
+	f.methods = append(f.methods, method{qualifier, classList, function.(Applicable)})
 	return true
 }
 
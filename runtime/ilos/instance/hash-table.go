@@ -0,0 +1,345 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package instance
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strings"
+
+	"github.com/ta2gch/iris/runtime/ilos"
+)
+
+// HashTable
+
+// HashTable maps keys, which are compared with equal, to values. It keeps its
+// entries in the order their keys were first added.
+//
+// Conses, strings, vectors, arrays and the numbers which are not fixnums are
+// keys by their structure: they are found by their hashes. Every key is also
+// found by its identity, so a key which is modified after it is added is
+// still found by the same object. Functions, streams and the instances of
+// classes are keys only by their identity.
+type HashTable struct {
+	identities  map[interface{}]*entry
+	buckets     map[uint64][]*entry
+	first, last *entry
+	len         int
+}
+
+type entry struct {
+	key, value ilos.Instance
+	hash       uint64 // of the key when it was added, if it is in a bucket
+	structural bool   // whether the entry is in a bucket
+	prev, next *entry
+}
+
+func NewHashTable() ilos.Instance {
+	return &HashTable{identities: map[interface{}]*entry{}, buckets: map[uint64][]*entry{}}
+}
+
+func (*HashTable) Class() ilos.Class {
+	return HashTableClass
+}
+
+// reference is the identity of an object whose Go value is not comparable.
+type reference struct {
+	t reflect.Type
+	p uintptr
+	n int
+}
+
+// identity returns a comparable Go value which is the same for two keys if
+// they are the same object, or nil if key has no identity but its structure,
+// as a class.
+func identity(key ilos.Instance) interface{} {
+	switch k := key.(type) {
+	case String, GeneralVector:
+		v := reflect.ValueOf(k)
+		return reference{v.Type(), v.Pointer(), v.Len()}
+	case Bignum:
+		return k.value
+	case Ratio:
+		return k.value
+	case Instance:
+		return reference{reflect.TypeOf(k), reflect.ValueOf(k.slots).Pointer(), 0}
+	case Stream:
+		return k.Column
+	}
+	if reflect.TypeOf(key).Comparable() {
+		return key
+	}
+	return nil
+}
+
+// structural reports whether key is found by its structure as well as by its
+// identity.
+func structural(key ilos.Instance) bool {
+	switch key.(type) {
+	case *Cons, String, GeneralVector, *GeneralArrayStar, Bignum, Ratio:
+		return true
+	}
+	return identity(key) == nil
+}
+
+// maxHashDepth is how deep hash looks into the conses, vectors and arrays of a
+// key, so that it takes a bounded time.
+const maxHashDepth = 4
+
+const (
+	offset64 = 14695981039346656037
+	prime64  = 1099511628211
+)
+
+// mix returns the FNV-1a hash h extended with the bytes of v.
+func mix(h, v uint64) uint64 {
+	for i := 0; i < 8; i++ {
+		h ^= v & 0xff
+		h *= prime64
+		v >>= 8
+	}
+	return h
+}
+
+func mixString(h uint64, s string) uint64 {
+	for i := 0; i < len(s); i++ {
+		h ^= uint64(s[i])
+		h *= prime64
+	}
+	return h
+}
+
+func mixInt(h uint64, i *big.Int) uint64 {
+	h = mix(h, uint64(i.Sign()+1))
+	for _, w := range i.Bits() {
+		h = mix(h, uint64(w))
+	}
+	return h
+}
+
+// hash returns a hash of key which is the same for two keys if they are
+// equal. Keys compared by their identity hash by their type alone.
+func hash(key ilos.Instance, depth int) uint64 {
+	h := mixString(offset64, reflect.TypeOf(key).String())
+	switch k := key.(type) {
+	case Integer:
+		return mix(h, uint64(k))
+	case Float:
+		f := float64(k)
+		if f == 0 {
+			f = 0 // -0.0 is equal to 0.0
+		}
+		return mix(h, math.Float64bits(f))
+	case Character:
+		return mix(h, uint64(k))
+	case Symbol:
+		return mixString(h, string(k))
+	case Bignum:
+		return mixInt(h, k.value)
+	case Ratio:
+		return mixInt(mixInt(h, k.value.Num()), k.value.Denom())
+	case String:
+		return mixString(h, string(k))
+	case *Cons:
+		if depth < maxHashDepth {
+			h = mix(h, hash(k.Car, depth+1))
+			h = mix(h, hash(k.Cdr, depth+1))
+		}
+		return h
+	case GeneralVector:
+		h = mix(h, uint64(len(k)))
+		for i := 0; i < len(k) && i < maxHashDepth && depth < maxHashDepth; i++ {
+			h = mix(h, hash(k[i], depth+1))
+		}
+		return h
+	case *GeneralArrayStar:
+		if k.Vector == nil {
+			if depth < maxHashDepth {
+				h = mix(h, hash(k.Scalar, depth+1))
+			}
+			return h
+		}
+		h = mix(h, uint64(len(k.Vector)))
+		for i := 0; i < len(k.Vector) && i < maxHashDepth && depth < maxHashDepth; i++ {
+			h = mix(h, hash(k.Vector[i], depth+1))
+		}
+		return h
+	}
+	if depth == 0 && identity(key) == nil {
+		return mixString(h, fmt.Sprint(key))
+	}
+	return h
+}
+
+// equal reports whether the keys a and b are equal.
+func equal(a, b ilos.Instance) bool {
+	for {
+		c, ok := a.(*Cons)
+		if !ok {
+			break
+		}
+		d, ok := b.(*Cons)
+		if !ok {
+			return false
+		}
+		if c == d {
+			return true
+		}
+		if !equal(c.Car, d.Car) {
+			return false
+		}
+		a, b = c.Cdr, d.Cdr
+	}
+	switch a := a.(type) {
+	case String:
+		b, ok := b.(String)
+		return ok && string(a) == string(b)
+	case GeneralVector:
+		b, ok := b.(GeneralVector)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !equal(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case *GeneralArrayStar:
+		b, ok := b.(*GeneralArrayStar)
+		if !ok || (a.Vector == nil) != (b.Vector == nil) || len(a.Vector) != len(b.Vector) {
+			return false
+		}
+		if a.Vector == nil {
+			return equal(a.Scalar, b.Scalar)
+		}
+		for i := range a.Vector {
+			if !equal(a.Vector[i], b.Vector[i]) {
+				return false
+			}
+		}
+		return true
+	case Bignum:
+		b, ok := b.(Bignum)
+		return ok && a.value.Cmp(b.value) == 0
+	case Ratio:
+		b, ok := b.(Ratio)
+		return ok && a.value.Cmp(b.value) == 0
+	}
+	if ia := identity(a); ia != nil {
+		return reflect.TypeOf(a) == reflect.TypeOf(b) && ia == identity(b)
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// lookup returns the entry of key, or nil if h has none. It also returns the
+// hash of key if key is found by its structure.
+func (h *HashTable) lookup(key ilos.Instance) (*entry, uint64) {
+	if id := identity(key); id != nil {
+		if e, ok := h.identities[id]; ok {
+			return e, 0
+		}
+	}
+	if !structural(key) {
+		return nil, 0
+	}
+	k := hash(key, 0)
+	for _, e := range h.buckets[k] {
+		if equal(e.key, key) {
+			return e, k
+		}
+	}
+	return nil, k
+}
+
+// Get returns the value of key.
+func (h *HashTable) Get(key ilos.Instance) (ilos.Instance, bool) {
+	if e, _ := h.lookup(key); e != nil {
+		return e.value, true
+	}
+	return nil, false
+}
+
+// Set sets the value of key to value.
+func (h *HashTable) Set(key, value ilos.Instance) {
+	e, k := h.lookup(key)
+	if e != nil {
+		e.value = value
+		return
+	}
+	e = &entry{key: key, value: value, hash: k, structural: structural(key), prev: h.last}
+	if id := identity(key); id != nil {
+		h.identities[id] = e
+	}
+	if e.structural {
+		h.buckets[k] = append(h.buckets[k], e)
+	}
+	if h.last == nil {
+		h.first = e
+	} else {
+		h.last.next = e
+	}
+	h.last = e
+	h.len++
+}
+
+// Delete removes key and reports whether it was in h.
+func (h *HashTable) Delete(key ilos.Instance) bool {
+	e, _ := h.lookup(key)
+	if e == nil {
+		return false
+	}
+	if id := identity(e.key); id != nil {
+		delete(h.identities, id)
+	}
+	if e.structural {
+		bucket := h.buckets[e.hash]
+		for i, f := range bucket {
+			if f == e {
+				bucket = append(bucket[:i], bucket[i+1:]...)
+				break
+			}
+		}
+		if len(bucket) == 0 {
+			delete(h.buckets, e.hash)
+		} else {
+			h.buckets[e.hash] = bucket
+		}
+	}
+	if e.prev == nil {
+		h.first = e.next
+	} else {
+		e.prev.next = e.next
+	}
+	if e.next == nil {
+		h.last = e.prev
+	} else {
+		e.next.prev = e.prev
+	}
+	h.len--
+	return true
+}
+
+// Len returns the number of entries of h.
+func (h *HashTable) Len() int {
+	return h.len
+}
+
+// Each calls f with every key and its value in order.
+func (h *HashTable) Each(f func(key, value ilos.Instance)) {
+	for e := h.first; e != nil; e = e.next {
+		f(e.key, e.value)
+	}
+}
+
+func (h *HashTable) String() string {
+	s := []string{}
+	h.Each(func(key, value ilos.Instance) {
+		s = append(s, fmt.Sprint(key), fmt.Sprint(value))
+	})
+	return "#{" + strings.Join(s, " ") + "}"
+}
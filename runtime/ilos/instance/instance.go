@@ -7,6 +7,8 @@ package instance
 import (
 	"fmt"
 	"reflect"
+	"sort"
+	"strings"
 
 	"github.com/ta2gch/iris/runtime/env"
 	"github.com/ta2gch/iris/runtime/ilos"
@@ -22,10 +24,36 @@ func Create(e env.Environment, c ilos.Instance, i ...ilos.Instance) ilos.Instanc
 	return InitializeObject(e, Instance{c.(ilos.Class), p, map[ilos.Instance]ilos.Instance{}}, i...)
 }
 
+// CreateBuiltIn is Create for a built-in class, such as a condition made by
+// the reader. A built-in class has no initforms, so it needs no environment.
+func CreateBuiltIn(c ilos.Class, i ...ilos.Instance) ilos.Instance {
+	p := []ilos.Instance{}
+	for _, q := range c.Supers() {
+		p = append(p, CreateBuiltIn(q, i...))
+	}
+	object := Instance{c, p, map[ilos.Instance]ilos.Instance{}}
+	initializeSlots(object, i...)
+	return object
+}
+
 func InitializeObject(e env.Environment, object ilos.Instance, inits ...ilos.Instance) ilos.Instance {
 	for _, super := range object.(Instance).supers {
 		InitializeObject(e, super, inits...)
 	}
+	initializeSlots(object, inits...)
+	for _, slotName := range object.Class().Slots() {
+		if _, ok := object.(Instance).GetSlotValue(slotName, object.Class()); !ok {
+			if form, ok := object.Class().Initform(slotName); ok {
+				value, _ := form.(Applicable).Apply(e.NewDynamic())
+				object.(Instance).SetSlotValue(slotName, value, object.Class())
+			}
+		}
+	}
+	return object
+}
+
+// initializeSlots sets the slots of object whose initargs are in inits.
+func initializeSlots(object ilos.Instance, inits ...ilos.Instance) {
 	for i := 0; i < len(inits); i += 2 {
 		argName := inits[i]
 		argValue := inits[i+1]
@@ -38,28 +66,31 @@ func InitializeObject(e env.Environment, object ilos.Instance, inits ...ilos.Ins
 			}
 		}
 	}
-	for _, slotName := range object.Class().Slots() {
-		if _, ok := object.(Instance).GetSlotValue(slotName, object.Class()); !ok {
-			if form, ok := object.Class().Initform(slotName); ok {
-				value, _ := form.(Applicable).Apply(e.NewDynamic())
-				object.(Instance).SetSlotValue(slotName, value, object.Class())
-			}
-		}
-	}
-	return object
 }
 
 type slots map[ilos.Instance]ilos.Instance
 
+// String prints the slots except the internal ones of the implementation.
 func (s slots) String() string {
-	str := "{"
+	pairs := []string{}
 	for k, v := range s {
-		str += fmt.Sprintf(`%v: %v, `, k, v)
+		if Internal(k) {
+			continue
+		}
+		pairs = append(pairs, fmt.Sprintf(`%v: %v`, k, v))
 	}
-	if len(str) == 1 {
+	if len(pairs) == 0 {
 		return ""
 	}
-	return str[:len(str)-2] + "}"
+	sort.Strings(pairs) // Map iteration order is random
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+// Internal reports whether name is the name of a slot which the
+// implementation keeps for itself, such as IRIS.LOCATION.
+func Internal(name ilos.Instance) bool {
+	s, ok := name.(Symbol)
+	return ok && strings.HasPrefix(string(s), "IRIS.")
 }
 
 type Instance struct {
@@ -112,7 +143,15 @@ func (i Instance) getAllSlots() slots {
 	return m
 }
 
+// String prints the instance as #<name slots>, where name is the name of its
+// class without the angle brackets, if it has them.
 func (i Instance) String() string {
-	c := i.Class().String()
-	return fmt.Sprintf("#%v %v>", c[:len(c)-1], i.getAllSlots())
+	name := i.Class().String()
+	if len(name) > 1 && strings.HasPrefix(name, "<") && strings.HasSuffix(name, ">") {
+		name = name[1 : len(name)-1]
+	}
+	if s := i.getAllSlots().String(); s != "" {
+		return fmt.Sprintf("#<%v %v>", name, s)
+	}
+	return fmt.Sprintf("#<%v>", name)
 }
@@ -6,6 +6,10 @@ package instance
 
 import (
 	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
 
 	"github.com/ta2gch/iris/runtime/ilos"
 )
@@ -25,6 +29,65 @@ func (i Integer) String() string {
 	return fmt.Sprint(int(i))
 }
 
+// Bignum
+
+// Bignum is an integer too large for an Integer. Arithmetic makes a Bignum
+// only when its result does not fit in an Integer, so the two never overlap.
+type Bignum struct {
+	value *big.Int
+}
+
+// NewBigInteger returns i as an Integer if it fits in one, or else as a
+// Bignum. i must not be modified afterwards.
+func NewBigInteger(i *big.Int) ilos.Instance {
+	if i.IsInt64() && int64(int(i.Int64())) == i.Int64() {
+		return Integer(i.Int64())
+	}
+	return Bignum{i}
+}
+
+func (Bignum) Class() ilos.Class {
+	return BignumClass
+}
+
+// Int returns the value of i, which must not be modified.
+func (i Bignum) Int() *big.Int {
+	return i.value
+}
+
+func (i Bignum) String() string {
+	return i.value.String()
+}
+
+// Ratio
+
+// Ratio is an exact quotient of integers that is not an integer itself.
+type Ratio struct {
+	value *big.Rat
+}
+
+// NewRatio returns r as an integer if its denominator is 1, or else as a
+// Ratio. r must not be modified afterwards.
+func NewRatio(r *big.Rat) ilos.Instance {
+	if r.IsInt() {
+		return NewBigInteger(r.Num())
+	}
+	return Ratio{r}
+}
+
+func (Ratio) Class() ilos.Class {
+	return RatioClass
+}
+
+// Rat returns the value of r, which must not be modified.
+func (r Ratio) Rat() *big.Rat {
+	return r.value
+}
+
+func (r Ratio) String() string {
+	return r.value.String()
+}
+
 // Float
 
 type Float float64
@@ -38,5 +101,10 @@ func (Float) Class() ilos.Class {
 }
 
 func (i Float) String() string {
-	return fmt.Sprint(float64(i))
+	f := float64(i)
+	str := strconv.FormatFloat(f, 'g', -1, 64)
+	if math.IsInf(f, 0) || math.IsNaN(f) || strings.ContainsAny(str, ".e") {
+		return str
+	}
+	return str + ".0" // Read back as a float, not as an integer
 }
@@ -0,0 +1,31 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package instance
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/ta2gch/iris/runtime/ilos"
+)
+
+// Regexp
+
+// Regexp is a compiled regular expression of the syntax of package regexp.
+type Regexp struct {
+	*regexp.Regexp
+}
+
+func NewRegexp(r *regexp.Regexp) ilos.Instance {
+	return Regexp{r}
+}
+
+func (Regexp) Class() ilos.Class {
+	return RegexpClass
+}
+
+func (r Regexp) String() string {
+	return `#r"` + strings.Replace(r.Regexp.String(), `"`, `\"`, -1) + `"`
+}
@@ -5,6 +5,9 @@
 package instance
 
 import (
+	"regexp"
+	"strings"
+
 	"github.com/ta2gch/iris/runtime/ilos"
 )
 
@@ -20,8 +23,16 @@ func (Symbol) Class() ilos.Class {
 	return SymbolClass
 }
 
+// plain matches the names of symbols which read back without vertical bars,
+// if they have no lower case letters.
+var plain = regexp.MustCompile(`^(?:[:&][-\pL\pM\pN+<>/*=?_!$%[\]^{}~.@:]+|\+|-|1\+|1-|[\pL<>/*=?_!$%[\]^{}~][-\pL\pM\pN+<>/*=?_!$%[\]^{}~.@:]*)$`)
+
 func (i Symbol) String() string {
-	return string(i)
+	if plain.MatchString(string(i)) && strings.ToUpper(string(i)) == string(i) && i != "NIL" {
+		return string(i)
+	}
+	r := strings.NewReplacer(`\`, `\\`, `|`, `\|`)
+	return "|" + r.Replace(string(i)) + "|"
 }
 
 var T = NewSymbol("T")
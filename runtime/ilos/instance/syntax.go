@@ -0,0 +1,63 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package instance
+
+import (
+	"github.com/ta2gch/iris/runtime/ilos"
+)
+
+// The syntax of the special forms, shared by the evaluators.
+
+// ProperList returns the elements of obj, or false if obj is not a proper
+// list.
+func ProperList(obj ilos.Instance) ([]ilos.Instance, bool) {
+	s := []ilos.Instance{}
+	for obj != Nil {
+		cons, ok := obj.(*Cons)
+		if !ok {
+			return nil, false
+		}
+		s = append(s, cons.Car)
+		obj = cons.Cdr
+	}
+	return s, true
+}
+
+// Bindings returns the variables and forms of the variable list of let, let*
+// and dynamic-let, or false if varForm is not a list of (var form).
+func Bindings(varForm ilos.Instance) ([]ilos.Instance, []ilos.Instance, bool) {
+	list, ok := ProperList(varForm)
+	if !ok {
+		return nil, nil, false
+	}
+	variables, forms := []ilos.Instance{}, []ilos.Instance{}
+	for _, cadr := range list {
+		binding, ok := ProperList(cadr)
+		if !ok || len(binding) != 2 || !ilos.InstanceOf(SymbolClass, binding[0]) {
+			return nil, nil, false
+		}
+		variables = append(variables, binding[0])
+		forms = append(forms, binding[1])
+	}
+	return variables, forms, true
+}
+
+// ParseLambdaList returns the parameters of lambdaList and whether it has a
+// rest parameter, or false if lambdaList is malformed.
+func ParseLambdaList(lambdaList ilos.Instance) ([]ilos.Instance, bool, bool) {
+	parameters, ok := ProperList(lambdaList)
+	if !ok {
+		return nil, false, false
+	}
+	for i, cadr := range parameters {
+		if !ilos.InstanceOf(SymbolClass, cadr) {
+			return nil, false, false
+		}
+		if cadr == NewSymbol(":REST") || cadr == NewSymbol("&REST") {
+			return parameters, true, i == len(parameters)-2
+		}
+	}
+	return parameters, false, true
+}
@@ -29,3 +29,10 @@ func NewTagbodyTag(tag, uid ilos.Instance) ilos.Instance {
 		NewSymbol("IRIS.TAG"), tag,
 		NewSymbol("IRIS.UID"), uid)
 }
+func NewRestartTag(tag, uid, object ilos.Instance) ilos.Instance {
+	return Create(env.NewEnvironment(nil, nil, nil, nil),
+		RestartTagClass,
+		NewSymbol("IRIS.TAG"), tag,
+		NewSymbol("IRIS.UID"), uid,
+		NewSymbol("IRIS.OBJECT"), object)
+}
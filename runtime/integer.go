@@ -5,7 +5,7 @@
 package runtime
 
 import (
-	"math"
+	"math/big"
 
 	"github.com/ta2gch/iris/runtime/env"
 	"github.com/ta2gch/iris/runtime/ilos"
@@ -13,13 +13,64 @@ import (
 	"github.com/ta2gch/iris/runtime/ilos/instance"
 )
 
+// convInt returns the value of the integer z as an int. A bignum does not
+// fit in one and signals a domain error, as do indices and lengths too large
+// to be used.
 func convInt(e env.Environment, z ilos.Instance) (int, ilos.Instance) {
-	if err := ensure(e, class.Integer, z); err != nil {
+	if err := ensureFixnum(e, z); err != nil {
 		return 0, err
 	}
 	return int(z.(instance.Integer)), nil
 }
 
+// bigInt returns the value of the integer z as a big.Int, which the caller
+// may modify.
+func bigInt(z ilos.Instance) *big.Int {
+	if b, ok := z.(instance.Bignum); ok {
+		return new(big.Int).Set(b.Int())
+	}
+	return big.NewInt(int64(z.(instance.Integer)))
+}
+
+// smallInts returns the values of the integers z1 and z2 if both are no
+// larger in magnitude than 2^31, so that their product fits in an int.
+func smallInts(z1, z2 ilos.Instance) (int, int, bool) {
+	a, ok1 := z1.(instance.Integer)
+	b, ok2 := z2.(instance.Integer)
+	if !ok1 || !ok2 || a < -1<<31 || a > 1<<31 || b < -1<<31 || b > 1<<31 {
+		return 0, 0, false
+	}
+	return int(a), int(b), true
+}
+
+// addInteger, subInteger and mulInteger compute with ints, and with big.Int
+// only when the result would overflow.
+
+func addInteger(z1, z2 ilos.Instance) ilos.Instance {
+	a, ok1 := z1.(instance.Integer)
+	b, ok2 := z2.(instance.Integer)
+	if c := a + b; ok1 && ok2 && (c > a) == (b > 0) {
+		return c
+	}
+	return instance.NewBigInteger(new(big.Int).Add(bigInt(z1), bigInt(z2)))
+}
+
+func subInteger(z1, z2 ilos.Instance) ilos.Instance {
+	a, ok1 := z1.(instance.Integer)
+	b, ok2 := z2.(instance.Integer)
+	if c := a - b; ok1 && ok2 && (c < a) == (b > 0) {
+		return c
+	}
+	return instance.NewBigInteger(new(big.Int).Sub(bigInt(z1), bigInt(z2)))
+}
+
+func mulInteger(z1, z2 ilos.Instance) ilos.Instance {
+	if a, b, ok := smallInts(z1, z2); ok {
+		return instance.NewInteger(a * b)
+	}
+	return instance.NewBigInteger(new(big.Int).Mul(bigInt(z1), bigInt(z2)))
+}
+
 // Integerp returns t if obj is an integer (instance of class integer);
 // otherwise, returns nil. obj may be any ISLISP object.
 func Integerp(e env.Environment, obj ilos.Instance) (ilos.Instance, ilos.Instance) {
@@ -32,26 +83,30 @@ func Integerp(e env.Environment, obj ilos.Instance) (ilos.Instance, ilos.Instanc
 // Div returns the greatest integer less than or equal to the quotient of z1 and
 // z2. An error shall be signaled if z2 is zero (error-id. division-by-zero).
 func Div(e env.Environment, z1, z2 ilos.Instance) (ilos.Instance, ilos.Instance) {
-	a, err := convInt(e, z1)
-	if err != nil {
-		return nil, err
-	}
-	b, err := convInt(e, z2)
-	if err != nil {
+	if err := ensure(e, class.Integer, z1, z2); err != nil {
 		return nil, err
 	}
-	if b == 0 {
+	if z2 == instance.NewInteger(0) {
 		operation := instance.NewSymbol("DIV")
 		operands, err := List(e, z1, z2)
 		if err != nil {
 			return nil, err
 		}
-		return SignalCondition(e, instance.NewArithmeticError(e, operation, operands), Nil)
+		return SignalCondition(e, instance.NewDivisionByZero(e, operation, operands), Nil)
 	}
-	if a*b < 0 { // Issue #2
-		return instance.NewInteger(a/b - 1), nil
+	if a, b, ok := smallInts(z1, z2); ok {
+		q := a / b
+		if a%b != 0 && (a < 0) != (b < 0) {
+			q--
+		}
+		return instance.NewInteger(q), nil
+	}
+	a, b := bigInt(z1), bigInt(z2)
+	q, m := new(big.Int).QuoRem(a, b, new(big.Int))
+	if m.Sign() != 0 && m.Sign() != b.Sign() {
+		q.Sub(q, big.NewInt(1))
 	}
-	return instance.NewInteger(a / b), nil
+	return instance.NewBigInteger(q), nil
 }
 
 // Mod returns the remainder of the integer division of z1 by z2. The sign of
@@ -64,11 +119,7 @@ func Mod(e env.Environment, z1, z2 ilos.Instance) (ilos.Instance, ilos.Instance)
 	if err != nil {
 		return nil, err
 	}
-	g, err := Multiply(e, f, z2)
-	if err != nil {
-		return nil, err
-	}
-	return Substruct(e, z1, g) // Issue #2
+	return subInteger(z1, mulInteger(f, z2)), nil
 }
 
 // Gcd returns the greatest common divisor of its integer arguments. The result
@@ -77,54 +128,41 @@ func Mod(e env.Environment, z1, z2 ilos.Instance) (ilos.Instance, ilos.Instance)
 // error shall be signaled if either z1 or z2 is not an integer (error-id.
 // domain-error).
 func Gcd(e env.Environment, z1, z2 ilos.Instance) (ilos.Instance, ilos.Instance) {
-	gcd := func(x, y int) int {
-		for y != 0 {
-			x, y = y, x%y
-		}
-		return x
-	}
-	a, err := convInt(e, z1)
-	if err != nil {
-		return nil, err
-	}
-	b, err := convInt(e, z2)
-	if err != nil {
+	if err := ensure(e, class.Integer, z1, z2); err != nil {
 		return nil, err
 	}
-	return instance.NewInteger(gcd(a, b)), nil
+	return gcd(z1, z2), nil
+}
+
+func gcd(z1, z2 ilos.Instance) ilos.Instance {
+	a, b := bigInt(z1), bigInt(z2)
+	return instance.NewBigInteger(new(big.Int).GCD(nil, nil, a.Abs(a), b.Abs(b)))
 }
 
 // Lcm returns the least common multiple of its integer arguments. An error
 // shall be signaled if either z1 or z2 is not an integer (error-id.
 // domain-error).
 func Lcm(e env.Environment, z1, z2 ilos.Instance) (ilos.Instance, ilos.Instance) {
-	gcd := func(x, y int) int {
-		for y != 0 {
-			x, y = y, x%y
-		}
-		return x
-	}
-	a, err := convInt(e, z1)
-	if err != nil {
+	if err := ensure(e, class.Integer, z1, z2); err != nil {
 		return nil, err
 	}
-	b, err := convInt(e, z2)
-	if err != nil {
-		return nil, err
+	if z1 == instance.NewInteger(0) || z2 == instance.NewInteger(0) {
+		return instance.NewInteger(0), nil
 	}
-	return instance.NewInteger(a * b / gcd(a, b)), nil
+	a := bigInt(mulInteger(z1, z2))
+	return instance.NewBigInteger(a.Quo(a.Abs(a), bigInt(gcd(z1, z2)))), nil
 }
 
 // Isqrt Returns the greatest integer less than or equal to the exact positive
 // square root of z . An error shall be signaled if z is not a non-negative
 // integer (error-id. domain-error).
 func Isqrt(e env.Environment, z ilos.Instance) (ilos.Instance, ilos.Instance) {
-	a, err := convInt(e, z)
-	if err != nil {
+	if err := ensure(e, class.Integer, z); err != nil {
 		return nil, err
 	}
-	if a < 0 {
+	a := bigInt(z)
+	if a.Sign() < 0 {
 		return SignalCondition(e, instance.NewDomainError(e, z, class.Number), Nil)
 	}
-	return instance.NewInteger(int(math.Sqrt(float64(a)))), nil
+	return instance.NewBigInteger(a.Sqrt(a)), nil
 }
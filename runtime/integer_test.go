@@ -0,0 +1,112 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package runtime
+
+import "testing"
+
+func TestBignum(t *testing.T) {
+	execTests(t, Integerp, []test{
+		{
+			exp:     `(defun fact (n) (if (= n 0) 1 (* n (fact (- n 1)))))`,
+			want:    `'fact`,
+			wantErr: false,
+		},
+		{
+			exp:     `(fact 25)`,
+			want:    `15511210043330985984000000`,
+			wantErr: false,
+		},
+		{
+			exp:     `(quotient (fact 25) (fact 24))`,
+			want:    `25`,
+			wantErr: false,
+		},
+		{
+			exp:     `(* 4611686018427387904 2)`,
+			want:    `9223372036854775808`,
+			wantErr: false,
+		},
+		{
+			exp:     `(- -9223372036854775808 1)`,
+			want:    `-9223372036854775809`,
+			wantErr: false,
+		},
+		{
+			exp:     `(+ 9223372036854775808 -1)`,
+			want:    `9223372036854775807`,
+			wantErr: false,
+		},
+		{
+			exp:     `(instancep 9223372036854775808 (class <integer>))`,
+			want:    `t`,
+			wantErr: false,
+		},
+		{
+			exp:     `(integerp 100000000000000000000000)`,
+			want:    `t`,
+			wantErr: false,
+		},
+		{
+			exp:     `(expt 2 100)`,
+			want:    `1267650600228229401496703205376`,
+			wantErr: false,
+		},
+		{
+			exp:     `(list (div (expt 2 100) -3) (mod (expt 2 100) -3))`,
+			want:    `'(-422550200076076467165567735126 -2)`,
+			wantErr: false,
+		},
+		{
+			exp:     `(list (div -7 2) (mod -7 2))`,
+			want:    `'(-4 1)`,
+			wantErr: false,
+		},
+		{
+			exp:     `(list (gcd (expt 2 80) (expt 6 40)) (lcm 4 6) (isqrt (expt 10 40)))`,
+			want:    `'(1099511627776 12 100000000000000000000)`,
+			wantErr: false,
+		},
+		{
+			exp:     `(list (= (expt 2 70) (* (expt 2 35) (expt 2 35))) (< 1 (expt 2 70)) (> (- (expt 2 70)) 1))`,
+			want:    `'(t t nil)`,
+			wantErr: false,
+		},
+		{
+			exp:     `(eql (expt 2 70) (expt 2 70))`,
+			want:    `t`,
+			wantErr: false,
+		},
+		{
+			exp:     `(floor 1e20)`,
+			want:    `100000000000000000000`,
+			wantErr: false,
+		},
+		{
+			exp:     `(float (expt 2 70))`,
+			want:    `1180591620717411303424.0`,
+			wantErr: false,
+		},
+		{
+			exp:     `(let ((s (create-string-output-stream))) (format-integer s (expt 2 64) 16) (get-output-stream-string s))`,
+			want:    `"10000000000000000"`,
+			wantErr: false,
+		},
+		{
+			exp:     `(div 1 0)`,
+			want:    `nil`,
+			wantErr: true,
+		},
+		{
+			exp:     `(create-list (expt 2 70) 0)`,
+			want:    `nil`,
+			wantErr: true,
+		},
+		{
+			exp:     `(expt 2 (expt 2 70))`,
+			want:    `nil`,
+			wantErr: true,
+		},
+	})
+}
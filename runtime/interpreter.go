@@ -0,0 +1,208 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package runtime
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ta2gch/iris/reader/parser"
+	"github.com/ta2gch/iris/reader/tokenizer"
+	"github.com/ta2gch/iris/runtime/env"
+	"github.com/ta2gch/iris/runtime/ilos"
+	"github.com/ta2gch/iris/runtime/ilos/class"
+	"github.com/ta2gch/iris/runtime/ilos/instance"
+	"github.com/ta2gch/iris/runtime/pretty"
+	"github.com/ta2gch/iris/runtime/vm"
+)
+
+// Options configures a new interpreter. Streams left nil default to the
+// standard input, output and error of the process.
+type Options struct {
+	StandardInput  io.Reader
+	StandardOutput io.Writer
+	ErrorOutput    io.Writer
+
+	// MaxSteps bounds the number of forms one call of an Eval method may
+	// evaluate. Zero means no limit.
+	MaxSteps int
+
+	// Capability restricts the file access of scripts. Root is the directory
+	// readable under CapabilityReadOnly.
+	Capability Capability
+	Root       string
+
+	// Backend selects how forms are evaluated.
+	Backend Backend
+
+	// IEEE makes float operations and the reader return infinities, NaN and
+	// denormals as IEEE 754 does, instead of signaling
+	// <floating-point-overflow>, <floating-point-underflow> or
+	// <arithmetic-error>.
+	IEEE bool
+
+	// Debugger, if not nil, is called with every error no handler handles,
+	// before the evaluation is unwound (see Break).
+	Debugger func(b *Break) (ilos.Instance, ilos.Instance)
+}
+
+// Backend is an evaluator of forms.
+type Backend int
+
+const (
+	// BackendCompiler compiles forms to trees of Go closures.
+	BackendCompiler Backend = iota
+	// BackendVM compiles forms to bytecode for the virtual machine of package
+	// vm.
+	BackendVM
+)
+
+func (b Backend) String() string {
+	if b == BackendVM {
+		return "vm"
+	}
+	return "compiler"
+}
+
+// newMachine creates a virtual machine which evaluates the special forms it
+// does not implement with this package.
+func newMachine() *vm.Machine {
+	return vm.New(vm.Runtime{
+		Signal: SignalCondition,
+		Special: func(e env.Environment, special ilos.Instance, forms ...ilos.Instance) (ilos.Instance, ilos.Instance) {
+			return force(special.(instance.Applicable).Apply(e.NewLexical(), forms...))
+		},
+		Check:  checkBudget,
+		Unique: uniqueInt,
+		Locate: locate,
+	})
+}
+
+// Interpreter is an independent ISLisp top level. Every interpreter has its
+// own environment, standard streams and builtin table, so definitions made in
+// one are never visible from another.
+type Interpreter struct {
+	Environment env.Environment
+	maxSteps    int
+	eval        func(e env.Environment, obj ilos.Instance) (ilos.Instance, ilos.Instance)
+}
+
+// New creates an interpreter whose top level environment holds every builtin.
+func New(options Options) *Interpreter {
+	stdin, stdout, stderr := options.StandardInput, options.StandardOutput, options.ErrorOutput
+	if stdin == nil {
+		stdin = os.Stdin
+	}
+	if stdout == nil {
+		stdout = os.Stdout
+	}
+	if stderr == nil {
+		stderr = os.Stderr
+	}
+	e := env.NewEnvironment(
+		instance.NewStream(stdin, nil),
+		instance.NewStream(nil, stdout),
+		instance.NewStream(nil, stderr),
+		instance.NewFunction(instance.NewSymbol("TOP-LEVEL-HANDLER"), TopLevelHander),
+	)
+	e.IEEE = options.IEEE
+	defineBuiltins(e)
+	switch options.Capability {
+	case CapabilityReadOnly:
+		restrict(e, readOnlyAccess(options.Root))
+	case CapabilityPure:
+		restrict(e, noAccess)
+	}
+	i := &Interpreter{Environment: e, maxSteps: options.MaxSteps, eval: evalCompiled}
+	if options.Backend == BackendVM {
+		i.eval = newMachine().Eval
+	}
+	if options.Debugger != nil {
+		i.Environment.Debugged = true
+		i.Environment.Handler = instance.NewFunction(instance.NewSymbol("TOP-LEVEL-HANDLER"), func(e env.Environment, condition ilos.Instance) (ilos.Instance, ilos.Instance) {
+			if !ilos.InstanceOf(class.Error, condition) {
+				return TopLevelHander(e, condition)
+			}
+			return options.Debugger(&Break{Condition: condition, e: e, eval: i.eval})
+		})
+	}
+	return i
+}
+
+// Pprint writes obj to w over lines no wider than the value of
+// *print-right-margin* in the interpreter.
+func (i *Interpreter) Pprint(w io.Writer, obj ilos.Instance) error {
+	return pretty.Fprint(w, obj, rightMargin(i.Environment))
+}
+
+// Read reads the next form from the standard input of the interpreter.
+func (i *Interpreter) Read() (ilos.Instance, ilos.Instance) {
+	return Read(i.Environment)
+}
+
+// Eval compiles obj and evaluates it in the top level environment of the
+// interpreter.
+func (i *Interpreter) Eval(obj ilos.Instance) (ilos.Instance, ilos.Instance) {
+	return i.EvalContext(context.Background(), obj)
+}
+
+// EvalContext evaluates obj like Eval. The evaluation is aborted with an
+// <evaluation-aborted> condition when ctx is done, so a deadline of ctx works
+// as a timeout, or when the step budget of the interpreter is exhausted.
+func (i *Interpreter) EvalContext(ctx context.Context, obj ilos.Instance) (ilos.Instance, ilos.Instance) {
+	return i.eval(i.environment(ctx), obj)
+}
+
+// environment returns the top level environment bounded by ctx and the step
+// budget of the interpreter.
+func (i *Interpreter) environment(ctx context.Context) env.Environment {
+	e := i.Environment
+	if ctx.Done() != nil || i.maxSteps > 0 {
+		e.Budget = &env.Budget{Context: ctx, MaxSteps: i.maxSteps}
+	}
+	return e
+}
+
+// EvalReader reads and evaluates every form from r in order and returns the
+// value of the last one, or Nil if r holds no form. A form which r ends
+// inside is a <parse-error>.
+func (i *Interpreter) EvalReader(r io.Reader) (ilos.Instance, ilos.Instance) {
+	return i.EvalReaderContext(context.Background(), r)
+}
+
+// EvalReaderContext is EvalReader bounded by ctx and the step budget of the
+// interpreter (see EvalContext). Every form read from r counts against the
+// same budget.
+func (i *Interpreter) EvalReaderContext(ctx context.Context, r io.Reader) (ilos.Instance, ilos.Instance) {
+	e := i.environment(ctx)
+	t := tokenizer.NewReader(r)
+	ret := Nil
+	for {
+		t.SetSyntax(syntax(e))
+		obj, err := parser.Parse(t)
+		if err != nil {
+			if ilos.InstanceOf(class.EndOfStream, err) {
+				return ret, nil
+			}
+			return nil, err
+		}
+		if ret, err = i.eval(e, obj); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// EvalString reads and evaluates every form in s (see EvalReader).
+func (i *Interpreter) EvalString(s string) (ilos.Instance, ilos.Instance) {
+	return i.EvalReader(strings.NewReader(s))
+}
+
+// EvalStringContext is EvalString bounded by ctx and the step budget of the
+// interpreter (see EvalContext).
+func (i *Interpreter) EvalStringContext(ctx context.Context, s string) (ilos.Instance, ilos.Instance) {
+	return i.EvalReaderContext(ctx, strings.NewReader(s))
+}
@@ -0,0 +1,354 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ta2gch/iris/runtime/ilos"
+	"github.com/ta2gch/iris/runtime/ilos/class"
+	"github.com/ta2gch/iris/runtime/ilos/instance"
+)
+
+func TestInterpreter_Isolation(t *testing.T) {
+	a := New(Options{})
+	b := New(Options{})
+	if _, err := a.EvalString(`(defun foo (x) (+ x 1)) (defglobal bar 10)`); err != nil {
+		t.Fatalf("EvalString() err = %v", err)
+	}
+	if got, err := a.EvalString(`(foo bar)`); err != nil || got != instance.NewInteger(11) {
+		t.Errorf("EvalString() got = %v, err = %v, want 11", got, err)
+	}
+	if _, err := b.EvalString(`(foo 1)`); err == nil || !ilos.InstanceOf(class.UndefinedFunction, err) {
+		t.Errorf("EvalString() err = %v, want <undefined-function>", err)
+	}
+	if _, err := b.EvalString(`bar`); err == nil || !ilos.InstanceOf(class.UndefinedVariable, err) {
+		t.Errorf("EvalString() err = %v, want <undefined-variable>", err)
+	}
+}
+
+func TestInterpreter_StandardOutput(t *testing.T) {
+	out := new(bytes.Buffer)
+	i := New(Options{StandardOutput: out})
+	if _, err := i.EvalString(`(format (standard-output) "~A-~A" 1 2)`); err != nil {
+		t.Fatalf("EvalString() err = %v", err)
+	}
+	if got := out.String(); got != "1-2" {
+		t.Errorf("StandardOutput got = %q, want %q", got, "1-2")
+	}
+}
+
+func TestInterpreter_EvalString(t *testing.T) {
+	tests := []struct {
+		source  string
+		want    string
+		wantErr bool
+	}{
+		{"(+ 1 2) ; the end", "3", false},
+		{"", "NIL", false},
+		{"(+ 1 2) (+ 1", "", true},
+		{"'(a", "", true},
+		{"'", "", true},
+	}
+	for _, tt := range tests {
+		got, err := New(Options{}).EvalString(tt.source)
+		if tt.wantErr {
+			if err == nil || !ilos.InstanceOf(class.ParseError, err) {
+				t.Errorf("EvalString(%q) err = %v, want <parse-error>", tt.source, err)
+			}
+			continue
+		}
+		if err != nil || fmt.Sprint(got) != tt.want {
+			t.Errorf("EvalString(%q) = %v, err = %v, want %v", tt.source, got, err, tt.want)
+		}
+	}
+}
+
+func TestInterpreter_RegisterFunc(t *testing.T) {
+	i := New(Options{})
+	funcs := map[string]interface{}{
+		"scale": func(x int, s string) (float64, error) {
+			if s == "" {
+				return 0, errors.New("empty unit")
+			}
+			return float64(x) * 1.5, nil
+		},
+		"go-sum":     func(xs ...float64) float64 { return xs[0] + xs[1] + xs[2] },
+		"go-upcase":  func(xs []string) []string { return []string{strings.ToUpper(xs[0]), xs[1]} },
+		"go-nothing": func() {},
+	}
+	for name, fn := range funcs {
+		if err := i.RegisterFunc(name, fn); err != nil {
+			t.Fatalf("RegisterFunc() err = %v", err)
+		}
+	}
+	if err := i.RegisterFunc("bad", func(chan int) {}); err == nil {
+		t.Errorf("RegisterFunc() err = nil, want unsupported parameter")
+	}
+	tests := []struct {
+		exp     string
+		want    string
+		wantErr ilos.Class
+	}{
+		{`(scale 2 "m")`, `3.0`, nil},
+		{`(scale 2 "")`, ``, class.SimpleError},
+		{`(scale "2" "m")`, ``, class.DomainError},
+		{`(scale 2)`, ``, class.ProgramError},
+		{`(go-sum 1 2.5 3)`, `6.5`, nil},
+		{`(go-upcase '("ab" "cd"))`, `#("AB" "cd")`, nil},
+		{`(go-upcase #("ab" "cd"))`, `#("AB" "cd")`, nil},
+		{`(go-nothing)`, `nil`, nil},
+	}
+	for _, tt := range tests {
+		got, err := i.EvalString(tt.exp)
+		if tt.wantErr != nil {
+			if err == nil || !ilos.InstanceOf(tt.wantErr, err) {
+				t.Errorf("%v err = %v, want %v", tt.exp, err, tt.wantErr)
+			}
+			continue
+		}
+		want, _ := i.EvalString(tt.want)
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("%v got = %v, err = %v, want %v", tt.exp, got, err, want)
+		}
+	}
+}
+
+func TestInterpreter_EvalContext(t *testing.T) {
+	reason := func(err ilos.Instance) ilos.Instance {
+		if err == nil || !ilos.InstanceOf(class.EvaluationAborted, err) {
+			return nil
+		}
+		r, _ := err.(instance.Instance).GetSlotValue(instance.NewSymbol("REASON"), class.EvaluationAborted)
+		return r
+	}
+	i := New(Options{})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := i.EvalStringContext(ctx, `(while t)`); reason(err) != instance.NewSymbol(":TIMEOUT") {
+		t.Errorf("EvalStringContext() err = %v, want :TIMEOUT", err)
+	}
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if _, err := i.EvalStringContext(ctx, `(tagbody a (go a))`); reason(err) != instance.NewSymbol(":CANCELED") {
+		t.Errorf("EvalStringContext() err = %v, want :CANCELED", err)
+	}
+	j := New(Options{MaxSteps: 1000})
+	if _, err := j.EvalString(`(for ((i 0 (+ i 1))) ((= i 1000000)))`); reason(err) != instance.NewSymbol(":STEP-LIMIT") {
+		t.Errorf("EvalString() err = %v, want :STEP-LIMIT", err)
+	}
+	if got, err := j.EvalString(`(for ((i 0 (+ i 1))) ((= i 10) i))`); err != nil || got != instance.NewInteger(10) {
+		t.Errorf("EvalString() got = %v, err = %v, want 10", got, err)
+	}
+}
+
+func TestInterpreter_IEEE(t *testing.T) {
+	i := New(Options{})
+	tests := []struct {
+		exp       string
+		class     ilos.Class
+		operation string
+		operands  string
+	}{
+		{`(exp 1000)`, class.FloatingPointOverflow, "EXP", "(1000)"},
+		{`(* 1e200 1e200)`, class.FloatingPointOverflow, "*", "(1e+200 1e+200)"},
+		{`(quotient 1e300 1e-300)`, class.FloatingPointOverflow, "QUOTIENT", "(1e+300 1e-300)"},
+		{`(* 1e-200 1e-200)`, class.FloatingPointUnderflow, "*", "(1e-200 1e-200)"},
+		{`(exp -1000)`, class.FloatingPointUnderflow, "EXP", "(-1000)"},
+		{`(float (expt 10 400))`, class.FloatingPointOverflow, "FLOAT", ""},
+		{`(quotient 1 0)`, class.DivisionByZero, "QUOTIENT", "(1 0)"},
+		{`(sqrt (+ (expt 10 700) 1))`, class.FloatingPointOverflow, "SQRT", ""},
+		{`(parse-number "1e400")`, class.FloatingPointOverflow, "PARSE-NUMBER", `("1e400")`},
+		{`(parse-number "-1e-310")`, class.FloatingPointUnderflow, "PARSE-NUMBER", `("-1e-310")`},
+		{`(+ 1e400 1)`, class.FloatingPointOverflow, "READ", `("1e400")`},
+		{`(list 1e-310)`, class.FloatingPointUnderflow, "READ", `("1e-310")`},
+	}
+	for _, tt := range tests {
+		_, err := i.EvalString(tt.exp)
+		if err == nil || !ilos.InstanceOf(tt.class, err) {
+			t.Errorf("EvalString(%v) err = %v, want %v", tt.exp, err, tt.class)
+			continue
+		}
+		operation, _ := err.(instance.Instance).GetSlotValue(instance.NewSymbol("OPERATION"), class.ArithmeticError)
+		operands, _ := err.(instance.Instance).GetSlotValue(instance.NewSymbol("OPERANDS"), class.ArithmeticError)
+		if operation != instance.NewSymbol(tt.operation) || tt.operands != "" && operands.String() != tt.operands {
+			t.Errorf("EvalString(%v) operation = %v, operands = %v, want %v %v", tt.exp, operation, operands, tt.operation, tt.operands)
+		}
+	}
+	j := New(Options{IEEE: true})
+	if got, err := j.EvalString(`(exp 1000)`); err != nil || !math.IsInf(float64(got.(instance.Float)), 1) {
+		t.Errorf("EvalString() got = %v, err = %v, want +Inf", got, err)
+	}
+	if got, err := j.EvalString(`(* 1e-200 1e-200)`); err != nil || got != instance.NewFloat(0) {
+		t.Errorf("EvalString() got = %v, err = %v, want 0.0", got, err)
+	}
+	for _, exp := range []string{`(parse-number "1e400")`, `1e400`} {
+		if got, err := j.EvalString(exp); err != nil || !math.IsInf(float64(got.(instance.Float)), 1) {
+			t.Errorf("EvalString(%v) got = %v, err = %v, want +Inf", exp, got, err)
+		}
+	}
+	for exp, want := range map[string]float64{
+		`(log (expt 10 400))`:               400 * math.Ln10,
+		`(log (quotient 1 (expt 10 400)))`:  -400 * math.Ln10,
+		`(log (quotient (expt 10 400) 3))`:  400*math.Ln10 - math.Log(3),
+		`(sqrt (* 2 (expt 10 400)))`:        math.Sqrt2 * 1e200,
+		`(sqrt (+ (expt 10 401) 1))`:        math.Sqrt(10) * 1e200,
+		`(sqrt (quotient 2 (expt 10 400)))`: math.Sqrt2 * 1e-200,
+	} {
+		got, err := i.EvalString(exp)
+		if f, ok := got.(instance.Float); err != nil || !ok || math.Abs(float64(f)-want) > math.Abs(want)*1e-12 {
+			t.Errorf("EvalString(%v) got = %v, err = %v, want %v", exp, got, err, want)
+		}
+	}
+	if got, err := i.EvalString(`(list (sqrt 1e300) (sqrt 16.0))`); err != nil || fmt.Sprint(got) != "(1e+150 4)" {
+		t.Errorf("EvalString() got = %v, err = %v, want (1e+150 4)", got, err)
+	}
+}
+
+func TestInterpreter_Capability(t *testing.T) {
+	root, err := ioutil.TempDir("", "iris")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	if err := ioutil.WriteFile(filepath.Join(root, "rules.lsp"), []byte("(1 2)"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	outside, err := ioutil.TempFile("", "iris")
+	if err != nil {
+		t.Fatal(err)
+	}
+	outside.Close()
+	defer os.Remove(outside.Name())
+	tests := []struct {
+		capability Capability
+		exp        string
+		want       string
+		wantErr    ilos.Class
+	}{
+		{CapabilityFull, `(with-open-input-file (s "` + filepath.Join(root, "rules.lsp") + `") (read s))`, `'(1 2)`, nil},
+		{CapabilityFull, `(progn (with-open-output-file (s "` + filepath.Join(root, "out.lsp") + `") (format s "(1 2 3)")) (with-open-output-file (s "` + filepath.Join(root, "out.lsp") + `") (format s "(4)")) (with-open-input-file (s "` + filepath.Join(root, "out.lsp") + `") (list (read s) (read s nil nil))))`, `'((4) nil)`, nil},
+		{CapabilityFull, `(progn (with-open-io-file (s "` + filepath.Join(root, "io.lsp") + `") (format s "(5)")) (with-open-input-file (s "` + filepath.Join(root, "io.lsp") + `") (read s)))`, `'(5)`, nil},
+		{CapabilityPure, `(open-input-file "rules.lsp")`, ``, class.AccessDenied},
+		{CapabilityPure, `(with-open-input-file (s "` + outside.Name() + `") (read s))`, ``, class.AccessDenied},
+		{CapabilityReadOnly, `(with-open-input-file (s "rules.lsp") (read s))`, `'(1 2)`, nil},
+		{CapabilityReadOnly, `(read (open-input-file "` + filepath.Join(root, "rules.lsp") + `"))`, `'(1 2)`, nil},
+		{CapabilityReadOnly, `(open-input-file "` + outside.Name() + `")`, ``, class.AccessDenied},
+		{CapabilityReadOnly, `(open-input-file "../` + filepath.Base(outside.Name()) + `")`, ``, class.AccessDenied},
+		{CapabilityReadOnly, `(open-output-file "rules.lsp")`, ``, class.AccessDenied},
+		{CapabilityReadOnly, `(with-open-io-file (s "new.lsp") s)`, ``, class.AccessDenied},
+	}
+	for _, tt := range tests {
+		i := New(Options{Capability: tt.capability, Root: root})
+		got, err := i.EvalString(tt.exp)
+		if tt.wantErr != nil {
+			if err == nil || !ilos.InstanceOf(tt.wantErr, err) {
+				t.Errorf("%v err = %v, want %v", tt.exp, err, tt.wantErr)
+			}
+			continue
+		}
+		want, _ := i.EvalString(tt.want)
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("%v got = %v, err = %v, want %v", tt.exp, got, err, want)
+		}
+	}
+}
+
+func TestInterpreter_CapabilityProbe(t *testing.T) {
+	root, err := ioutil.TempDir("", "iris")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	outside, err := ioutil.TempFile("", "iris")
+	if err != nil {
+		t.Fatal(err)
+	}
+	outside.Close()
+	defer os.Remove(outside.Name())
+	missing := outside.Name() + "-missing"
+	i := New(Options{Capability: CapabilityReadOnly, Root: root})
+	for _, names := range [][2]string{
+		{outside.Name(), missing},
+		{"../" + filepath.Base(outside.Name()), "../" + filepath.Base(missing)},
+	} {
+		_, existing := i.EvalString(`(open-input-file "` + names[0] + `")`)
+		_, absent := i.EvalString(`(open-input-file "` + names[1] + `")`)
+		if existing == nil || !ilos.InstanceOf(class.AccessDenied, existing) {
+			t.Errorf("%v err = %v, want %v", names[0], existing, class.AccessDenied)
+		}
+		got := strings.Replace(fmt.Sprint(absent), names[1], names[0], 1)
+		if want := fmt.Sprint(existing); got != want {
+			t.Errorf("%v err = %v, want %v", names[1], got, want)
+		}
+	}
+}
+
+func TestInterpreter_Location(t *testing.T) {
+	dir, err := ioutil.TempDir("", "iris")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "script.lsp")
+	source := "(defun f (x)\n  (+ x 1))\n\n(defun g (y)\n  (let ((z 2))\n    (car y)))\n(f 1)\n  (g 5)\n"
+	if err := ioutil.WriteFile(path, []byte(source), 0666); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		exp  string
+		want string
+	}{
+		{"(defun f (x)\n  (+ x 1))\n(f 'a)", "2:3"},
+		{"(defun g (y)\n  (car y))\n(g '(1))\n  (g 5)", "2:3"},
+		{"(list 1\n  undefined-variable)", "1:1"},
+		{"(progn\n  (undefined-function 1))", "2:3"},
+		{"(list 1 2)\n (a #\\nosuch)", "2:5"},
+	}
+	for _, backend := range []Backend{BackendCompiler, BackendVM} {
+		for _, tt := range tests {
+			_, err := New(Options{Backend: backend}).EvalString(tt.exp)
+			if err == nil {
+				t.Errorf("%v: %q err = nil", backend, tt.exp)
+				continue
+			}
+			if got, _ := Location(err); got != tt.want {
+				t.Errorf("%v: %q Location() = %q, want %q", backend, tt.exp, got, tt.want)
+			}
+			if got := fmt.Sprint(err); strings.Contains(got, "IRIS.") {
+				t.Errorf("%v: %q err = %v, want no internal slots", backend, tt.exp, got)
+			}
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		i := New(Options{StandardInput: file, Backend: backend})
+		for {
+			exp, err := i.Read()
+			if err != nil {
+				t.Fatalf("%v: Read() err = %v", backend, err)
+			}
+			if _, err = i.Eval(exp); err != nil {
+				if got, want := fmt.Sprintln(Location(err)), path+":6:5 true\n"; got != want {
+					t.Errorf("%v: Location() = %v, want %v", backend, got, want)
+				}
+				break
+			}
+		}
+		file.Close()
+	}
+}
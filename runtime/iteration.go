@@ -21,7 +21,10 @@ func While(e env.Environment, testForm ilos.Instance, bodyForm ...ilos.Instance)
 	if err != nil {
 		return nil, err
 	}
-	for test == T {
+	for test != Nil {
+		if err := checkBudget(e); err != nil {
+			return nil, err
+		}
 		_, err := Progn(e, bodyForm...)
 		if err != nil {
 			return nil, err
@@ -91,6 +94,9 @@ func For(e env.Environment, iterationSpecs, endTestAndResults ilos.Instance, for
 		return nil, err
 	}
 	for test == Nil {
+		if err := checkBudget(e); err != nil {
+			return nil, err
+		}
 		_, err := Progn(a, forms...)
 		if err != nil {
 			return nil, err
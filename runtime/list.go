@@ -27,7 +27,7 @@ func Listp(e env.Environment, obj ilos.Instance) (ilos.Instance, ilos.Instance)
 // shall be signaled if i is not a non-negative integer (error-id.
 // domain-error).initial-element may be any ISLISP object.
 func CreateList(e env.Environment, i ilos.Instance, initialElement ...ilos.Instance) (ilos.Instance, ilos.Instance) {
-	if ok, _ := Integerp(e, i); ok == Nil {
+	if _, ok := i.(instance.Integer); !ok {
 		return nil, instance.NewDomainError(e, i, class.Integer)
 	}
 	if len(initialElement) > 1 {
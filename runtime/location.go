@@ -0,0 +1,56 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package runtime
+
+import (
+	"github.com/ta2gch/iris/reader/parser"
+	"github.com/ta2gch/iris/runtime/env"
+	"github.com/ta2gch/iris/runtime/ilos"
+	"github.com/ta2gch/iris/runtime/ilos/class"
+	"github.com/ta2gch/iris/runtime/ilos/instance"
+)
+
+// locate records the source location of form in the condition err, unless it
+// has the location of a form inside form already. Escapes are passed through.
+func locate(form, err ilos.Instance) ilos.Instance {
+	if !ilos.InstanceOf(class.SeriousCondition, err) {
+		return err
+	}
+	if _, ok := Location(err); ok {
+		return err
+	}
+	if span, ok := parser.Location(form); ok {
+		err.(instance.Instance).SetSlotValue(instance.NewSymbol("IRIS.LOCATION"), instance.NewString([]rune(span.String())), class.SeriousCondition)
+	}
+	return err
+}
+
+// Location returns the source location of the innermost form read by the
+// parser whose evaluation signaled condition, such as "script.lsp:12:5".
+func Location(condition ilos.Instance) (string, bool) {
+	if !ilos.InstanceOf(class.SeriousCondition, condition) {
+		return "", false
+	}
+	location, ok := condition.(instance.Instance).GetSlotValue(instance.NewSymbol("IRIS.LOCATION"), class.SeriousCondition)
+	if !ok {
+		return "", false
+	}
+	return string(location.(instance.String)), true
+}
+
+// located is c which records the location of form in the conditions it
+// returns.
+func located(form ilos.Instance, c code) code {
+	if _, ok := parser.Location(form); !ok {
+		return c
+	}
+	return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+		ret, err := c(e)
+		if err != nil {
+			return nil, locate(form, err)
+		}
+		return ret, nil
+	}
+}
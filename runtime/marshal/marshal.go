@@ -0,0 +1,419 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+// Package marshal converts Go values to ISLisp objects and back.
+//
+// Booleans become T or NIL, integers <integer>, floats <float>, strings
+// <string>, slices and arrays <general-vector>, maps association lists and
+// structs instances of a <standard-object> subclass made for the struct type.
+// Map keys keep their case, so string keys stay strings; symbol keys of
+// association lists become lower-cased strings. Struct fields are named by
+// upper-cased symbols, as the reader folds the case of symbols; the
+// `lisp:"name"` field tag overrides the name and `lisp:"-"` skips the field.
+package marshal
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ta2gch/iris/runtime/env"
+	"github.com/ta2gch/iris/runtime/ilos"
+	"github.com/ta2gch/iris/runtime/ilos/class"
+	"github.com/ta2gch/iris/runtime/ilos/instance"
+)
+
+var instanceType = reflect.TypeOf((*ilos.Instance)(nil)).Elem()
+
+// TypeError is returned by FromLisp when an object can not be stored in a Go
+// value of the given type.
+type TypeError struct {
+	Object ilos.Instance
+	Type   reflect.Type
+	Class  ilos.Class // the class an object must belong to
+}
+
+func (err *TypeError) Error() string {
+	return fmt.Sprintf("marshal: cannot convert %v to %v", err.Object, err.Type)
+}
+
+// ToLisp converts v to an ISLisp object. The instances made from structs are
+// initialized in e. Values of unsupported kinds such as channels and functions
+// become NIL.
+func ToLisp(e env.Environment, v interface{}) ilos.Instance {
+	return toLisp(e, reflect.ValueOf(v))
+}
+
+func toLisp(e env.Environment, v reflect.Value) ilos.Instance {
+	if !v.IsValid() {
+		return instance.Nil
+	}
+	if v.Type().Implements(instanceType) {
+		if (v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr) && v.IsNil() {
+			return instance.Nil
+		}
+		return v.Interface().(ilos.Instance)
+	}
+	switch v.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if v.IsNil() {
+			return instance.Nil
+		}
+		return toLisp(e, v.Elem())
+	case reflect.Bool:
+		if v.Bool() {
+			return instance.T
+		}
+		return instance.Nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return instance.NewInteger(int(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return instance.NewBigInteger(new(big.Int).SetUint64(v.Uint()))
+	case reflect.Float32, reflect.Float64:
+		return instance.NewFloat(v.Float())
+	case reflect.String:
+		return instance.NewString([]rune(v.String()))
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return instance.Nil
+		}
+		elements := make([]ilos.Instance, v.Len())
+		for i := range elements {
+			elements[i] = toLisp(e, v.Index(i))
+		}
+		return instance.NewGeneralVector(elements)
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		alist := instance.Nil
+		for i := len(keys) - 1; i >= 0; i-- {
+			pair := instance.NewCons(toLisp(e, keys[i]), toLisp(e, v.MapIndex(keys[i])))
+			alist = instance.NewCons(pair, alist)
+		}
+		return alist
+	case reflect.Struct:
+		c := Class(v.Type())
+		inits := []ilos.Instance{}
+		for _, f := range fields(v.Type()) {
+			inits = append(inits, f.name, toLisp(e, v.FieldByIndex(f.index)))
+		}
+		return instance.Create(e, c, inits...)
+	}
+	return instance.Nil
+}
+
+// FromLisp stores obj in the value pointed to by v, which must be a non-nil
+// pointer. When v points to an empty interface, integers are stored as int,
+// floats as float64, strings and symbols as string, characters as rune, lists
+// and vectors as []interface{} and standard objects as map[string]interface{}.
+func FromLisp(obj ilos.Instance, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("marshal: FromLisp needs a non-nil pointer, got %T", v)
+	}
+	return fromLisp(obj, rv.Elem())
+}
+
+func fromLisp(obj ilos.Instance, v reflect.Value) error {
+	t := v.Type()
+	mismatch := func(c ilos.Class) error {
+		return &TypeError{obj, t, c}
+	}
+	if t == instanceType {
+		v.Set(reflect.ValueOf(&obj).Elem())
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.Interface:
+		if t.NumMethod() != 0 {
+			return mismatch(class.Object)
+		}
+		if obj == instance.Nil {
+			v.Set(reflect.Zero(t))
+			return nil
+		}
+		n := natural(obj)
+		if n == nil {
+			v.Set(reflect.ValueOf(obj))
+			return nil
+		}
+		w := reflect.New(n).Elem()
+		if err := fromLisp(obj, w); err != nil {
+			return err
+		}
+		v.Set(w)
+		return nil
+	case reflect.Ptr:
+		if obj == instance.Nil && t.Elem().Kind() != reflect.Bool {
+			v.Set(reflect.Zero(t))
+			return nil
+		}
+		w := reflect.New(t.Elem())
+		if err := fromLisp(obj, w.Elem()); err != nil {
+			return err
+		}
+		v.Set(w)
+		return nil
+	case reflect.Bool:
+		v.SetBool(obj != instance.Nil)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var i int64
+		switch obj := obj.(type) {
+		case instance.Integer:
+			i = int64(obj)
+		case instance.Character:
+			if t.Kind() != reflect.Int32 {
+				return mismatch(class.Integer)
+			}
+			i = int64(obj)
+		default:
+			return mismatch(class.Integer)
+		}
+		if v.OverflowInt(i) {
+			return mismatch(class.Integer)
+		}
+		v.SetInt(i)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		var u uint64
+		switch obj := obj.(type) {
+		case instance.Integer:
+			if obj < 0 {
+				return mismatch(class.Integer)
+			}
+			u = uint64(obj)
+		case instance.Bignum:
+			if !obj.Int().IsUint64() {
+				return mismatch(class.Integer)
+			}
+			u = obj.Int().Uint64()
+		default:
+			return mismatch(class.Integer)
+		}
+		if v.OverflowUint(u) {
+			return mismatch(class.Integer)
+		}
+		v.SetUint(u)
+		return nil
+	case reflect.Float32, reflect.Float64:
+		switch obj := obj.(type) {
+		case instance.Integer:
+			v.SetFloat(float64(obj))
+		case instance.Bignum:
+			f, _ := new(big.Float).SetInt(obj.Int()).Float64()
+			v.SetFloat(f)
+		case instance.Ratio:
+			f, _ := obj.Rat().Float64()
+			v.SetFloat(f)
+		case instance.Float:
+			v.SetFloat(float64(obj))
+		default:
+			return mismatch(class.Float)
+		}
+		return nil
+	case reflect.String:
+		switch {
+		case ilos.InstanceOf(class.String, obj):
+			v.SetString(string(obj.(instance.String)))
+		case ilos.InstanceOf(class.Symbol, obj) && obj != instance.Nil:
+			v.SetString(string(obj.(instance.Symbol)))
+		default:
+			return mismatch(class.String)
+		}
+		return nil
+	case reflect.Slice, reflect.Array:
+		elements, ok := sequence(obj)
+		if !ok {
+			return mismatch(class.List)
+		}
+		if t.Kind() == reflect.Array {
+			if len(elements) != t.Len() {
+				return mismatch(class.GeneralVector)
+			}
+		} else {
+			v.Set(reflect.MakeSlice(t, len(elements), len(elements)))
+		}
+		for i, element := range elements {
+			if err := fromLisp(element, v.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Map:
+		pairs, ok := associations(obj)
+		if !ok {
+			return mismatch(class.List)
+		}
+		m := reflect.MakeMapWithSize(t, len(pairs))
+		for _, pair := range pairs {
+			k := reflect.New(t.Key()).Elem()
+			if t.Key().Kind() == reflect.String && ilos.InstanceOf(class.Symbol, pair[0]) {
+				k.SetString(strings.ToLower(string(pair[0].(instance.Symbol))))
+			} else if err := fromLisp(pair[0], k); err != nil {
+				return err
+			}
+			e := reflect.New(t.Elem()).Elem()
+			if err := fromLisp(pair[1], e); err != nil {
+				return err
+			}
+			m.SetMapIndex(k, e)
+		}
+		v.Set(m)
+		return nil
+	case reflect.Struct:
+		pairs, ok := associations(obj)
+		if !ok {
+			return mismatch(class.StandardObject)
+		}
+		values := map[ilos.Instance]ilos.Instance{}
+		for _, pair := range pairs {
+			values[pair[0]] = pair[1]
+		}
+		for _, f := range fields(t) {
+			if value, ok := values[f.name]; ok {
+				if err := fromLisp(value, v.FieldByIndex(f.index)); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	return mismatch(class.Object)
+}
+
+// natural returns the Go type obj is stored as in an empty interface, or nil
+// if obj is stored as it is.
+func natural(obj ilos.Instance) reflect.Type {
+	switch {
+	case obj == instance.T:
+		return reflect.TypeOf(true)
+	case ilos.InstanceOf(class.Bignum, obj):
+		return nil
+	case ilos.InstanceOf(class.Integer, obj):
+		return reflect.TypeOf(0)
+	case ilos.InstanceOf(class.Float, obj):
+		return reflect.TypeOf(0.0)
+	case ilos.InstanceOf(class.Character, obj):
+		return reflect.TypeOf('a')
+	case ilos.InstanceOf(class.String, obj), ilos.InstanceOf(class.Symbol, obj):
+		return reflect.TypeOf("")
+	case ilos.InstanceOf(class.GeneralVector, obj):
+		return reflect.TypeOf([]interface{}{})
+	case ilos.InstanceOf(class.Cons, obj):
+		if _, ok := sequence(obj); ok {
+			return reflect.TypeOf([]interface{}{})
+		}
+	case ilos.InstanceOf(class.StandardObject, obj):
+		return reflect.TypeOf(map[string]interface{}{})
+	}
+	return nil
+}
+
+// sequence returns the elements of a proper list or a general vector
+func sequence(obj ilos.Instance) ([]ilos.Instance, bool) {
+	if ilos.InstanceOf(class.GeneralVector, obj) {
+		return obj.(instance.GeneralVector), true
+	}
+	elements := []ilos.Instance{}
+	for ilos.InstanceOf(class.Cons, obj) {
+		elements = append(elements, obj.(*instance.Cons).Car)
+		obj = obj.(*instance.Cons).Cdr
+	}
+	return elements, obj == instance.Nil
+}
+
+// associations returns the key-value pairs of an association list or the slot
+// names and values of a standard object
+func associations(obj ilos.Instance) ([][2]ilos.Instance, bool) {
+	if o, ok := obj.(instance.Instance); ok && ilos.InstanceOf(class.StandardObject, obj) {
+		pairs := [][2]ilos.Instance{}
+		var walk func(c ilos.Class)
+		walk = func(c ilos.Class) {
+			for _, slot := range c.Slots() {
+				if value, ok := o.GetSlotValue(slot, c); ok {
+					pairs = append(pairs, [2]ilos.Instance{slot, value})
+				}
+			}
+			for _, super := range c.Supers() {
+				walk(super)
+			}
+		}
+		walk(o.Class())
+		return pairs, true
+	}
+	elements, ok := sequence(obj)
+	if !ok {
+		return nil, false
+	}
+	pairs := [][2]ilos.Instance{}
+	for _, element := range elements {
+		if !ilos.InstanceOf(class.Cons, element) {
+			return nil, false
+		}
+		pairs = append(pairs, [2]ilos.Instance{element.(*instance.Cons).Car, element.(*instance.Cons).Cdr})
+	}
+	return pairs, true
+}
+
+type field struct {
+	name  ilos.Instance
+	index []int
+}
+
+func fields(t reflect.Type) []field {
+	fs := []field{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name := strings.ToUpper(f.Name)
+		if tag, ok := f.Tag.Lookup("lisp"); ok {
+			if tag == "-" {
+				continue
+			}
+			name = strings.ToUpper(tag)
+		}
+		fs = append(fs, field{instance.NewSymbol(name), f.Index})
+	}
+	return fs
+}
+
+var classes sync.Map // map[reflect.Type]ilos.Class
+
+// Class returns the subclass of <standard-object> whose instances ToLisp makes
+// from structs of type t. The class is named after the struct type, like
+// <CONFIG> for a struct type named Config, and has one slot per field.
+func Class(t reflect.Type) ilos.Class {
+	if c, ok := classes.Load(t); ok {
+		return c.(ilos.Class)
+	}
+	name := "STRUCT"
+	if t.Name() != "" {
+		name = strings.ToUpper(t.Name())
+	}
+	slots := []ilos.Instance{}
+	initargs := map[ilos.Instance]ilos.Instance{}
+	for _, f := range fields(t) {
+		slots = append(slots, f.name)
+		initargs[f.name] = f.name
+	}
+	c := instance.NewStandardClass(
+		instance.NewSymbol("<"+name+">"),
+		[]ilos.Class{class.StandardObject},
+		slots,
+		map[ilos.Instance]ilos.Instance{},
+		initargs,
+		class.StandardClass,
+		instance.Nil,
+	)
+	c2, _ := classes.LoadOrStore(t, c)
+	return c2.(ilos.Class)
+}
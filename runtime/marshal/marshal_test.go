@@ -0,0 +1,100 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package marshal
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ta2gch/iris/runtime/env"
+	"github.com/ta2gch/iris/runtime/ilos"
+	"github.com/ta2gch/iris/runtime/ilos/class"
+	"github.com/ta2gch/iris/runtime/ilos/instance"
+)
+
+type rule struct {
+	Name     string
+	Priority int
+	Weights  []float64
+	Enabled  bool
+	Comment  string `lisp:"note"`
+	Secret   string `lisp:"-"`
+}
+
+var e = env.NewEnvironment(nil, nil, nil, nil)
+
+func TestToLisp(t *testing.T) {
+	tests := []struct {
+		name string
+		arg  interface{}
+		want string
+	}{
+		{"nil", nil, "NIL"},
+		{"bool", true, "T"},
+		{"integer", 42, "42"},
+		{"float", 1.5, "1.5"},
+		{"string", "abc", `"abc"`},
+		{"slice", []int{1, 2, 3}, "#(1 2 3)"},
+		{"map", map[string]interface{}{"b": 2, "a": "x"}, `(("a" . "x") ("b" . 2))`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ToLisp(e, tt.arg).String(); got != tt.want {
+				t.Errorf("ToLisp() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStruct(t *testing.T) {
+	r := rule{"discount", 3, []float64{0.5, 1}, true, "seasonal", "hidden"}
+	obj := ToLisp(e, &r)
+	if !ilos.InstanceOf(class.StandardObject, obj) || !reflect.DeepEqual(obj.Class(), Class(reflect.TypeOf(r))) {
+		t.Fatalf("ToLisp() = %v, want an instance of %v", obj, Class(reflect.TypeOf(r)))
+	}
+	if got := obj.Class().String(); got != "<RULE>" {
+		t.Errorf("Class() = %v, want <RULE>", got)
+	}
+	if v, ok := obj.(instance.Instance).GetSlotValue(instance.NewSymbol("NOTE"), obj.Class()); !ok || v.String() != `"seasonal"` {
+		t.Errorf("slot NOTE = %v, want \"seasonal\"", v)
+	}
+	var got rule
+	if err := FromLisp(obj, &got); err != nil {
+		t.Fatalf("FromLisp() err = %v", err)
+	}
+	r.Secret = ""
+	if !reflect.DeepEqual(got, r) {
+		t.Errorf("FromLisp() = %#v, want %#v", got, r)
+	}
+}
+
+func TestFromLisp(t *testing.T) {
+	alist := ToLisp(e, map[string]int{"Foo": 1, "y": 2})
+	var m map[string]int
+	if err := FromLisp(alist, &m); err != nil || !reflect.DeepEqual(m, map[string]int{"Foo": 1, "y": 2}) {
+		t.Errorf("FromLisp() = %v, err = %v", m, err)
+	}
+	alist = instance.NewCons(instance.NewCons(instance.NewSymbol("X"), instance.NewInteger(1)), instance.Nil)
+	if err := FromLisp(alist, &m); err != nil || !reflect.DeepEqual(m, map[string]int{"x": 1}) {
+		t.Errorf("FromLisp() = %v, err = %v", m, err)
+	}
+	list := instance.NewCons(instance.NewInteger(1), instance.NewCons(instance.NewFloat(2.5), instance.Nil))
+	var s []float64
+	if err := FromLisp(list, &s); err != nil || !reflect.DeepEqual(s, []float64{1, 2.5}) {
+		t.Errorf("FromLisp() = %v, err = %v", s, err)
+	}
+	var any interface{}
+	if err := FromLisp(list, &any); err != nil || !reflect.DeepEqual(any, []interface{}{1, 2.5}) {
+		t.Errorf("FromLisp() = %v, err = %v", any, err)
+	}
+	var i int
+	err := FromLisp(instance.NewString([]rune("1")), &i)
+	if err, ok := err.(*TypeError); !ok || !reflect.DeepEqual(err.Class, class.Integer) {
+		t.Errorf("FromLisp() err = %v, want *TypeError", err)
+	}
+	if err := FromLisp(instance.NewInteger(1), i); err == nil {
+		t.Errorf("FromLisp() err = nil, want non-pointer error")
+	}
+}
@@ -28,46 +28,70 @@ func checkLambdaList(e env.Environment, lambdaList ilos.Instance) ilos.Instance
 	return nil
 }
 
+// lambda is a function object defined in ISLisp, such as by lambda, defun or
+// labels.
+type lambda struct {
+	name       ilos.Instance
+	lexical    env.Environment
+	parameters []ilos.Instance
+	variadic   bool
+	forms      []ilos.Instance
+	body       code
+}
+
 func newNamedFunction(e env.Environment, functionName, lambdaList ilos.Instance, forms ...ilos.Instance) (ilos.Instance, ilos.Instance) {
-	lexical := e
 	if err := ensure(e, class.Symbol, functionName); err != nil {
 		return nil, err
 	}
 	if err := checkLambdaList(e, lambdaList); err != nil {
 		return nil, err
 	}
-	parameters := []ilos.Instance{}
-	variadic := false
-	for _, cadr := range lambdaList.(instance.List).Slice() {
-		if cadr == instance.NewSymbol(":REST") || cadr == instance.NewSymbol("&REST") {
-			variadic = true
-		}
-		parameters = append(parameters, cadr)
+	parameters, variadic, ok := instance.ParseLambdaList(lambdaList)
+	if !ok {
+		return SignalCondition(e, instance.NewDomainError(e, lambdaList, class.List), Nil)
 	}
-	return instance.NewFunction(functionName.(instance.Symbol), func(e env.Environment, arguments ...ilos.Instance) (ilos.Instance, ilos.Instance) {
-		e.MergeLexical(lexical)
-		if (variadic && len(parameters)-2 > len(arguments)) || (!variadic && len(parameters) != len(arguments)) {
+	body := compileBody(e, new(scope).bind(parameters...), forms, true)
+	return &lambda{functionName, e, parameters, variadic, forms, body}, nil
+}
+
+func (*lambda) Class() ilos.Class {
+	return class.Function
+}
+
+func (l *lambda) String() string {
+	return fmt.Sprintf("#%v", l.Class())
+}
+
+// Apply calls l with arguments and returns its value.
+func (l *lambda) Apply(e env.Environment, arguments ...ilos.Instance) (ilos.Instance, ilos.Instance) {
+	return force(l.call(e, arguments...))
+}
+
+// call binds the parameters of l to arguments and runs the compiled body of l.
+// The last form of the body is in tail position, so the value may be a
+// *tailCall.
+func (l *lambda) call(e env.Environment, arguments ...ilos.Instance) (ilos.Instance, ilos.Instance) {
+	e.MergeLexical(l.lexical)
+	if (l.variadic && len(l.parameters)-2 > len(arguments)) || (!l.variadic && len(l.parameters) != len(arguments)) {
 		return SignalCondition(e, instance.NewArityError(e), Nil)
-		}
-		for idx := range parameters {
-			key := parameters[idx]
-			if key == instance.NewSymbol(":REST") || key == instance.NewSymbol("&REST") {
-				key := parameters[idx+1]
-				value, err := List(e, arguments[idx:]...)
-				if err != nil {
-					return nil, err
-				}
-				if !e.Variable.Define(key, value) {
-					return SignalCondition(e, instance.NewImmutableBinding(e), Nil)
-				}
-				break
+	}
+	for idx := range l.parameters {
+		key := l.parameters[idx]
+		if key == instance.NewSymbol(":REST") || key == instance.NewSymbol("&REST") {
+			key := l.parameters[idx+1]
+			value, err := List(e, arguments[idx:]...)
+			if err != nil {
+				return nil, err
 			}
-			value := arguments[idx]
 			if !e.Variable.Define(key, value) {
-				fmt.Print(key, value)
 				return SignalCondition(e, instance.NewImmutableBinding(e), Nil)
 			}
+			break
 		}
-		return Progn(e, forms...)
-	}), nil
+		value := arguments[idx]
+		if !e.Variable.Define(key, value) {
+			return SignalCondition(e, instance.NewImmutableBinding(e), Nil)
+		}
+	}
+	return l.body(e)
 }
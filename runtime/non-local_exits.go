@@ -53,8 +53,12 @@ func Block(e env.Environment, tag ilos.Instance, body ...ilos.Instance) (ilos.In
 	}
 	var fail ilos.Instance
 	sucess := Nil
-	for _, cadr := range body {
-		sucess, fail = Eval(e, cadr)
+	for i, cadr := range body {
+		if i == len(body)-1 && tailSafe(e, body...) {
+			sucess, fail = evalTail(e, cadr)
+		} else {
+			sucess, fail = Eval(e, cadr)
+		}
 		if fail != nil {
 			if ilos.InstanceOf(class.BlockTag, fail) {
 				tag1, _ := fail.(instance.Instance).GetSlotValue(instance.NewSymbol("IRIS.TAG"), class.Escape) // Checked at the head of// This condition
@@ -152,37 +156,32 @@ func Tagbody(e env.Environment, body ...ilos.Instance) (ilos.Instance, ilos.Inst
 			}
 		}
 	}
-	for idx, cadr := range body {
-		if ilos.InstanceOf(class.Cons, cadr) {
-			_, fail := Eval(e, cadr)
-			if fail != nil {
-			TAG:
-				if ilos.InstanceOf(class.TagbodyTag, fail) {
-					tag1, _ := fail.(instance.Instance).GetSlotValue(instance.NewSymbol("IRIS.TAG"), class.Escape) // Checked at the top of// This loop
-					uid1, _ := fail.(instance.Instance).GetSlotValue(instance.NewSymbol("IRIS.UID"), class.Escape) // Checked at the top of// This loop
-					found := false
-					for _, tag := range body {
-						if tag == tag1 && uid == uid1 {
-							found = true
-							break
-						}
-					}
-					if found {
-						for _, form := range body[idx+1:] {
-							if ilos.InstanceOf(class.Cons, form) {
-								_, fail = Eval(e, form)
-								if fail != nil {
-									goto TAG
-								}
-							}
-						}
-						break
-					}
-
-				}
-				return nil, fail
+	for idx := 0; idx < len(body); idx++ {
+		if !ilos.InstanceOf(class.Cons, body[idx]) {
+			continue
+		}
+		_, fail := Eval(e, body[idx])
+		if fail == nil {
+			continue
+		}
+		if !ilos.InstanceOf(class.TagbodyTag, fail) {
+			return nil, fail
+		}
+		if err := checkBudget(e); err != nil {
+			return nil, err
+		}
+		tag1, _ := fail.(instance.Instance).GetSlotValue(instance.NewSymbol("IRIS.TAG"), class.Escape) // Checked at the top of// This loop
+		uid1, _ := fail.(instance.Instance).GetSlotValue(instance.NewSymbol("IRIS.UID"), class.Escape) // Checked at the top of// This loop
+		found := false
+		for i, tag := range body {
+			if !ilos.InstanceOf(class.Cons, tag) && tag == tag1 && uid == uid1 {
+				idx, found = i, true
+				break
 			}
 		}
+		if !found {
+			return nil, fail
+		}
 	}
 	return Nil, nil
 }
@@ -217,7 +216,7 @@ func Go(e env.Environment, tag ilos.Instance) (ilos.Instance, ilos.Instance) {
 func UnwindProtect(e env.Environment, form ilos.Instance, cleanupForms ...ilos.Instance) (ilos.Instance, ilos.Instance) {
 	ret1, err1 := Eval(e, form)
 	ret2, err2 := Progn(e, cleanupForms...)
-	if ilos.InstanceOf(class.Escape, err2) {
+	if err2 != nil && ilos.InstanceOf(class.Escape, err2) {
 		return SignalCondition(e, instance.NewControlError(e), Nil)
 	}
 	if err2 != nil {
@@ -0,0 +1,154 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package runtime
+
+import "testing"
+
+func TestBlock(t *testing.T) {
+	tests := []test{
+		{
+			exp:     `(block 'b 1 2)`,
+			want:    `2`,
+			wantErr: false,
+		},
+		{
+			exp:     `(block 'b (+ 1 (return-from 'b 10)) 2)`,
+			want:    `10`,
+			wantErr: false,
+		},
+		{
+			exp:     `(block 'a (block 'b (return-from 'a 1)) 2)`,
+			want:    `1`,
+			wantErr: false,
+		},
+		{
+			exp:     `(return-from 'b 1)`,
+			want:    `nil`,
+			wantErr: true,
+		},
+		{
+			exp:     `(block 1 2)`,
+			want:    `nil`,
+			wantErr: true,
+		},
+	}
+	execTests(t, Block, tests)
+}
+
+func TestCatch(t *testing.T) {
+	tests := []test{
+		{
+			exp:     `(defun throw-to (tag) (throw tag 'thrown))`,
+			want:    `'throw-to`,
+			wantErr: false,
+		},
+		{
+			exp:     `(catch 'c (throw-to 'c) 'not-thrown)`,
+			want:    `'thrown`,
+			wantErr: false,
+		},
+		{
+			exp:     `(catch 'c (catch 'd (throw-to 'c)) 'not-thrown)`,
+			want:    `'thrown`,
+			wantErr: false,
+		},
+		{
+			exp:     `(throw-to 'c)`,
+			want:    `nil`,
+			wantErr: true,
+		},
+	}
+	execTests(t, Catch, tests)
+}
+
+func TestTagbody(t *testing.T) {
+	tests := []test{
+		{
+			exp:     `(defglobal x nil)`,
+			want:    `'x`,
+			wantErr: false,
+		},
+		{
+			exp:     `(tagbody (setq x 1) (go end) (setq x 2) end)`,
+			want:    `nil`,
+			wantErr: false,
+		},
+		{
+			exp:     `x`,
+			want:    `1`,
+			wantErr: false,
+		},
+		{
+			exp: `
+			(let ((i 0) (acc nil))
+			  (tagbody
+			   top
+			    (setq acc (cons i acc))
+			    (setq i (+ i 1))
+			    (if (< i 3) (go top))
+			    (setq acc (cons 'end acc)))
+			  acc)`,
+			want:    `'(end 2 1 0)`,
+			wantErr: false,
+		},
+		{
+			exp:     `(tagbody (funcall (lambda () (go b))) (setq x 3) b (setq x 4))`,
+			want:    `nil`,
+			wantErr: false,
+		},
+		{
+			exp:     `x`,
+			want:    `4`,
+			wantErr: false,
+		},
+		{
+			exp:     `(go b)`,
+			want:    `nil`,
+			wantErr: true,
+		},
+	}
+	execTests(t, Tagbody, tests)
+}
+
+func TestUnwindProtect(t *testing.T) {
+	tests := []test{
+		{
+			exp:     `(defglobal y nil)`,
+			want:    `'y`,
+			wantErr: false,
+		},
+		{
+			exp:     `(unwind-protect 1 (setq y 'cleaned))`,
+			want:    `1`,
+			wantErr: false,
+		},
+		{
+			exp:     `(progn (setq y nil) (catch 'c (unwind-protect (throw 'c 2) (setq y 'cleaned))))`,
+			want:    `2`,
+			wantErr: false,
+		},
+		{
+			exp:     `y`,
+			want:    `'cleaned`,
+			wantErr: false,
+		},
+		{
+			exp:     `(block 'b (unwind-protect (unwind-protect (return-from 'b 3) (setq y 1)) (setq y (+ y 1))))`,
+			want:    `3`,
+			wantErr: false,
+		},
+		{
+			exp:     `y`,
+			want:    `2`,
+			wantErr: false,
+		},
+		{
+			exp:     `(catch 'c (unwind-protect (throw 'c 1) (throw 'c 2)))`,
+			want:    `nil`,
+			wantErr: true,
+		},
+	}
+	execTests(t, UnwindProtect, tests)
+}
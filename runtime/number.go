@@ -6,6 +6,7 @@ package runtime
 
 import (
 	"math"
+	"math/big"
 
 	"github.com/ta2gch/iris/reader/parser"
 	"github.com/ta2gch/iris/runtime/env"
@@ -28,6 +29,8 @@ func Numberp(e env.Environment, obj ilos.Instance) (ilos.Instance, ilos.Instance
 // shall be signaled if string is not a string (error-id. domain-error). An
 // error shall be signaled if string is not the textual representation of a
 // number (error-id. cannot-parse-number).
+// A float out of the range of floats signals <floating-point-overflow> or
+// <floating-point-underflow>, as float operations do.
 func ParseNumber(e env.Environment, str ilos.Instance) (ilos.Instance, ilos.Instance) {
 	if err := ensure(e, class.String, str); err != nil {
 		return nil, err
@@ -36,6 +39,9 @@ func ParseNumber(e env.Environment, str ilos.Instance) (ilos.Instance, ilos.Inst
 	if err != nil || !ilos.InstanceOf(class.Number, ret) {
 		return SignalCondition(e, instance.NewParseError(e, str, class.Number), Nil)
 	}
+	if f, ok := ret.(instance.Float); ok {
+		return newFloat(e, float64(f), false, "PARSE-NUMBER", str)
+	}
 	return ret, err
 }
 
@@ -48,23 +54,41 @@ func NumberEqual(e env.Environment, x1, x2 ilos.Instance) (ilos.Instance, ilos.I
 	if err := ensure(e, class.Number, x1, x2); err != nil {
 		return nil, err
 	}
-	ret := false
-	switch {
-	case ilos.InstanceOf(class.Integer, x1) && ilos.InstanceOf(class.Integer, x2):
-		ret = x1 == x2
-	case ilos.InstanceOf(class.Integer, x1) && ilos.InstanceOf(class.Float, x2):
-		ret = float64(x1.(instance.Integer)) == float64(x2.(instance.Float))
-	case ilos.InstanceOf(class.Float, x1) && ilos.InstanceOf(class.Integer, x2):
-		ret = float64(x1.(instance.Float)) == float64(x2.(instance.Integer))
-	case ilos.InstanceOf(class.Float, x1) && ilos.InstanceOf(class.Float, x2):
-		ret = x1 == x2
-	}
-	if ret {
+	if c, ok := compare(x1, x2); ok && c == 0 {
 		return T, nil
 	}
 	return Nil, nil
 }
 
+// compare returns -1, 0 or +1 as the number x1 is less than, equal to or
+// greater than the number x2. It returns false if either is NaN.
+func compare(x1, x2 ilos.Instance) (int, bool) {
+	if isRational(x1) && isRational(x2) {
+		a, ok1 := x1.(instance.Integer)
+		b, ok2 := x2.(instance.Integer)
+		if !ok1 || !ok2 {
+			return bigRat(x1).Cmp(bigRat(x2)), true
+		}
+		switch {
+		case a < b:
+			return -1, true
+		case a > b:
+			return 1, true
+		}
+		return 0, true
+	}
+	a, b := toFloat64(x1), toFloat64(x2)
+	switch {
+	case a < b:
+		return -1, true
+	case a > b:
+		return 1, true
+	case a == b:
+		return 0, true
+	}
+	return 0, false
+}
+
 // NumberNotEqual returns t if x1 and x2 have mathematically distinct values;
 // otherwise, returns nil. An error shall be signaled if either x1 or x2 is not
 // a number (error-id. domain-error).
@@ -81,18 +105,7 @@ func NumberGreaterThan(e env.Environment, x1, x2 ilos.Instance) (ilos.Instance,
 	if err := ensure(e, class.Number, x1, x2); err != nil {
 		return nil, err
 	}
-	ret := false
-	switch {
-	case ilos.InstanceOf(class.Integer, x1) && ilos.InstanceOf(class.Integer, x2):
-		ret = float64(x1.(instance.Integer)) > float64(x2.(instance.Integer))
-	case ilos.InstanceOf(class.Integer, x1) && ilos.InstanceOf(class.Float, x2):
-		ret = float64(x1.(instance.Integer)) > float64(x2.(instance.Float))
-	case ilos.InstanceOf(class.Float, x1) && ilos.InstanceOf(class.Integer, x2):
-		ret = float64(x1.(instance.Float)) > float64(x2.(instance.Integer))
-	case ilos.InstanceOf(class.Float, x1) && ilos.InstanceOf(class.Float, x2):
-		ret = float64(x1.(instance.Float)) > float64(x2.(instance.Float))
-	}
-	if ret {
+	if c, ok := compare(x1, x2); ok && c > 0 {
 		return T, nil
 	}
 	return Nil, nil
@@ -134,8 +147,9 @@ func NumberLessThanOrEqual(e env.Environment, x1, x2 ilos.Instance) (ilos.Instan
 
 // Add returns the sum, respectively, of their arguments. If all arguments are
 // integers, the result is an integer. If any argument is a ﬂoat, the result is
-// a ﬂoat. When given no arguments, + returns 0. An error shall be signaled if
-// any x is not a number (error-id. domain-error).
+// a ﬂoat. Otherwise, if any argument is a ratio, the result is exact. When
+// given no arguments, + returns 0. An error shall be signaled if any x is not
+// a number (error-id. domain-error).
 func Add(e env.Environment, x ...ilos.Instance) (ilos.Instance, ilos.Instance) {
 	flt := false
 	sum := 0.0
@@ -148,9 +162,13 @@ func Add(e env.Environment, x ...ilos.Instance) (ilos.Instance, ilos.Instance) {
 		sum += f
 	}
 	if flt {
-		return instance.NewFloat(sum), nil
+		return newFloat(e, sum, false, "+", x...)
+	}
+	ret := instance.NewInteger(0)
+	for _, a := range x {
+		ret = addRational(ret, a)
 	}
-	return instance.NewInteger(int(sum)), nil
+	return ret, nil
 }
 
 // Multiply returns the product, respectively, of their arguments. If all
@@ -158,7 +176,7 @@ func Add(e env.Environment, x ...ilos.Instance) (ilos.Instance, ilos.Instance) {
 // the result is a ﬂoat. When given no arguments, Multiply returns 1. An error
 // shall be signaled if any x is not a number (error-id. domain-error).
 func Multiply(e env.Environment, x ...ilos.Instance) (ilos.Instance, ilos.Instance) {
-	flt := false
+	flt, zero := false, false
 	pdt := 1.0
 	for _, a := range x {
 		f, b, err := convFloat64(e, a)
@@ -167,11 +185,16 @@ func Multiply(e env.Environment, x ...ilos.Instance) (ilos.Instance, ilos.Instan
 		}
 		pdt *= f
 		flt = flt || b
+		zero = zero || f == 0
 	}
 	if flt {
-		return instance.NewFloat(pdt), nil
+		return newFloat(e, pdt, !zero, "*", x...)
+	}
+	ret := instance.NewInteger(1)
+	for _, a := range x {
+		ret = mulRational(ret, a)
 	}
-	return instance.NewInteger(int(pdt)), nil
+	return ret, nil
 }
 
 // Substruct returns its additive inverse. An error shall be signaled if x is
@@ -198,26 +221,32 @@ func Substruct(e env.Environment, x ilos.Instance, xs ...ilos.Instance) (ilos.In
 		flt = flt || b
 	}
 	if flt {
-		return instance.NewFloat(sub), nil
+		return newFloat(e, sub, false, "-", append([]ilos.Instance{x}, xs...)...)
 	}
-	return instance.NewInteger(int(sub)), nil
+	ret := x
+	for _, a := range xs {
+		ret = subRational(ret, a)
+	}
+	return ret, nil
 }
 
 // Quotient returns the quotient of those numbers. The result is an integer if
-// dividend and divisor are integers and divisor evenly divides dividend ,
-// otherwise it will be a ﬂoat. Given more than two arguments, quotient operates
-// iteratively on each of the divisor1 … divisorn as in dividend /divisor1 / …
-// /divisorn. The type of the result follows from the two-argument case because
-// the three-or-more-argument quotient can be defined as follows: An error shall
-// be signaled if dividend is not a number (error-id. domain-error). An error
-// shall be signaled if any divisor is not a number (error-id. domain-error). An
-// error shall be signaled if any divisor is zero (error-id. division-by-zero).
+// dividend and divisor are integers and divisor evenly divides dividend , a
+// ratio if they are integers or ratios otherwise, or else a ﬂoat. Given more
+// than two arguments, quotient operates iteratively on each of the divisor1 …
+// divisorn as in dividend /divisor1 / … /divisorn. The type of the result
+// follows from the two-argument case because the three-or-more-argument
+// quotient can be defined as follows: An error shall be signaled if dividend
+// is not a number (error-id. domain-error). An error shall be signaled if any
+// divisor is not a number (error-id. domain-error). An error shall be signaled
+// if any divisor is zero (error-id. division-by-zero).
 func Quotient(e env.Environment, dividend, divisor1 ilos.Instance, divisor ...ilos.Instance) (ilos.Instance, ilos.Instance) {
 	divisor = append([]ilos.Instance{divisor1}, divisor...)
 	quotient, flt, err := convFloat64(e, dividend)
 	if err != nil {
 		return nil, err
 	}
+	exact := dividend
 	for _, a := range divisor {
 		f, b, err := convFloat64(e, a)
 		if err != nil {
@@ -228,17 +257,23 @@ func Quotient(e env.Environment, dividend, divisor1 ilos.Instance, divisor ...il
 			for i := len(divisor) - 1; i >= 0; i-- {
 				arguments = instance.NewCons(divisor[i], arguments)
 			}
-			return SignalCondition(e, instance.NewArithmeticError(e, instance.NewSymbol("QUOTIENT"), arguments), Nil)
+			arguments = instance.NewCons(dividend, arguments)
+			return SignalCondition(e, instance.NewDivisionByZero(e, instance.NewSymbol("QUOTIENT"), arguments), Nil)
 		}
-		if !flt && !b && int(quotient)%int(f) != 0 {
+		if !flt && !b {
+			exact = quoRational(exact, a)
+			continue
+		}
+		if !flt {
+			quotient = toFloat64(exact)
 			flt = true
 		}
 		quotient /= f
 	}
 	if flt {
-		return instance.NewFloat(quotient), nil
+		return newFloat(e, quotient, toFloat64(dividend) != 0, "QUOTIENT", append([]ilos.Instance{dividend}, divisor...)...)
 	}
-	return instance.NewInteger(int(quotient)), nil
+	return exact, nil
 }
 
 // Reciprocal returns the reciprocal of its argument x ; that is, 1/x . An error
@@ -302,28 +337,34 @@ func Exp(e env.Environment, x ilos.Instance) (ilos.Instance, ilos.Instance) {
 	if err != nil {
 		return nil, err
 	}
-	return instance.NewFloat(math.Exp(f)), nil
+	return newFloat(e, math.Exp(f), true, "EXP", x)
 }
 
 // Log returns the natural logarithm of x. An error shall be signaled if x is
 // not a positive number (error-id. domain-error).
 func Log(e env.Environment, x ilos.Instance) (ilos.Instance, ilos.Instance) {
-	f, _, err := convFloat64(e, x)
+	f, flt, err := convFloat64(e, x)
 	if err != nil {
 		return nil, err
 	}
+	if !flt && bigRat(x).Sign() > 0 && outOfRange(f) {
+		// log(m * 2^k) = log(m) + k log(2)
+		m, k := scaled(bigRat(x))
+		return newFloat(e, math.Log(m)+float64(k)*math.Ln2, false, "LOG", x)
+	}
 	if f <= 0.0 {
 		return SignalCondition(e, instance.NewDomainError(e, x, class.Number), Nil)
 	}
-	return instance.NewFloat(math.Log(f)), nil
+	return newFloat(e, math.Log(f), false, "LOG", x)
 }
 
 // Expt returns x1 raised to the power x2. The result will be an integer if x1
-// is an integer and x2 is a non-negative integer. An error shall be signaled if
+// is an integer and x2 is a non-negative integer, and a ratio if x1 is a ratio
+// or x2 is a negative integer and x1 is not a ﬂoat. An error shall be signaled if
 // x1 is zero and x2 is negative, or if x1 is zero and x2 is a zero ﬂoat, or if
 // x1 is negative and x2 is not an integer.
 func Expt(e env.Environment, x1, x2 ilos.Instance) (ilos.Instance, ilos.Instance) {
-	a, af, err := convFloat64(e, x1)
+	a, _, err := convFloat64(e, x1)
 	if err != nil {
 		return nil, err
 	}
@@ -331,10 +372,24 @@ func Expt(e env.Environment, x1, x2 ilos.Instance) (ilos.Instance, ilos.Instance
 	if err != nil {
 		return nil, err
 	}
-	if !af && !bf && b >= 0 {
-		return instance.NewInteger(int(math.Pow(a, b))), nil
+	if isRational(x1) && ilos.InstanceOf(class.Integer, x2) && (a != 0 || b >= 0) {
+		if _, ok := x2.(instance.Bignum); ok && math.Abs(a) != 1 && a != 0 {
+			operation := instance.NewSymbol("EXPT")
+			operands, err := List(e, x1, x2)
+			if err != nil {
+				return nil, err
+			}
+			return SignalCondition(e, instance.NewArithmeticError(e, operation, operands), Nil)
+		}
+		r := bigRat(x1)
+		n := new(big.Int).Abs(bigInt(x2))
+		r.SetFrac(new(big.Int).Exp(r.Num(), n, nil), new(big.Int).Exp(r.Denom(), n, nil))
+		if b < 0 {
+			r.Inv(r)
+		}
+		return instance.NewRatio(r), nil
 	}
-	if (a == 0 && b < 0) || (a == 0 && bf && b == 0) || (a < 0 && bf) {
+	if (a == 0 && b < 0) || (a == 0 && bf && b == 0) || (a < 0 && !ilos.InstanceOf(class.Integer, x2)) {
 		operation := instance.NewSymbol("EXPT")
 		operands, err := List(e, x1, x2)
 		if err != nil {
@@ -342,23 +397,39 @@ func Expt(e env.Environment, x1, x2 ilos.Instance) (ilos.Instance, ilos.Instance
 		}
 		return SignalCondition(e, instance.NewArithmeticError(e, operation, operands), Nil)
 	}
-	return instance.NewFloat(math.Pow(a, b)), nil
+	return newFloat(e, math.Pow(a, b), a != 0, "EXPT", x1, x2)
 }
 
 // Sqrt returns the non-negative square root of x. An error shall be signaled if
 // x is not a non-negative number (error-id. domain-error).
 func Sqrt(e env.Environment, x ilos.Instance) (ilos.Instance, ilos.Instance) {
-	a, _, err := convFloat64(e, x)
+	a, flt, err := convFloat64(e, x)
 	if err != nil {
 		return nil, err
 	}
-	if a < 0.0 {
+	if a < 0.0 || !flt && bigRat(x).Sign() < 0 {
 		return SignalCondition(e, instance.NewDomainError(e, x, class.Number), Nil)
 	}
-	if math.Ceil(math.Sqrt(a)) == math.Sqrt(a) {
-		return instance.NewInteger(int(math.Sqrt(a))), nil
+	if !flt {
+		q := bigRat(x)
+		n, d := new(big.Int).Sqrt(q.Num()), new(big.Int).Sqrt(q.Denom())
+		if new(big.Rat).SetFrac(new(big.Int).Mul(n, n), new(big.Int).Mul(d, d)).Cmp(q) == 0 {
+			return instance.NewRatio(new(big.Rat).SetFrac(n, d)), nil
+		}
+		if outOfRange(a) {
+			// sqrt(m * 2^k) = sqrt(m) * 2^(k/2) for an even k
+			m, k := scaled(q)
+			if k%2 != 0 {
+				m, k = m*2, k-1
+			}
+			return newFloat(e, math.Ldexp(math.Sqrt(m), k/2), false, "SQRT", x)
+		}
+	}
+	// An integral root is an integer only if it fits in one exactly
+	if r := math.Sqrt(a); math.Ceil(r) == r && r < math.MaxInt {
+		return instance.NewInteger(int(r)), nil
 	}
-	return instance.NewFloat(math.Sqrt(a)), nil
+	return newFloat(e, math.Sqrt(a), false, "SQRT", x)
 }
 
 // Pi is an approximation of π.
@@ -371,7 +442,7 @@ func Sin(e env.Environment, x ilos.Instance) (ilos.Instance, ilos.Instance) {
 	if err != nil {
 		return nil, err
 	}
-	return instance.NewFloat(math.Sin(a)), nil
+	return newFloat(e, math.Sin(a), false, "SIN", x)
 }
 
 // Cos returns the cosine of x . x must be given in radians. An error shall be
@@ -381,7 +452,7 @@ func Cos(e env.Environment, x ilos.Instance) (ilos.Instance, ilos.Instance) {
 	if err != nil {
 		return nil, err
 	}
-	return instance.NewFloat(math.Cos(a)), nil
+	return newFloat(e, math.Cos(a), false, "COS", x)
 }
 
 // Tan returns the tangent of x . x must be given in radians. An error shall be
@@ -391,7 +462,7 @@ func Tan(e env.Environment, x ilos.Instance) (ilos.Instance, ilos.Instance) {
 	if err != nil {
 		return nil, err
 	}
-	return instance.NewFloat(math.Tan(a)), nil
+	return newFloat(e, math.Tan(a), false, "TAN", x)
 }
 
 // Atan returns the arc tangent of x. The result is a (real) number that lies
@@ -402,7 +473,7 @@ func Atan(e env.Environment, x ilos.Instance) (ilos.Instance, ilos.Instance) {
 	if err != nil {
 		return nil, err
 	}
-	return instance.NewFloat(math.Atan(a)), nil
+	return newFloat(e, math.Atan(a), false, "ATAN", x)
 }
 
 // Atan2 returns the phase of its representation in polar coordinates. If x1 is
@@ -429,7 +500,7 @@ func Atan2(e env.Environment, x1, x2 ilos.Instance) (ilos.Instance, ilos.Instanc
 		}
 		return SignalCondition(e, instance.NewArithmeticError(e, operation, operands), Nil)
 	}
-	return instance.NewFloat(math.Atan2(a, b)), nil
+	return newFloat(e, math.Atan2(a, b), false, "ATAN2", x1, x2)
 }
 
 // Sinh returns the hyperbolic sine of x . x must be given in radians. An error
@@ -439,7 +510,7 @@ func Sinh(e env.Environment, x ilos.Instance) (ilos.Instance, ilos.Instance) {
 	if err != nil {
 		return nil, err
 	}
-	return instance.NewFloat(math.Sinh(a)), nil
+	return newFloat(e, math.Sinh(a), false, "SINH", x)
 }
 
 // Cosh returns the hyperbolic cosine of x . x must be given in radians. An
@@ -449,7 +520,7 @@ func Cosh(e env.Environment, x ilos.Instance) (ilos.Instance, ilos.Instance) {
 	if err != nil {
 		return nil, err
 	}
-	return instance.NewFloat(math.Cosh(a)), nil
+	return newFloat(e, math.Cosh(a), false, "COSH", x)
 }
 
 // Tanh returns the hyperbolic tangent of x . x must be given in radians. An
@@ -459,7 +530,7 @@ func Tanh(e env.Environment, x ilos.Instance) (ilos.Instance, ilos.Instance) {
 	if err != nil {
 		return nil, err
 	}
-	return instance.NewFloat(math.Tanh(a)), nil
+	return newFloat(e, math.Tanh(a), false, "TANH", x)
 }
 
 // Atanh returns the hyperbolic arc tangent of x. An error shall be signaled if
@@ -472,5 +543,5 @@ func Atanh(e env.Environment, x ilos.Instance) (ilos.Instance, ilos.Instance) {
 	if math.Abs(a) >= 1 {
 		return SignalCondition(e, instance.NewDomainError(e, x, class.Number), Nil)
 	}
-	return instance.NewFloat(math.Atanh(a)), nil
+	return newFloat(e, math.Atanh(a), false, "ATANH", x)
 }
@@ -0,0 +1,70 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package runtime
+
+import (
+	"fmt"
+
+	"github.com/ta2gch/iris/runtime/env"
+	"github.com/ta2gch/iris/runtime/ilos"
+	"github.com/ta2gch/iris/runtime/ilos/class"
+	"github.com/ta2gch/iris/runtime/ilos/instance"
+	"github.com/ta2gch/iris/runtime/pretty"
+)
+
+// rightMargin returns the value of *print-right-margin*, or the default
+// margin if it is not a positive integer.
+func rightMargin(e env.Environment) int {
+	if v, ok := e.DynamicVariable.Get(instance.NewSymbol("*PRINT-RIGHT-MARGIN*")); ok {
+		if n, ok := v.(instance.Integer); ok && n > 0 {
+			return int(n)
+		}
+	}
+	return pretty.DefaultMargin
+}
+
+// printObject writes object to stream, laid out by the pretty printer if
+// *print-pretty* is not nil. Unless escape is true, the objects in it are
+// written without escape characters, as pretty.Text writes them.
+func printObject(e env.Environment, stream, object ilos.Instance, escape bool) ilos.Instance {
+	if v, ok := e.DynamicVariable.Get(instance.NewSymbol("*PRINT-PRETTY*")); ok && v != Nil {
+		fprint := pretty.FprintText
+		if escape {
+			fprint = pretty.Fprint
+		}
+		if err := fprint(stream.(instance.Stream), object, rightMargin(e)); err != nil {
+			_, err := SignalCondition(e, instance.NewStreamError(e, stream), Nil)
+			return err
+		}
+		return nil
+	}
+	if escape {
+		fmt.Fprint(stream.(instance.Stream), object)
+	} else {
+		fmt.Fprint(stream.(instance.Stream), pretty.Text(object))
+	}
+	return nil
+}
+
+// Pprint outputs obj to stream, or the standard output, over lines no wider
+// than *print-right-margin* and ends the line. Lists are broken after their
+// elements and forms such as defun, let and cond are indented as code.
+func Pprint(e env.Environment, obj ilos.Instance, stream ...ilos.Instance) (ilos.Instance, ilos.Instance) {
+	if len(stream) > 1 {
+		return SignalCondition(e, instance.NewArityError(e), Nil)
+	}
+	s := e.StandardOutput
+	if len(stream) == 1 {
+		s = stream[0]
+	}
+	if ok, _ := OutputStreamP(e, s); ok == Nil {
+		return SignalCondition(e, instance.NewDomainError(e, s, class.Stream), Nil)
+	}
+	if err := pretty.Fprint(s.(instance.Stream), obj, rightMargin(e)); err != nil {
+		return SignalCondition(e, instance.NewStreamError(e, s), Nil)
+	}
+	fmt.Fprintln(s.(instance.Stream))
+	return Nil, nil
+}
@@ -0,0 +1,271 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+// Package pretty prints ISLisp objects over lines no wider than a right
+// margin.
+//
+// A list or vector which does not fit in the rest of the line is broken after
+// its elements, which line up under the first one. Atoms which fit stay on the
+// line of the atom before them. Forms which define or bind, such as defun and
+// let, keep their first arguments on the line of the operator and indent their
+// body by two columns.
+package pretty
+
+import (
+	"io"
+	"strings"
+
+	"github.com/ta2gch/iris/runtime/ilos"
+	"github.com/ta2gch/iris/runtime/ilos/instance"
+)
+
+// DefaultMargin is the right margin used when none is given.
+const DefaultMargin = 80
+
+// forms maps the operators indented as bodies to the number of their
+// arguments which stay on the line of the operator.
+var forms = map[instance.Symbol]int{
+	"BLOCK":                 1,
+	"CASE":                  1,
+	"CASE-USING":            2,
+	"CATCH":                 1,
+	"DEFCLASS":              2,
+	"DEFGENERIC":            2,
+	"DEFMACRO":              2,
+	"DEFMETHOD":             2,
+	"DEFUN":                 2,
+	"DYNAMIC-LET":           1,
+	"FLET":                  1,
+	"FOR":                   2,
+	"LABELS":                1,
+	"LAMBDA":                1,
+	"LET":                   1,
+	"LET*":                  1,
+	"PROGN":                 0,
+	"TAGBODY":               0,
+	"UNWIND-PROTECT":        1,
+	"WHILE":                 1,
+	"WITH-ERROR-OUTPUT":     1,
+	"WITH-HANDLER":          1,
+	"WITH-OPEN-INPUT-FILE":  1,
+	"WITH-OPEN-IO-FILE":     1,
+	"WITH-OPEN-OUTPUT-FILE": 1,
+	"WITH-STANDARD-INPUT":   1,
+	"WITH-STANDARD-OUTPUT":  1,
+}
+
+// Fprint writes obj to w within margin columns. If w is a stream, the output
+// starts at its current column.
+func Fprint(w io.Writer, obj ilos.Instance, margin int) error {
+	return fprint(w, obj, margin, ilos.Instance.String)
+}
+
+// FprintText is Fprint without escape characters, as Text prints.
+func FprintText(w io.Writer, obj ilos.Instance, margin int) error {
+	return fprint(w, obj, margin, Text)
+}
+
+func fprint(w io.Writer, obj ilos.Instance, margin int, text func(ilos.Instance) string) error {
+	column := 0
+	if s, ok := w.(instance.Stream); ok {
+		column = *s.Column
+	}
+	_, err := io.WriteString(w, print(obj, column, margin, text))
+	return err
+}
+
+// Sprint returns obj printed within margin columns from column 0.
+func Sprint(obj ilos.Instance, margin int) string {
+	return print(obj, 0, margin, ilos.Instance.String)
+}
+
+// Text returns obj printed on one line without escape characters: strings,
+// symbols and characters are written as their text, also inside lists and
+// vectors.
+func Text(obj ilos.Instance) string {
+	var elements []string
+	switch obj := obj.(type) {
+	case instance.String:
+		return string(obj)
+	case instance.Symbol:
+		return string(obj)
+	case instance.Character:
+		return string(obj)
+	case *instance.Cons:
+		for _, elt := range obj.Slice() {
+			elements = append(elements, Text(elt))
+		}
+		if t := tail(obj); t != instance.Nil {
+			elements = append(elements, ".", Text(t))
+		}
+		return "(" + strings.Join(elements, " ") + ")"
+	case instance.GeneralVector:
+		for _, elt := range obj {
+			elements = append(elements, Text(elt))
+		}
+		return "#(" + strings.Join(elements, " ") + ")"
+	}
+	return obj.String()
+}
+
+func print(obj ilos.Instance, column, margin int, text func(ilos.Instance) string) string {
+	if margin <= 0 {
+		margin = DefaultMargin
+	}
+	p := &printer{column: column, margin: margin, text: text}
+	p.print(obj)
+	return p.b.String()
+}
+
+type printer struct {
+	b      strings.Builder
+	column int
+	margin int
+	text   func(ilos.Instance) string // of an object on one line
+}
+
+func (p *printer) write(s string) {
+	p.b.WriteString(s)
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		p.column = len([]rune(s[i+1:]))
+	} else {
+		p.column += len([]rune(s))
+	}
+}
+
+func (p *printer) newline(indent int) {
+	p.write("\n" + strings.Repeat(" ", indent))
+}
+
+// fits returns true if obj fits on the rest of the line.
+func (p *printer) fits(obj ilos.Instance) bool {
+	return p.width(obj, p.margin-p.column) <= p.margin-p.column
+}
+
+func (p *printer) print(obj ilos.Instance) {
+	if p.fits(obj) {
+		p.write(p.text(obj))
+		return
+	}
+	switch obj := obj.(type) {
+	case *instance.Cons:
+		p.write("(")
+		p.list(obj.Slice(), tail(obj))
+		p.write(")")
+	case instance.GeneralVector:
+		p.write("#(")
+		p.list(obj, instance.Nil)
+		p.write(")")
+	default:
+		p.write(p.text(obj))
+	}
+}
+
+// list prints the elements of a list or vector after its opening parenthesis,
+// and the tail of a dotted list.
+func (p *printer) list(elements []ilos.Instance, tail ilos.Instance) {
+	if len(elements) == 0 {
+		return
+	}
+	indent := p.column
+	p.print(elements[0])
+	prev, rest := elements[0], elements[1:]
+	if op, ok := elements[0].(instance.Symbol); ok && len(rest) > 0 {
+		if n, ok := forms[op]; ok {
+			// (op args...
+			//   body...)
+			n = min(n, len(rest))
+			for _, elt := range rest[:n] {
+				p.write(" ")
+				p.print(elt)
+			}
+			for _, elt := range rest[n:] {
+				p.newline(indent + 1)
+				p.print(elt)
+			}
+			p.tail(tail, indent+1)
+			return
+		}
+		if p.column-indent < p.margin/4 {
+			// (op arg
+			//     args...)
+			p.write(" ")
+			indent = p.column
+			p.print(rest[0])
+			prev, rest = rest[0], rest[1:]
+		}
+	}
+	for _, elt := range rest {
+		if isAtom(prev) && isAtom(elt) && p.width(elt, p.margin)+1 <= p.margin-p.column {
+			p.write(" ")
+		} else {
+			p.newline(indent)
+		}
+		p.print(elt)
+		prev = elt
+	}
+	p.tail(tail, indent)
+}
+
+func (p *printer) tail(tail ilos.Instance, indent int) {
+	if tail == instance.Nil {
+		return
+	}
+	if p.width(tail, p.margin)+3 <= p.margin-p.column {
+		p.write(" . ")
+	} else {
+		p.newline(indent)
+		p.write(". ")
+	}
+	p.print(tail)
+}
+
+// tail returns the object ending the list c, which is nil if c is proper.
+func tail(c *instance.Cons) ilos.Instance {
+	var obj ilos.Instance = c
+	for {
+		c, ok := obj.(*instance.Cons)
+		if !ok {
+			return obj
+		}
+		obj = c.Cdr
+	}
+}
+
+func isAtom(obj ilos.Instance) bool {
+	switch obj.(type) {
+	case *instance.Cons, instance.GeneralVector:
+		return false
+	}
+	return true
+}
+
+// width returns the width of obj printed on one line, or a number greater
+// than limit if the width is.
+func (p *printer) width(obj ilos.Instance, limit int) int {
+	var elements []ilos.Instance
+	n := 2
+	switch obj := obj.(type) {
+	case *instance.Cons:
+		elements = obj.Slice()
+		if t := tail(obj); t != instance.Nil {
+			n += 3 + p.width(t, limit)
+		}
+	case instance.GeneralVector:
+		elements = obj
+		n++
+	default:
+		return len([]rune(p.text(obj)))
+	}
+	for i, elt := range elements {
+		if n > limit {
+			return n
+		}
+		if i > 0 {
+			n++
+		}
+		n += p.width(elt, limit-n)
+	}
+	return n
+}
@@ -0,0 +1,80 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package pretty
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ta2gch/iris/reader/parser"
+	"github.com/ta2gch/iris/reader/tokenizer"
+)
+
+func TestSprint(t *testing.T) {
+	tests := []struct {
+		name   string
+		exp    string
+		margin int
+		want   string
+	}{
+		{
+			name:   "fits",
+			exp:    `(a (b c) #(d e) . f)`,
+			margin: 80,
+			want:   `(A (B C) #(D E) . F)`,
+		},
+		{
+			name:   "defun",
+			exp:    `(defun fact (n) (if (= n 0) 1 (* n (fact (- n 1)))))`,
+			margin: 40,
+			want: `(DEFUN FACT (N)
+  (IF (= N 0) 1 (* N (FACT (- N 1)))))`,
+		},
+		{
+			name:   "cond and let",
+			exp:    `(defun f (x) (cond ((< x 0) 'negative) (t (let ((y (* x 2)) (z (+ x 1))) (list y z)))))`,
+			margin: 40,
+			want: `(DEFUN F (X)
+  (COND ((< X 0) (QUOTE NEGATIVE))
+        (T (LET ((Y (* X 2))
+                 (Z (+ X 1)))
+             (LIST Y Z)))))`,
+		},
+		{
+			name:   "fill",
+			exp:    `(1 2 3 4 5 6 7 8 9 10 11 12 13 14 15 16 17 18 19 20)`,
+			margin: 30,
+			want: `(1 2 3 4 5 6 7 8 9 10 11 12 13
+ 14 15 16 17 18 19 20)`,
+		},
+		{
+			name:   "data",
+			exp:    `((name "alice") (tags #(a b c)) (address ((street "main") (city "springfield"))))`,
+			margin: 40,
+			want: `((NAME "alice")
+ (TAGS #(A B C))
+ (ADDRESS ((STREET "main")
+           (CITY "springfield"))))`,
+		},
+		{
+			name:   "dotted",
+			exp:    `((alpha beta gamma) . (delta epsilon zeta))`,
+			margin: 20,
+			want: `((ALPHA BETA GAMMA)
+ DELTA EPSILON ZETA)`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj, err := parser.Parse(tokenizer.NewReader(strings.NewReader(tt.exp)))
+			if err != nil {
+				t.Fatalf("Parse() err = %v", err)
+			}
+			if got := Sprint(obj, tt.margin); got != tt.want {
+				t.Errorf("Sprint() got =\n%v\nwant\n%v", got, tt.want)
+			}
+		})
+	}
+}
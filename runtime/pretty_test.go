@@ -0,0 +1,48 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package runtime
+
+import "testing"
+
+func TestPprint(t *testing.T) {
+	execTests(t, Pprint, []test{
+		{
+			exp:     `(let ((s (create-string-output-stream))) (pprint '(a b) s) (get-output-stream-string s))`,
+			want:    "\"(A B)\n\"",
+			wantErr: false,
+		},
+		{
+			exp: `
+			(dynamic-let ((*print-right-margin* 20))
+			  (let ((s (create-string-output-stream)))
+			    (pprint '(let ((x 1) (y 2)) (list x y x y)) s)
+			    (get-output-stream-string s)))`,
+			want:    "\"(LET ((X 1) (Y 2))\n  (LIST X Y X Y))\n\"",
+			wantErr: false,
+		},
+		{
+			exp: `
+			(dynamic-let ((*print-pretty* t) (*print-right-margin* 10))
+			  (let ((s (create-string-output-stream)))
+			    (format s "~S" '(aaa bbb ccc))
+			    (get-output-stream-string s)))`,
+			want:    "\"(AAA BBB\n CCC)\"",
+			wantErr: false,
+		},
+		{
+			exp: `
+			(let ((s (create-string-output-stream)))
+			  (format s "~S" '(aaa bbb ccc))
+			  (get-output-stream-string s))`,
+			want:    `"(AAA BBB CCC)"`,
+			wantErr: false,
+		},
+		{
+			exp:     `(pprint 1 2)`,
+			want:    `nil`,
+			wantErr: true,
+		},
+	})
+}
@@ -0,0 +1,132 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package runtime
+
+import "testing"
+
+func TestPrintReadably(t *testing.T) {
+	execTests(t, Format, []test{
+		{
+			exp:     `(defun reread (x) (read (create-string-input-stream (format nil "~S" x))))`,
+			want:    `'reread`,
+			wantErr: false,
+		},
+		{
+			exp:     `(format nil "~S ~S ~S" "a\"b\\c" '|foo bar| 'abc)`,
+			want:    `"\"a\\\"b\\\\c\" |foo bar| ABC"`,
+			wantErr: false,
+		},
+		{
+			exp:     `(format nil "~S ~S ~S ~S" ':foo-bar 'a.b 'a@b 'a:b)`,
+			want:    `":FOO-BAR A.B A@B A:B"`,
+			wantErr: false,
+		},
+		{
+			exp:     `(format nil "~A ~A ~A" "a\"b" '|foo bar| #\a)`,
+			want:    `"a\"b foo bar a"`,
+			wantErr: false,
+		},
+		{
+			exp:     `(format nil "~S ~S ~S ~S" 1.0 1e20 #\a #\space)`,
+			want:    `"1.0 1e+20 #\\a #\\SPACE"`,
+			wantErr: false,
+		},
+		{
+			exp:     `(let ((x '(1 -2.5 3/4 "q\"\\" |a\|b| || #\x #(1 "v") nil (a . b)))) (equal (reread x) x))`,
+			want:    `t`,
+			wantErr: false,
+		},
+		{
+			exp:     `(let ((x (expt 2 80))) (equal (reread x) x))`,
+			want:    `t`,
+			wantErr: false,
+		},
+		{
+			exp:     `(let ((x (list 1.0 (quote |nil|) (quote |Mixed|) (quote |1+2|)))) (equal (reread x) x))`,
+			want:    `t`,
+			wantErr: false,
+		},
+		{
+			exp:     `(defclass foo () ((a :initarg a) (b :initarg b)))`,
+			want:    `'foo`,
+			wantErr: false,
+		},
+		{
+			exp:     `(format nil "~A|~S|~A" (create (class foo) 'a 1 'b "x") (create (class foo) 'a 1) (create (class foo)))`,
+			want:    `"#<FOO {A: 1, B: \"x\"}>|#<FOO {A: 1}>|#<FOO>"`,
+			wantErr: false,
+		},
+		{
+			exp:     `(convert #\space <string>)`,
+			want:    `" "`,
+			wantErr: false,
+		},
+		{
+			exp:     `(dynamic-let ((*print-readably* t)) (format nil "~S" '(1 "a" #(b))))`,
+			want:    `"(1 \"a\" #(B))"`,
+			wantErr: false,
+		},
+		{
+			exp:     `(dynamic-let ((*print-readably* t)) (format nil "~A" #'car))`,
+			want:    `"#<FUNCTION>"`,
+			wantErr: false,
+		},
+		{
+			exp:     `(dynamic-let ((*print-readably* t)) (format nil "~S" (list 1 #'car)))`,
+			want:    `nil`,
+			wantErr: true,
+		},
+		{
+			exp:     `(instancep (catch 'c (with-handler (lambda (c) (throw 'c c)) (dynamic-let ((*print-readably* t)) (format nil "~S" (create-string-output-stream))))) (class <print-not-readable>))`,
+			want:    `t`,
+			wantErr: false,
+		},
+	})
+}
+
+func TestReadUnicode(t *testing.T) {
+	execTests(t, Read, []test{
+		{
+			exp:     `(defun 二倍 (数) (* 数 2))`,
+			want:    `'二倍`,
+			wantErr: false,
+		},
+		{
+			exp:     `(二倍 21)`,
+			want:    `42`,
+			wantErr: false,
+		},
+		{
+			exp:     `(list (char-index #\U+3042 "いあう") (length "日本語") (elt "日本語" 1))`,
+			want:    `'(1 3 #\本)`,
+			wantErr: false,
+		},
+		{
+			exp:     `(eq 'Größe 'GRÖSSE)`,
+			want:    `nil`,
+			wantErr: false,
+		},
+		{
+			exp:     `(eq 'ÉTÉ 'été)`,
+			want:    `t`,
+			wantErr: false,
+		},
+		{
+			exp:     `(format nil "~S ~S ~S ~S ~S ~S" #\tab #\Return #\nul #\linefeed #\あ (convert 1 <character>))`,
+			want:    `"#\\TAB #\\RETURN #\\NUL #\\NEWLINE #\\あ #\\U+0001"`,
+			wantErr: false,
+		},
+		{
+			exp:     `(let ((x (list #\tab #\U+0001 #\U+00A0 '規則 '|ä| "\"日本\\"))) (equal (read (create-string-input-stream (format nil "~S" x))) x))`,
+			want:    `t`,
+			wantErr: false,
+		},
+		{
+			exp:     `(read (create-string-input-stream "#\\nosuchname"))`,
+			want:    `nil`,
+			wantErr: true,
+		},
+	})
+}
@@ -0,0 +1,87 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package runtime
+
+import (
+	"math/big"
+
+	"github.com/ta2gch/iris/runtime/ilos"
+	"github.com/ta2gch/iris/runtime/ilos/class"
+	"github.com/ta2gch/iris/runtime/ilos/instance"
+)
+
+// isRational returns true if x is an integer or a ratio, whose arithmetic is
+// exact.
+func isRational(x ilos.Instance) bool {
+	return ilos.InstanceOf(class.Integer, x) || ilos.InstanceOf(class.Ratio, x)
+}
+
+// bigRat returns the value of the rational x as a big.Rat, which the caller
+// may modify.
+func bigRat(x ilos.Instance) *big.Rat {
+	if r, ok := x.(instance.Ratio); ok {
+		return new(big.Rat).Set(r.Rat())
+	}
+	return new(big.Rat).SetInt(bigInt(x))
+}
+
+// addRational, subRational, mulRational and quoRational compute with the
+// integer arithmetic if both arguments are integers, and with big.Rat
+// otherwise. quoRational does not check for a zero divisor.
+
+func addRational(x1, x2 ilos.Instance) ilos.Instance {
+	if ilos.InstanceOf(class.Integer, x1) && ilos.InstanceOf(class.Integer, x2) {
+		return addInteger(x1, x2)
+	}
+	return instance.NewRatio(new(big.Rat).Add(bigRat(x1), bigRat(x2)))
+}
+
+func subRational(x1, x2 ilos.Instance) ilos.Instance {
+	if ilos.InstanceOf(class.Integer, x1) && ilos.InstanceOf(class.Integer, x2) {
+		return subInteger(x1, x2)
+	}
+	return instance.NewRatio(new(big.Rat).Sub(bigRat(x1), bigRat(x2)))
+}
+
+func mulRational(x1, x2 ilos.Instance) ilos.Instance {
+	if ilos.InstanceOf(class.Integer, x1) && ilos.InstanceOf(class.Integer, x2) {
+		return mulInteger(x1, x2)
+	}
+	return instance.NewRatio(new(big.Rat).Mul(bigRat(x1), bigRat(x2)))
+}
+
+func quoRational(x1, x2 ilos.Instance) ilos.Instance {
+	return instance.NewRatio(new(big.Rat).Quo(bigRat(x1), bigRat(x2)))
+}
+
+// floorRatio, ceilingRatio, truncateRatio and roundRatio return the integer
+// that floor, ceiling, truncate and round make of the ratio r.
+
+func floorRatio(r *big.Rat) ilos.Instance {
+	// The denominator of a big.Rat is positive, so Div rounds down.
+	return instance.NewBigInteger(new(big.Int).Div(r.Num(), r.Denom()))
+}
+
+func ceilingRatio(r *big.Rat) ilos.Instance {
+	q := new(big.Int).Div(new(big.Int).Neg(r.Num()), r.Denom())
+	return instance.NewBigInteger(q.Neg(q))
+}
+
+func truncateRatio(r *big.Rat) ilos.Instance {
+	return instance.NewBigInteger(new(big.Int).Quo(r.Num(), r.Denom()))
+}
+
+func roundRatio(r *big.Rat) ilos.Instance {
+	q, m := new(big.Int).DivMod(r.Num(), r.Denom(), new(big.Int))
+	switch m.Lsh(m, 1).Cmp(r.Denom()) {
+	case 1:
+		q.Add(q, big.NewInt(1))
+	case 0:
+		if q.Bit(0) == 1 {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return instance.NewBigInteger(q)
+}
@@ -0,0 +1,112 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package runtime
+
+import "testing"
+
+func TestRatio(t *testing.T) {
+	execTests(t, Quotient, []test{
+		{
+			exp:     `(quotient 1 3)`,
+			want:    `1/3`,
+			wantErr: false,
+		},
+		{
+			exp:     `(quotient 6 4)`,
+			want:    `3/2`,
+			wantErr: false,
+		},
+		{
+			exp:     `(quotient 6 3)`,
+			want:    `2`,
+			wantErr: false,
+		},
+		{
+			exp:     `(quotient 1 2 3)`,
+			want:    `1/6`,
+			wantErr: false,
+		},
+		{
+			exp:     `(quotient 1 2 0.5)`,
+			want:    `1.0`,
+			wantErr: false,
+		},
+		{
+			exp:     `(+ 1/3 2/3)`,
+			want:    `1`,
+			wantErr: false,
+		},
+		{
+			exp:     `(list (+ 1/2 1) (- 1/2 1) (* 2/3 3/4) (- 1/2))`,
+			want:    `'(3/2 -1/2 1/2 -1/2)`,
+			wantErr: false,
+		},
+		{
+			exp:     `(+ 1/2 0.25)`,
+			want:    `0.75`,
+			wantErr: false,
+		},
+		{
+			exp:     `(list (= 1/2 0.5) (< 1/3 0.34) (> 1/3 1/4) (= 2/4 1/2))`,
+			want:    `'(t t t t)`,
+			wantErr: false,
+		},
+		{
+			exp:     `(eql (quotient 1 2) 1/2)`,
+			want:    `t`,
+			wantErr: false,
+		},
+		{
+			exp:     `(instancep 1/2 (class <ratio>))`,
+			want:    `t`,
+			wantErr: false,
+		},
+		{
+			exp:     `(list (floor 7/2) (ceiling 7/2) (truncate 7/2) (round 7/2))`,
+			want:    `'(3 4 3 4)`,
+			wantErr: false,
+		},
+		{
+			exp:     `(list (floor -7/2) (ceiling -7/2) (truncate -7/2) (round -7/2) (round 5/2))`,
+			want:    `'(-4 -3 -3 -4 2)`,
+			wantErr: false,
+		},
+		{
+			exp:     `(list (round 2.5) (round 5/2) (round -2.5) (round -5/2) (round 3.5) (round -3.5))`,
+			want:    `'(2 2 -2 -2 4 -4)`,
+			wantErr: false,
+		},
+		{
+			exp:     `(float 1/4)`,
+			want:    `0.25`,
+			wantErr: false,
+		},
+		{
+			exp:     `(list (expt 2/3 2) (expt 2 -2) (sqrt 4/9))`,
+			want:    `'(4/9 1/4 2/3)`,
+			wantErr: false,
+		},
+		{
+			exp:     `(parse-number "-3/4")`,
+			want:    `-3/4`,
+			wantErr: false,
+		},
+		{
+			exp:     `(let ((s (create-string-output-stream))) (format s "~A" 5/10) (get-output-stream-string s))`,
+			want:    `"1/2"`,
+			wantErr: false,
+		},
+		{
+			exp:     `(quotient 1/2 0)`,
+			want:    `nil`,
+			wantErr: true,
+		},
+		{
+			exp:     `(parse-number "1/0")`,
+			want:    `nil`,
+			wantErr: true,
+		},
+	})
+}
@@ -0,0 +1,237 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package runtime
+
+import (
+	"github.com/ta2gch/iris/reader/parser"
+	"github.com/ta2gch/iris/reader/tokenizer"
+	"github.com/ta2gch/iris/runtime/env"
+	"github.com/ta2gch/iris/runtime/ilos"
+	"github.com/ta2gch/iris/runtime/ilos/class"
+	"github.com/ta2gch/iris/runtime/ilos/instance"
+)
+
+// readtable is a readtable of package parser as an object.
+type readtable struct {
+	*parser.Readtable
+}
+
+func (readtable) Class() ilos.Class {
+	return class.Readtable
+}
+
+func (readtable) String() string {
+	return "#<READTABLE>"
+}
+
+// readerSyntax is the readtable with which the reader of an environment
+// reads. The functions of macro characters defined in ISLisp are called in
+// that environment, so that they see its dynamic bindings and catch tags.
+type readerSyntax struct {
+	*parser.Readtable
+	e env.Environment
+}
+
+// IEEE reports whether the reader reads floats as IEEE 754 does, which it
+// does if e is in IEEE mode.
+func (s readerSyntax) IEEE() bool {
+	return s.e.IEEE
+}
+
+// currentReadtable returns the value of *readtable*, or nil if it is not a
+// readtable.
+func currentReadtable(e env.Environment) *parser.Readtable {
+	if rt, ok := e.DynamicVariable.Get(instance.NewSymbol("*READTABLE*")); ok {
+		if rt, ok := rt.(readtable); ok {
+			return rt.Readtable
+		}
+	}
+	return nil
+}
+
+// syntax returns the syntax of a tokenizer.Reader with which the reader of e
+// reads, or nil for the standard readtable.
+func syntax(e env.Environment) tokenizer.Syntax {
+	if rt := currentReadtable(e); rt != nil {
+		return readerSyntax{rt, e}
+	}
+	return nil
+}
+
+// readerEnvironment returns the environment of the reader which reads from t,
+// or e if t is not read by one. The syntax of t may wrap the one of the
+// reader, as it does while parser.ParseAll reads.
+func readerEnvironment(t *tokenizer.Reader, e env.Environment) env.Environment {
+	s := t.Syntax()
+	for {
+		w, ok := s.(interface{ Unwrap() tokenizer.Syntax })
+		if !ok {
+			break
+		}
+		s = w.Unwrap()
+	}
+	if s, ok := s.(readerSyntax); ok {
+		return s.e
+	}
+	return e
+}
+
+// Readtable returns the value of *readtable* in the top level environment of
+// the interpreter, with which its Read and Eval methods read forms. Changes to
+// it take effect at the next form read.
+func (i *Interpreter) Readtable() *parser.Readtable {
+	return currentReadtable(i.Environment)
+}
+
+// readerStream returns t as an input stream for the function of a macro
+// character, which reads the rest of the form from it.
+func readerStream(t *tokenizer.Reader) ilos.Instance {
+	return instance.Stream{Column: new(int), Reader: t}
+}
+
+// readtableOption returns the readtable in the optional arguments of a
+// function, or the value of *readtable* if there is none.
+func readtableOption(e env.Environment, options []ilos.Instance) (*parser.Readtable, ilos.Instance) {
+	if len(options) == 0 {
+		if rt := currentReadtable(e); rt != nil {
+			return rt, nil
+		}
+		return parser.NewReadtable(), nil
+	}
+	if err := ensure(e, class.Readtable, options[0]); err != nil {
+		return nil, err
+	}
+	return options[0].(readtable).Readtable, nil
+}
+
+// CopyReadtable returns a copy of from-readtable, which defaults to the
+// value of *readtable*. If from-readtable is nil, it returns a copy of the
+// standard readtable.
+func CopyReadtable(e env.Environment, fromReadtable ...ilos.Instance) (ilos.Instance, ilos.Instance) {
+	if len(fromReadtable) > 1 {
+		return SignalCondition(e, instance.NewArityError(e), Nil)
+	}
+	if len(fromReadtable) == 1 && fromReadtable[0] == Nil {
+		return readtable{parser.NewReadtable()}, nil
+	}
+	rt, err := readtableOption(e, fromReadtable)
+	if err != nil {
+		return nil, err
+	}
+	return readtable{rt.Copy()}, nil
+}
+
+// SetMacroCharacter makes char a macro character of readtable, which
+// defaults to the value of *readtable*. When the reader reads char, it calls
+// function with the input stream and char, and the value of function is the
+// object read. A macro character terminates a symbol or a number unless
+// non-terminating-p is given and not nil.
+func SetMacroCharacter(e env.Environment, char, function ilos.Instance, options ...ilos.Instance) (ilos.Instance, ilos.Instance) {
+	if len(options) > 2 {
+		return SignalCondition(e, instance.NewArityError(e), Nil)
+	}
+	if err := ensure(e, class.Character, char); err != nil {
+		return nil, err
+	}
+	if err := ensure(e, class.Function, function); err != nil {
+		return nil, err
+	}
+	nonTerminating := len(options) > 0 && options[0] != Nil
+	if len(options) > 0 {
+		options = options[1:]
+	}
+	rt, err := readtableOption(e, options)
+	if err != nil {
+		return nil, err
+	}
+	rt.SetMacroCharacter(rune(char.(instance.Character)), func(t *tokenizer.Reader, char rune) (ilos.Instance, ilos.Instance) {
+		e := readerEnvironment(t, e)
+		return Funcall(e.NewDynamic(), function, readerStream(t), instance.NewCharacter(char))
+	}, nonTerminating)
+	return T, nil
+}
+
+// MakeDispatchMacroCharacter makes char a dispatching macro character of
+// readtable, which defaults to the value of *readtable*, without
+// sub-characters. non-terminating-p is as for set-macro-character.
+func MakeDispatchMacroCharacter(e env.Environment, char ilos.Instance, options ...ilos.Instance) (ilos.Instance, ilos.Instance) {
+	if len(options) > 2 {
+		return SignalCondition(e, instance.NewArityError(e), Nil)
+	}
+	if err := ensure(e, class.Character, char); err != nil {
+		return nil, err
+	}
+	nonTerminating := len(options) > 0 && options[0] != Nil
+	if len(options) > 0 {
+		options = options[1:]
+	}
+	rt, err := readtableOption(e, options)
+	if err != nil {
+		return nil, err
+	}
+	rt.MakeDispatchMacroCharacter(rune(char.(instance.Character)), nonTerminating)
+	return T, nil
+}
+
+// SetDispatchMacroCharacter makes function read the sub-character
+// sub-char of the dispatching macro character disp-char of readtable, which
+// defaults to the value of *readtable*. The reader calls function with the
+// input stream, sub-char and the decimal argument between the two
+// characters, or nil if there is none. An error shall be signaled if
+// disp-char is not a dispatching macro character (error-id. domain-error).
+func SetDispatchMacroCharacter(e env.Environment, dispChar, subChar, function ilos.Instance, options ...ilos.Instance) (ilos.Instance, ilos.Instance) {
+	if len(options) > 1 {
+		return SignalCondition(e, instance.NewArityError(e), Nil)
+	}
+	if err := ensure(e, class.Character, dispChar, subChar); err != nil {
+		return nil, err
+	}
+	if err := ensure(e, class.Function, function); err != nil {
+		return nil, err
+	}
+	rt, err := readtableOption(e, options)
+	if err != nil {
+		return nil, err
+	}
+	f := func(t *tokenizer.Reader, sub rune, n int) (ilos.Instance, ilos.Instance) {
+		arg := Nil
+		if n >= 0 {
+			arg = instance.NewInteger(n)
+		}
+		e := readerEnvironment(t, e)
+		return Funcall(e.NewDynamic(), function, readerStream(t), instance.NewCharacter(sub), arg)
+	}
+	if !rt.SetDispatchMacroCharacter(rune(dispChar.(instance.Character)), rune(subChar.(instance.Character)), f) {
+		return SignalCondition(e, instance.NewDomainError(e, dispChar, class.Character), Nil)
+	}
+	return T, nil
+}
+
+// ReadDelimitedList reads objects from input-stream, which defaults to the
+// standard input, up to the character char and returns them in a list. char
+// must be a terminating macro character or #\).
+func ReadDelimitedList(e env.Environment, char ilos.Instance, options ...ilos.Instance) (ilos.Instance, ilos.Instance) {
+	if len(options) > 1 {
+		return SignalCondition(e, instance.NewArityError(e), Nil)
+	}
+	if err := ensure(e, class.Character, char); err != nil {
+		return nil, err
+	}
+	s := e.StandardInput
+	if len(options) > 0 {
+		s = options[0]
+	}
+	if b, _ := InputStreamP(e, s); b == Nil {
+		return SignalCondition(e, instance.NewDomainError(e, s, class.Stream), Nil)
+	}
+	t := s.(instance.Stream).Reader
+	defer t.SetSyntax(t.Syntax())
+	t.SetSyntax(syntax(e))
+	v, err := parser.ReadDelimitedList(t, rune(char.(instance.Character)))
+	if err != nil {
+		return readError(e, err)
+	}
+	return v, nil
+}
@@ -0,0 +1,105 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package runtime
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ta2gch/iris/reader/tokenizer"
+	"github.com/ta2gch/iris/runtime/ilos"
+	"github.com/ta2gch/iris/runtime/ilos/instance"
+)
+
+func TestReadtable(t *testing.T) {
+	execTests(t, SetMacroCharacter, []test{
+		{
+			exp:     `(defun read-string (s) (read (create-string-input-stream s)))`,
+			want:    `'read-string`,
+			wantErr: false,
+		},
+		{
+			exp:     `(set-macro-character #\! (lambda (s c) (list 'not (read s))))`,
+			want:    `t`,
+			wantErr: false,
+		},
+		{
+			exp:     `(read-string "(a !b c!d)")`,
+			want:    `'(a (not b) c (not d))`,
+			wantErr: false,
+		},
+		{
+			exp:     `(dynamic-let ((*readtable* (copy-readtable nil))) (read-string "(a !b)"))`,
+			want:    `'(a !b)`,
+			wantErr: false,
+		},
+		{
+			exp:     `(set-dispatch-macro-character #\# #\d (lambda (s c n) (list c n (read s))))`,
+			want:    `t`,
+			wantErr: false,
+		},
+		{
+			exp:     `(read-string "(#d x #3D y)")`,
+			want:    `'((#\d nil x) (#\D 3 y))`,
+			wantErr: false,
+		},
+		{
+			exp: `(progn
+			        (set-macro-character #\] (lambda (s c) (error "unmatched ]")))
+			        (set-macro-character #\[ (lambda (s c) (cons 'vector (read-delimited-list #\] s)))))`,
+			want:    `t`,
+			wantErr: false,
+		},
+		{
+			exp:     `(read-string "[1 [2]x]")`,
+			want:    `'(vector 1 (vector 2) x)`,
+			wantErr: false,
+		},
+		{
+			exp:     `(read-string "(1 ])")`,
+			want:    `nil`,
+			wantErr: true,
+		},
+		{
+			exp:     `(progn (set-macro-character #\? (lambda (s c) (throw 'c (dynamic *x*)))) (catch 'c (dynamic-let ((*x* 'thrown)) (read-string "?"))))`,
+			want:    `'thrown`,
+			wantErr: false,
+		},
+		{
+			exp:     `(let ((rt (copy-readtable))) (make-dispatch-macro-character #\$ t rt) (set-dispatch-macro-character #\$ #\q (lambda (s c n) (list 'quote (read s))) rt) (dynamic-let ((*readtable* rt)) (read-string "(a$b $qc)")))`,
+			want:    `'(a$b (quote c))`,
+			wantErr: false,
+		},
+		{
+			exp:     `(set-dispatch-macro-character #\! #\a (lambda (s c n) n))`,
+			want:    `nil`,
+			wantErr: true,
+		},
+		{
+			exp:     `(read-string "#{a}")`,
+			want:    `nil`,
+			wantErr: true,
+		},
+		{
+			exp:     `(read-string "#r\"(\"")`,
+			want:    `nil`,
+			wantErr: true,
+		},
+	})
+}
+
+func TestInterpreter_Readtable(t *testing.T) {
+	i := New(Options{})
+	i.Readtable().SetMacroCharacter('@', func(t *tokenizer.Reader, char rune) (ilos.Instance, ilos.Instance) {
+		return instance.NewInteger(42), nil
+	}, false)
+	if got, err := i.EvalReader(strings.NewReader("(list @ @)")); err != nil || got.String() != "(42 42)" {
+		t.Errorf("Interpreter.EvalReader() = %v, err = %v, want (42 42)", got, err)
+	}
+	got, err := i.EvalString(`(set-macro-character #\% (lambda (s c) (list 'quote (read s)))) (list %a %b)`)
+	if err != nil || got.String() != "(A B)" {
+		t.Errorf("Interpreter.EvalString() = %v, err = %v, want (A B)", got, err)
+	}
+}
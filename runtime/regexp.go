@@ -0,0 +1,40 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package runtime
+
+import (
+	"github.com/ta2gch/iris/runtime/env"
+	"github.com/ta2gch/iris/runtime/ilos"
+	"github.com/ta2gch/iris/runtime/ilos/class"
+	"github.com/ta2gch/iris/runtime/ilos/instance"
+)
+
+// RegexpMatch returns a list of the leftmost match of regexp in string and
+// the matches of its groups, or nil if there is none. A group which matched
+// nothing is nil in the list. #r"pattern" reads as a regexp of the syntax of
+// the regexp package of Go, in which a backslash is itself except before a
+// double quote.
+func RegexpMatch(e env.Environment, regexp, str ilos.Instance) (ilos.Instance, ilos.Instance) {
+	if err := ensure(e, class.Regexp, regexp); err != nil {
+		return nil, err
+	}
+	if err := ensure(e, class.String, str); err != nil {
+		return nil, err
+	}
+	s := string(str.(instance.String))
+	m := regexp.(instance.Regexp).FindStringSubmatchIndex(s)
+	if m == nil {
+		return Nil, nil
+	}
+	matches := []ilos.Instance{}
+	for i := 0; i < len(m); i += 2 {
+		if m[i] < 0 {
+			matches = append(matches, Nil)
+			continue
+		}
+		matches = append(matches, instance.NewString([]rune(s[m[i]:m[i+1]])))
+	}
+	return List(e, matches...)
+}
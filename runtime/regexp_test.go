@@ -0,0 +1,32 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package runtime
+
+import "testing"
+
+func TestRegexpMatch(t *testing.T) {
+	execTests(t, RegexpMatch, []test{
+		{
+			exp:     `(regexp-match #r"(\d+)-(\d+)?" "a 12- b")`,
+			want:    `'("12-" "12" nil)`,
+			wantErr: false,
+		},
+		{
+			exp:     `(regexp-match #r"\"x\"" "abc")`,
+			want:    `nil`,
+			wantErr: false,
+		},
+		{
+			exp:     `(format nil "~S" #r"a\"\s")`,
+			want:    `"#r\"a\\\"\\s\""`,
+			wantErr: false,
+		},
+		{
+			exp:     `(regexp-match "a" "abc")`,
+			want:    `nil`,
+			wantErr: true,
+		},
+	})
+}
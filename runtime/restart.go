@@ -0,0 +1,204 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package runtime
+
+import (
+	"fmt"
+
+	"github.com/ta2gch/iris/runtime/env"
+	"github.com/ta2gch/iris/runtime/ilos"
+	"github.com/ta2gch/iris/runtime/ilos/class"
+	"github.com/ta2gch/iris/runtime/ilos/instance"
+)
+
+// Restarts are an implementation extension modelled on those of Common Lisp.
+// A restart is a named way to go on from a condition, which restart-bind and
+// restart-case establish for the dynamic extent of their forms. A handler
+// finds the restarts active where the condition was signaled with
+// compute-restarts and transfers control with invoke-restart.
+
+// restart is a named function established by restart-bind or restart-case.
+type restart struct {
+	name     ilos.Instance
+	function ilos.Instance
+}
+
+func (*restart) Class() ilos.Class {
+	return class.Restart
+}
+
+func (r *restart) String() string {
+	return fmt.Sprintf("#<RESTART %v>", r.name)
+}
+
+// restarts makes a restart of each binding (name function-form) or clause
+// (name lambda-list form*), whose name must be a symbol, with function.
+func restarts(e env.Environment, bindings []ilos.Instance, function func(definition []ilos.Instance, r *restart) (ilos.Instance, ilos.Instance)) ([]ilos.Instance, ilos.Instance) {
+	rs := []ilos.Instance{}
+	for _, binding := range bindings {
+		if err := ensure(e, class.Cons, binding); err != nil {
+			return nil, err
+		}
+		definition := binding.(instance.List).Slice()
+		if len(definition) < 2 {
+			_, err := SignalCondition(e, instance.NewArityError(e), Nil)
+			return nil, err
+		}
+		if err := ensure(e, class.Symbol, definition[0]); err != nil {
+			return nil, err
+		}
+		r := &restart{name: definition[0]}
+		fun, err := function(definition, r)
+		if err != nil {
+			return nil, err
+		}
+		r.function = fun
+		rs = append(rs, r)
+	}
+	return rs, nil
+}
+
+// RestartBind establishes a restart for each binding (name function-form)
+// during the forms. Invoking the restart calls the value of function-form
+// with the arguments to invoke-restart and returns its value.
+func RestartBind(e env.Environment, bindings ilos.Instance, forms ...ilos.Instance) (ilos.Instance, ilos.Instance) {
+	if err := ensure(e, class.List, bindings); err != nil {
+		return nil, err
+	}
+	rs, err := restarts(e, bindings.(instance.List).Slice(), func(definition []ilos.Instance, r *restart) (ilos.Instance, ilos.Instance) {
+		if len(definition) != 2 {
+			return SignalCondition(e, instance.NewArityError(e), Nil)
+		}
+		fun, err := Eval(e, definition[1])
+		if err != nil {
+			return nil, err
+		}
+		if err := ensure(e, class.Function, fun); err != nil {
+			return nil, err
+		}
+		return fun, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	e.Restart = append(rs, e.Restart...)
+	return Progn(e, forms...)
+}
+
+// RestartCase evaluates form with a restart for each clause
+// (name lambda-list form*). Invoking the restart exits from restart-case,
+// which then returns the value of the forms of the clause with the
+// parameters of lambda-list bound to the arguments to invoke-restart.
+func RestartCase(e env.Environment, form ilos.Instance, clauses ...ilos.Instance) (ilos.Instance, ilos.Instance) {
+	uid := instance.NewInteger(uniqueInt())
+	rs, err := restarts(e, clauses, func(definition []ilos.Instance, r *restart) (ilos.Instance, ilos.Instance) {
+		if err := checkLambdaList(e, definition[1]); err != nil {
+			return nil, err
+		}
+		return instance.NewFunction(r.name, func(e env.Environment, arguments ...ilos.Instance) (ilos.Instance, ilos.Instance) {
+			list, err := List(e, arguments...)
+			if err != nil {
+				return nil, err
+			}
+			return nil, instance.NewRestartTag(r, uid, list)
+		}), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	inner := e
+	inner.Restart = append(rs, e.Restart...)
+	ret, err := Eval(inner, form)
+	if err == nil || !ilos.InstanceOf(class.RestartTag, err) {
+		return ret, err
+	}
+	tag, _ := err.(instance.Instance).GetSlotValue(instance.NewSymbol("IRIS.TAG"), class.Escape)
+	uid1, _ := err.(instance.Instance).GetSlotValue(instance.NewSymbol("IRIS.UID"), class.Escape)
+	if uid != uid1 {
+		return nil, err
+	}
+	arguments, _ := err.(instance.Instance).GetSlotValue(instance.NewSymbol("IRIS.OBJECT"), class.RestartTag)
+	for i, r := range rs {
+		if r != tag {
+			continue
+		}
+		clause := clauses[i].(instance.List).Slice()
+		fun, err := newNamedFunction(e, r.(*restart).name, clause[1], clause[2:]...)
+		if err != nil {
+			return nil, err
+		}
+		return fun.(instance.Applicable).Apply(e.NewDynamic(), arguments.(instance.List).Slice()...)
+	}
+	return nil, err
+}
+
+// findRestart returns the innermost active restart which is r or is named r.
+func findRestart(e env.Environment, r ilos.Instance) (*restart, bool) {
+	for _, active := range e.Restart {
+		if active == r || active.(*restart).name == r {
+			return active.(*restart), true
+		}
+	}
+	return nil, false
+}
+
+// FindRestart returns the innermost active restart named name, or nil if
+// there is none.
+func FindRestart(e env.Environment, name ilos.Instance) (ilos.Instance, ilos.Instance) {
+	if err := ensure(e, class.Symbol, name); err != nil {
+		return nil, err
+	}
+	if r, ok := findRestart(e, name); ok {
+		return r, nil
+	}
+	return Nil, nil
+}
+
+// InvokeRestart calls the function of the active restart r, which is a
+// restart or the name of one, with arguments. It signals <control-error> if
+// r is not active.
+func InvokeRestart(e env.Environment, r ilos.Instance, arguments ...ilos.Instance) (ilos.Instance, ilos.Instance) {
+	if !ilos.InstanceOf(class.Restart, r) {
+		if err := ensure(e, class.Symbol, r); err != nil {
+			return nil, err
+		}
+	}
+	active, ok := findRestart(e, r)
+	if !ok {
+		return SignalCondition(e, instance.NewControlError(e), Nil)
+	}
+	return active.function.(instance.Applicable).Apply(e.NewDynamic(), arguments...)
+}
+
+// ComputeRestarts returns a list of the active restarts, innermost first.
+func ComputeRestarts(e env.Environment) (ilos.Instance, ilos.Instance) {
+	return List(e, e.Restart...)
+}
+
+// RestartName returns the name of restart r.
+func RestartName(e env.Environment, r ilos.Instance) (ilos.Instance, ilos.Instance) {
+	if err := ensure(e, class.Restart, r); err != nil {
+		return nil, err
+	}
+	return r.(*restart).name, nil
+}
+
+// RestartNames returns the names of the restarts which were active where
+// condition was signaled, innermost first.
+func RestartNames(condition ilos.Instance) []ilos.Instance {
+	names := []ilos.Instance{}
+	c, ok := condition.(instance.Instance)
+	if !ok {
+		return names
+	}
+	rs, ok := c.GetSlotValue(instance.NewSymbol("IRIS.RESTARTS"), class.SeriousCondition)
+	if !ok {
+		return names
+	}
+	for _, r := range rs.(instance.List).Slice() {
+		names = append(names, r.(*restart).name)
+	}
+	return names
+}
@@ -0,0 +1,117 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package runtime
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ta2gch/iris/runtime/ilos/instance"
+)
+
+func TestRestartCase(t *testing.T) {
+	execTests(t, RestartCase, []test{
+		{
+			exp: `
+				(defun parse-record (x)
+					(restart-case (+ (car x) 1)
+						(skip-record () 'skipped)
+						(use-value (v) v)))
+				`,
+			want:    `'parse-record`,
+			wantErr: false,
+		},
+		{
+			exp:     `(with-handler (lambda (c) (invoke-restart 'use-value 0)) (mapcar #'parse-record '((1) 2 (3))))`,
+			want:    `'(2 0 4)`,
+			wantErr: false,
+		},
+		{
+			exp:     `(with-handler (lambda (c) (invoke-restart (find-restart 'skip-record))) (mapcar #'parse-record '((1) a)))`,
+			want:    `'(2 skipped)`,
+			wantErr: false,
+		},
+		{
+			exp:     `(restart-case (invoke-restart 'add 1 2) (add (x y) (+ x y)))`,
+			want:    `3`,
+			wantErr: false,
+		},
+		{
+			exp:     `(restart-case (restart-case (invoke-restart 'r) (r () 'inner)) (r () 'outer))`,
+			want:    `'inner`,
+			wantErr: false,
+		},
+		{
+			exp:     `(restart-case 1 (r () 2))`,
+			want:    `1`,
+			wantErr: false,
+		},
+		{
+			exp:     `(parse-record 'a)`,
+			want:    `nil`,
+			wantErr: true,
+		},
+		{
+			exp:     `(invoke-restart 'use-value 1)`,
+			want:    `nil`,
+			wantErr: true,
+		},
+		{
+			exp:     `(restart-case 1 (1 () 2))`,
+			want:    `nil`,
+			wantErr: true,
+		},
+	})
+}
+
+func TestRestartBind(t *testing.T) {
+	execTests(t, RestartBind, []test{
+		{
+			exp:     `(restart-bind ((twice (lambda (x) (* 2 x)))) (+ (invoke-restart 'twice 20) 2))`,
+			want:    `42`,
+			wantErr: false,
+		},
+		{
+			exp:     `(restart-bind ((a #'list) (b #'list)) (restart-case (mapcar #'restart-name (compute-restarts)) (c ())))`,
+			want:    `'(c a b)`,
+			wantErr: false,
+		},
+		{
+			exp:     `(mapcar #'restart-name (compute-restarts))`,
+			want:    `nil`,
+			wantErr: false,
+		},
+		{
+			exp:     `(find-restart 'a)`,
+			want:    `nil`,
+			wantErr: false,
+		},
+		{
+			exp:     `(restart-bind ((a 1)) 2)`,
+			want:    `nil`,
+			wantErr: true,
+		},
+	})
+}
+
+func TestRestartNames(t *testing.T) {
+	for _, backend := range []Backend{BackendCompiler, BackendVM} {
+		i := New(Options{Backend: backend})
+		_, err := i.EvalString(`(restart-case (restart-bind ((retry #'list)) (car 1)) (skip ()))`)
+		if err == nil {
+			t.Fatalf("%v: no error", backend)
+		}
+		names := []string{}
+		for _, name := range RestartNames(err) {
+			names = append(names, name.String())
+		}
+		if got := strings.Join(names, " "); got != "RETRY SKIP" {
+			t.Errorf("%v: RestartNames() = %v, want RETRY SKIP", backend, got)
+		}
+		if got := RestartNames(instance.NewInteger(1)); len(got) != 0 {
+			t.Errorf("%v: RestartNames() = %v, want none", backend, got)
+		}
+	}
+}
@@ -39,7 +39,7 @@ func Length(e env.Environment, sequence ilos.Instance) (ilos.Instance, ilos.Inst
 // error shall be signaled if sequence is not a basic-vector or a list or if z
 // is not an integer (error-id. domain-error).
 func Elt(e env.Environment, sequence, z ilos.Instance) (ilos.Instance, ilos.Instance) {
-	if err := ensure(e, class.Integer, z); err != nil {
+	if err := ensureFixnum(e, z); err != nil {
 		return nil, err
 	}
 	switch {
@@ -75,7 +75,7 @@ func Elt(e env.Environment, sequence, z ilos.Instance) (ilos.Instance, ilos.Inst
 // be signaled if sequence is not a basic-vector or a list or if z is not an
 // integer (error-id. domain-error). obj may be any ISLISP object.
 func SetElt(e env.Environment, obj, sequence, z ilos.Instance) (ilos.Instance, ilos.Instance) {
-	if err := ensure(e, class.Integer, z); err != nil {
+	if err := ensureFixnum(e, z); err != nil {
 		return nil, err
 	}
 	switch {
@@ -124,7 +124,7 @@ func SetElt(e env.Environment, obj, sequence, z ilos.Instance) (ilos.Instance, i
 // signaled if sequence is not a basic-vector or a list, or if z1 is not an
 // integer, or if z2 is not an integer (error-id. domain-error).
 func Subseq(e env.Environment, sequence, z1, z2 ilos.Instance) (ilos.Instance, ilos.Instance) {
-	if err := ensure(e, class.Integer, z1, z2); err != nil {
+	if err := ensureFixnum(e, z1, z2); err != nil {
 		return nil, err
 	}
 	start := int(z1.(instance.Integer))
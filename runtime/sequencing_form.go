@@ -15,13 +15,18 @@ import (
 // the last are discarded, so they are executed only for their side-effects.
 // progn without forms returns nil.
 func Progn(e env.Environment, forms ...ilos.Instance) (ilos.Instance, ilos.Instance) {
-	var err ilos.Instance
-	ret := Nil
-	for _, form := range forms {
-		ret, err = Eval(e, form)
-		if err != nil {
+	return force(progn(e, forms...))
+}
+
+// progn is Progn with the last form in tail position (see evalTail).
+func progn(e env.Environment, forms ...ilos.Instance) (ilos.Instance, ilos.Instance) {
+	if len(forms) == 0 {
+		return Nil, nil
+	}
+	for _, form := range forms[:len(forms)-1] {
+		if _, err := Eval(e, form); err != nil {
 			return nil, err
 		}
 	}
-	return ret, nil
+	return evalTail(e, forms[len(forms)-1])
 }
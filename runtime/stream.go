@@ -92,85 +92,80 @@ func WithErrorOutput(e env.Environment, streamForm ilos.Instance, forms ...ilos.
 
 func OpenInputFile(e env.Environment, filename ilos.Instance, elementClass ...ilos.Instance) (ilos.Instance, ilos.Instance) {
 	// TODO: elementClass
-	if ok, _ := Stringp(e, filename); ok == Nil {
-		return SignalCondition(e, instance.NewDomainError(e, filename, class.String), Nil)
-	}
-	file, err := os.Open(string(filename.(instance.String)))
-	if err != nil {
-		return SignalCondition(e, instance.NewStreamError(e), Nil)
-	}
-	return instance.NewStream(file, nil), nil
+	s, _, err := openFile(e, fullAccess, filename, os.O_RDONLY)
+	return s, err
 }
 
 func OpenOutputFile(e env.Environment, filename ilos.Instance, elementClass ...ilos.Instance) (ilos.Instance, ilos.Instance) {
 	// TODO: elementClass
-	if ok, _ := Stringp(e, filename); ok == Nil {
-		return SignalCondition(e, instance.NewDomainError(e, filename, class.String), Nil)
-	}
-	file, err := os.Open(string(filename.(instance.String)))
-	if err != nil {
-		return SignalCondition(e, instance.NewStreamError(e), Nil)
-	}
-	return instance.NewStream(nil, file), nil
+	s, _, err := openFile(e, fullAccess, filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	return s, err
 }
 
 func OpenIoFile(e env.Environment, filename ilos.Instance, elementClass ...ilos.Instance) (ilos.Instance, ilos.Instance) {
 	// TODO: elementClass
-	if ok, _ := Stringp(e, filename); ok == Nil {
-		return SignalCondition(e, instance.NewDomainError(e, filename, class.String), Nil)
-	}
-	file, err := os.Open(string(filename.(instance.String)))
-	if err != nil {
-		return SignalCondition(e, instance.NewStreamError(e), Nil)
-	}
-	return instance.NewStream(file, file), nil
+	s, _, err := openFile(e, fullAccess, filename, os.O_RDWR|os.O_CREATE)
+	return s, err
 }
 
 func WithOpenInputFile(e env.Environment, fileSpec ilos.Instance, forms ...ilos.Instance) (ilos.Instance, ilos.Instance) {
-	if ok, _ := Consp(e, fileSpec); ok == Nil {
-		return SignalCondition(e, instance.NewDomainError(e, fileSpec, class.Cons), Nil)
-	}
-	n, err := Car(e, fileSpec)
-	if err != nil {
-		return nil, err
-	}
-	s, err := OpenInputFile(e, n)
-	if err != nil {
-		return nil, err
-	}
-	e.Variable.Define(n, s)
-	return Progn(e, forms...)
+	return withOpenFile(e, fullAccess, os.O_RDONLY, fileSpec, forms...)
 }
 
 func WithOpenOutputFile(e env.Environment, fileSpec ilos.Instance, forms ...ilos.Instance) (ilos.Instance, ilos.Instance) {
-	if ok, _ := Consp(e, fileSpec); ok == Nil {
-		return SignalCondition(e, instance.NewDomainError(e, fileSpec, class.Cons), Nil)
+	return withOpenFile(e, fullAccess, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fileSpec, forms...)
+}
+
+func WithOpenIoFile(e env.Environment, fileSpec ilos.Instance, forms ...ilos.Instance) (ilos.Instance, ilos.Instance) {
+	return withOpenFile(e, fullAccess, os.O_RDWR|os.O_CREATE, fileSpec, forms...)
+}
+
+// openFile opens the file named by filename through fs. The direction of the
+// returned stream follows flag.
+func openFile(e env.Environment, fs fileSystem, filename ilos.Instance, flag int) (ilos.Instance, *os.File, ilos.Instance) {
+	if err := ensure(e, class.String, filename); err != nil {
+		return nil, nil, err
 	}
-	n, err := Car(e, fileSpec)
-	if err != nil {
-		return nil, err
+	file, err := fs(string(filename.(instance.String)), flag)
+	if err == errAccessDenied {
+		_, err := SignalCondition(e, instance.NewAccessDenied(e, filename), Nil)
+		return nil, nil, err
 	}
-	s, err := OpenOutputFile(e, n)
 	if err != nil {
-		return nil, err
+		_, err := SignalCondition(e, instance.NewStreamError(e, Nil), Nil)
+		return nil, nil, err
+	}
+	switch flag & (os.O_RDONLY | os.O_WRONLY | os.O_RDWR) {
+	case os.O_WRONLY:
+		return instance.NewStream(nil, file), file, nil
+	case os.O_RDWR:
+		return instance.NewStream(file, file), file, nil
+	default:
+		return instance.NewStream(file, nil), file, nil
 	}
-	e.Variable.Define(n, s)
-	return Progn(e, forms...)
 }
 
-func WithOpenIoFile(e env.Environment, fileSpec ilos.Instance, forms ...ilos.Instance) (ilos.Instance, ilos.Instance) {
-	if ok, _ := Consp(e, fileSpec); ok == Nil {
-		return SignalCondition(e, instance.NewDomainError(e, fileSpec, class.Cons), Nil)
+// withOpenFile evaluates forms with the variable name bound to a stream on the
+// file of fileSpec, which is (name filename [element-class]), and closes the
+// file afterwards.
+func withOpenFile(e env.Environment, fs fileSystem, flag int, fileSpec ilos.Instance, forms ...ilos.Instance) (ilos.Instance, ilos.Instance) {
+	if err := ensure(e, class.Cons, fileSpec); err != nil {
+		return nil, err
+	}
+	spec := fileSpec.(instance.List).Slice()
+	if len(spec) < 2 || len(spec) > 3 {
+		return SignalCondition(e, instance.NewArityError(e), Nil)
 	}
-	n, err := Car(e, fileSpec)
+	filename, err := Eval(e, spec[1])
 	if err != nil {
 		return nil, err
 	}
-	s, err := OpenIoFile(e, n)
+	s, file, err := openFile(e, fs, filename, flag)
 	if err != nil {
 		return nil, err
 	}
-	e.Variable.Define(n, s)
+	defer file.Close()
+	e.Variable.Define(spec[0], s)
 	return Progn(e, forms...)
 }
 
@@ -236,16 +231,33 @@ func Read(e env.Environment, options ...ilos.Instance) (ilos.Instance, ilos.Inst
 			eosValue = options[2]
 		}
 	}
-	v, err := parser.Parse(s.(instance.Stream).Reader)
+	t := s.(instance.Stream).Reader
+	defer t.SetSyntax(t.Syntax())
+	t.SetSyntax(syntax(e))
+	v, err := parser.Parse(t)
 	if err != nil && ilos.InstanceOf(class.EndOfStream, err) {
 		if eosErrorP {
 			return nil, err
 		}
 		return eosValue, nil
 	}
+	if err != nil {
+		return readError(e, err)
+	}
 	return v, nil
 }
 
+// readError signals err if it is a <parse-error> of the parser or an
+// <arithmetic-error> of a literal out of the float range. Other conditions
+// come from the functions of macro characters, which have signaled them
+// already, and are returned as they are.
+func readError(e env.Environment, err ilos.Instance) (ilos.Instance, ilos.Instance) {
+	if ilos.InstanceOf(class.ParseError, err) || ilos.InstanceOf(class.ArithmeticError, err) {
+		return SignalCondition(e, err, Nil)
+	}
+	return nil, err
+}
+
 func ReadChar(e env.Environment, options ...ilos.Instance) (ilos.Instance, ilos.Instance) {
 	s := e.StandardInput
 	if len(options) > 0 {
@@ -269,7 +281,7 @@ func ReadChar(e env.Environment, options ...ilos.Instance) (ilos.Instance, ilos.
 	v, _, err := bufio.NewReader(s.(instance.Stream).Reader).ReadRune()
 	if err != nil {
 		if eosErrorP {
-			return nil, instance.Create(e, class.EndOfStream)
+			return SignalCondition(e, instance.NewEndOfStream(e, s), Nil)
 		}
 		return eosValue, nil
 	}
@@ -299,7 +311,7 @@ func ReadLine(e env.Environment, options ...ilos.Instance) (ilos.Instance, ilos.
 	v, _, err := bufio.NewReader(s.(instance.Stream).Reader).ReadLine()
 	if err != nil {
 		if eosErrorP {
-			return nil, instance.Create(e, class.EndOfStream)
+			return SignalCondition(e, instance.NewEndOfStream(e, s), Nil)
 		}
 		return eosValue, nil
 	}
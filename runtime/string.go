@@ -6,6 +6,7 @@ package runtime
 
 import (
 	"strings"
+	"unicode/utf8"
 
 	"github.com/ta2gch/iris/runtime/env"
 	"github.com/ta2gch/iris/runtime/ilos"
@@ -29,7 +30,7 @@ func Stringp(e env.Environment, obj ilos.Instance) (ilos.Instance, ilos.Instance
 // cannot-create-string). An error shall be signaled if i is not a non-negative
 // integer or if initial-character is not a character (error-id. domain-error).
 func CreateString(e env.Environment, i ilos.Instance, initialElement ...ilos.Instance) (ilos.Instance, ilos.Instance) {
-	if !ilos.InstanceOf(class.Integer, i) || int(i.(instance.Integer)) < 0 {
+	if n, ok := i.(instance.Integer); !ok || n < 0 {
 		return SignalCondition(e, instance.NewDomainError(e, i, class.Object), Nil)
 	}
 	if len(initialElement) > 1 {
@@ -134,7 +135,7 @@ func CharIndex(e env.Environment, char, str ilos.Instance, startPosition ...ilos
 	}
 	n := 0
 	if len(startPosition) == 1 {
-		if err := ensure(e, class.Integer, startPosition[0]); err != nil {
+		if err := ensureFixnum(e, startPosition[0]); err != nil {
 			return nil, err
 		}
 		n = int(startPosition[0].(instance.Integer))
@@ -145,7 +146,7 @@ func CharIndex(e env.Environment, char, str ilos.Instance, startPosition ...ilos
 	if i < 0 {
 		return Nil, nil
 	}
-	return instance.NewInteger(i + n), nil
+	return instance.NewInteger(utf8.RuneCountInString(s[:i]) + n), nil
 }
 
 // StringIndex returns the position of the given substring within string. The
@@ -168,7 +169,7 @@ func StringIndex(e env.Environment, sub, str ilos.Instance, startPosition ...ilo
 	}
 	n := 0
 	if len(startPosition) == 1 {
-		if err := ensure(e, class.Integer, startPosition[0]); err != nil {
+		if err := ensureFixnum(e, startPosition[0]); err != nil {
 			return nil, err
 		}
 		n = int(startPosition[0].(instance.Integer))
@@ -179,7 +180,7 @@ func StringIndex(e env.Environment, sub, str ilos.Instance, startPosition ...ilo
 	if i < 0 {
 		return Nil, nil
 	}
-	return instance.NewInteger(i + n), nil
+	return instance.NewInteger(utf8.RuneCountInString(s[:i]) + n), nil
 }
 
 // StringAppend returns a single string containing a sequence of characters that
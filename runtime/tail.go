@@ -0,0 +1,65 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package runtime
+
+import (
+	"github.com/ta2gch/iris/runtime/env"
+	"github.com/ta2gch/iris/runtime/ilos"
+	"github.com/ta2gch/iris/runtime/ilos/class"
+	"github.com/ta2gch/iris/runtime/ilos/instance"
+)
+
+// tailCall is a call of a function defined in ISLisp that appears in tail
+// position and has not been made yet. Special forms return it up to the
+// nearest force instead of making the call, so a chain of tail calls runs in
+// constant Go stack. It never escapes Eval or the Apply method of a function.
+type tailCall struct {
+	function  *lambda
+	env       env.Environment
+	arguments []ilos.Instance
+}
+
+func (*tailCall) Class() ilos.Class {
+	return class.Object
+}
+
+func (*tailCall) String() string {
+	return "#<TAIL-CALL>"
+}
+
+// force makes the tail calls returned by an evaluation until it has a value.
+func force(ret, err ilos.Instance) (ilos.Instance, ilos.Instance) {
+	for err == nil {
+		t, ok := ret.(*tailCall)
+		if !ok {
+			break
+		}
+		ret, err = t.function.call(t.env, t.arguments...)
+	}
+	return ret, err
+}
+
+// tailSafe reports whether the last of forms can be evaluated in tail
+// position of a block. It is not if forms may return from the block: a
+// closure made in the block could do so after the tail call has left it. Any
+// macro call is assumed to expand to return-from.
+func tailSafe(e env.Environment, forms ...ilos.Instance) bool {
+	for _, form := range forms {
+		cons, ok := form.(*instance.Cons)
+		if !ok {
+			continue
+		}
+		if cons.Car == instance.NewSymbol("RETURN-FROM") {
+			return false
+		}
+		if _, ok := e.Macro.Get(cons.Car); ok {
+			return false
+		}
+		if !tailSafe(e, cons.Car, cons.Cdr) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,73 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package runtime
+
+import (
+	"runtime/debug"
+	"testing"
+)
+
+func TestTailCall(t *testing.T) {
+	// Without tail calls, every call below takes a few kilobytes of Go stack.
+	defer debug.SetMaxStack(debug.SetMaxStack(4 << 20))
+	tests := []test{
+		{
+			exp: `
+			(defun count-down (n)
+			  (if (= n 0)
+			      'done
+			      (count-down (- n 1))))`,
+			want:    `'count-down`,
+			wantErr: false,
+		},
+		{
+			exp:     `(count-down 10000)`,
+			want:    `'done`,
+			wantErr: false,
+		},
+		{
+			exp: `
+			(defun sum (n acc)
+			  (cond ((= n 0) acc)
+			        (t (let ((m (- n 1)))
+			             (progn (block 'b (sum m (+ acc n))))))))`,
+			want:    `'sum`,
+			wantErr: false,
+		},
+		{
+			exp:     `(sum 10000 0)`,
+			want:    `50005000`,
+			wantErr: false,
+		},
+		{
+			exp: `
+			(defun ping (n) (if (= n 0) 'ping (pong (- n 1))))
+			`,
+			want:    `'ping`,
+			wantErr: false,
+		},
+		{
+			exp:     `(defun pong (n) (if (= n 0) 'pong (ping (- n 1))))`,
+			want:    `'pong`,
+			wantErr: false,
+		},
+		{
+			exp:     `(ping 10001)`,
+			want:    `'pong`,
+			wantErr: false,
+		},
+		{
+			exp:     `(block 'b (funcall (lambda () (return-from 'b 1))) 2)`,
+			want:    `1`,
+			wantErr: false,
+		},
+		{
+			exp:     `(block 'b ((lambda (f) (funcall f)) (lambda () (return-from 'b 1))))`,
+			want:    `1`,
+			wantErr: false,
+		},
+	}
+	execTests(t, Eval, tests)
+}
@@ -20,6 +20,27 @@ type test struct {
 
 func execTests(t *testing.T, function interface{}, tests []test) {
 	name := runtime.FuncForPC(reflect.ValueOf(function).Pointer()).Name()
+	eachEvaluator(t, func(t *testing.T, interpreter *Interpreter) {
+		execBackendTests(t, name, interpreter, tests)
+	})
+}
+
+// eachEvaluator runs f as a subtest with an interpreter for Eval and for each
+// backend.
+func eachEvaluator(t *testing.T, f func(t *testing.T, interpreter *Interpreter)) {
+	t.Run("Eval", func(t *testing.T) {
+		interpreter := New(Options{})
+		interpreter.eval = Eval
+		f(t, interpreter)
+	})
+	for _, backend := range []Backend{BackendCompiler, BackendVM} {
+		t.Run(backend.String(), func(t *testing.T) {
+			f(t, New(Options{Backend: backend}))
+		})
+	}
+}
+
+func execBackendTests(t *testing.T, name string, interpreter *Interpreter, tests []test) {
 	re := regexp.MustCompile(`\s+`)
 	for _, tt := range tests {
 		t.Run(re.ReplaceAllString(tt.exp, " "), func(t *testing.T) {
@@ -28,13 +49,13 @@ func execTests(t *testing.T, function interface{}, tests []test) {
 				t.Errorf("ParseError %v, want %v", err1, tt.exp)
 				return
 			}
-			got, err := Eval(TopLevel, obj)
+			got, err := interpreter.Eval(obj)
 			wantObj, err1 := readFromString(tt.want)
 			if err1 != nil {
 				t.Errorf("ParseError %v, want %v", err1, tt.want)
 				return
 			}
-			want, _ := Eval(TopLevel, wantObj)
+			want, _ := interpreter.Eval(wantObj)
 			if !tt.wantErr && !reflect.DeepEqual(got, want) {
 				t.Errorf("%v() got = %v, want %v", name, got, want)
 			}
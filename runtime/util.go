@@ -5,10 +5,13 @@
 package runtime
 
 import (
+	"math"
+	"math/big"
 	"reflect"
 	"regexp"
 	"runtime"
 	"strings"
+	"sync/atomic"
 
 	"github.com/ta2gch/iris/reader/parser"
 	"github.com/ta2gch/iris/reader/tokenizer"
@@ -30,8 +33,8 @@ func isProperList(i ilos.Instance) bool {
 
 func convFloat64(e env.Environment, x ilos.Instance) (float64, bool, ilos.Instance) {
 	switch {
-	case ilos.InstanceOf(class.Integer, x):
-		return float64(x.(instance.Integer)), false, nil
+	case ilos.InstanceOf(class.Integer, x), ilos.InstanceOf(class.Ratio, x):
+		return toFloat64(x), false, nil
 	case ilos.InstanceOf(class.Float, x):
 		return float64(x.(instance.Float)), true, nil
 	default:
@@ -40,10 +43,59 @@ func convFloat64(e env.Environment, x ilos.Instance) (float64, bool, ilos.Instan
 	}
 }
 
+// toFloat64 returns the number x as a float64. A bignum or ratio too large for
+// one becomes an infinity.
+func toFloat64(x ilos.Instance) float64 {
+	switch x := x.(type) {
+	case instance.Integer:
+		return float64(x)
+	case instance.Bignum:
+		f, _ := new(big.Float).SetInt(x.Int()).Float64()
+		return f
+	case instance.Ratio:
+		f, _ := x.Rat().Float64()
+		return f
+	}
+	return float64(x.(instance.Float))
+}
+
+// outOfRange reports whether f is an infinity, zero or a denormal, as a
+// float64 made from a rational too large or too small for one is.
+func outOfRange(f float64) bool {
+	return math.IsInf(f, 0) || math.Abs(f) < minNormalFloat
+}
+
+// scaled splits the positive rational q into m and k with q = m * 2^k and m
+// near 1, so that a float function of q can be computed from m when q itself
+// is out of the range of float64.
+func scaled(q *big.Rat) (float64, int) {
+	k := q.Num().BitLen() - q.Denom().BitLen()
+	num, den := new(big.Int).Set(q.Num()), new(big.Int).Set(q.Denom())
+	if k > 0 {
+		den.Lsh(den, uint(k))
+	} else {
+		num.Lsh(num, uint(-k))
+	}
+	m, _ := new(big.Rat).SetFrac(num, den).Float64()
+	return m, k
+}
+
 func readFromString(s string) (ilos.Instance, ilos.Instance) {
 	return parser.Parse(tokenizer.NewReader(strings.NewReader(s)))
 }
 
+// ensureFixnum is ensure for integers that are used as Go ints, such as
+// indices and lengths. A bignum never fits and signals a domain error.
+func ensureFixnum(e env.Environment, i ...ilos.Instance) ilos.Instance {
+	for _, o := range i {
+		if _, ok := o.(instance.Integer); !ok {
+			_, err := SignalCondition(e, instance.NewDomainError(e, o, class.Integer), Nil)
+			return err
+		}
+	}
+	return nil
+}
+
 func ensure(e env.Environment, c ilos.Class, i ...ilos.Instance) ilos.Instance {
 	for _, o := range i {
 		if !ilos.InstanceOf(c, o) {
@@ -54,12 +106,11 @@ func ensure(e env.Environment, c ilos.Class, i ...ilos.Instance) ilos.Instance {
 	return nil
 }
 
-var unique = 0
+var unique int64
 
+// uniqueInt is shared by every interpreter in the process.
 func uniqueInt() int {
-	i := unique
-	unique++
-	return i
+	return int(atomic.AddInt64(&unique, 1) - 1)
 }
 
 func func2symbol(function interface{}) ilos.Instance {
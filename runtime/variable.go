@@ -58,7 +58,7 @@ func Setf(e env.Environment, var1, form ilos.Instance) (ilos.Instance, ilos.Inst
 // body (or nil if there is none). No var may appear more than once in let
 // variable list.
 func Let(e env.Environment, varForm ilos.Instance, bodyForm ...ilos.Instance) (ilos.Instance, ilos.Instance) {
-	vfs := map[ilos.Instance]ilos.Instance{}
+	vs, fs := []ilos.Instance{}, []ilos.Instance{}
 	if err := ensure(e, class.List, varForm); err != nil {
 		return nil, err
 	}
@@ -73,14 +73,14 @@ func Let(e env.Environment, varForm ilos.Instance, bodyForm ...ilos.Instance) (i
 		if err != nil {
 			return nil, err
 		}
-		vfs[cadr.(instance.List).Nth(0)] = f
+		vs, fs = append(vs, cadr.(instance.List).Nth(0)), append(fs, f)
 	}
-	for v, f := range vfs {
-		if !e.Variable.Define(v, f) {
+	for i, v := range vs {
+		if !e.Variable.Define(v, fs[i]) {
 			return SignalCondition(e, instance.NewImmutableBinding(e), Nil)
 		}
 	}
-	return Progn(e, bodyForm...)
+	return progn(e, bodyForm...)
 }
 
 // LetStar form is used to define a scope for a group of identifiers for a
@@ -115,5 +115,5 @@ func LetStar(e env.Environment, varForm ilos.Instance, bodyForm ...ilos.Instance
 			return SignalCondition(e, instance.NewImmutableBinding(e), Nil)
 		}
 	}
-	return Progn(e, bodyForm...)
+	return progn(e, bodyForm...)
 }
@@ -36,7 +36,7 @@ func GeneralVectorP(e env.Environment, obj ilos.Instance) (ilos.Instance, ilos.I
 // cannot-create-vector). An error shall be signaled if i is not a non-negative
 // integer (error-id. domain-error). initial-element may be any ISLISP object.
 func CreateVector(e env.Environment, i ilos.Instance, initialElement ...ilos.Instance) (ilos.Instance, ilos.Instance) {
-	if !ilos.InstanceOf(class.Integer, i) || int(i.(instance.Integer)) < 0 {
+	if n, ok := i.(instance.Integer); !ok || n < 0 {
 		return SignalCondition(e, instance.NewDomainError(e, i, class.Integer), Nil)
 	}
 	if len(initialElement) > 1 {
@@ -0,0 +1,99 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package vm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ta2gch/iris/runtime/ilos"
+)
+
+// Op is an operation of the machine. The machine has a stack of values per
+// call; operations take their operands from the top of the stack and push
+// their results there.
+type Op uint8
+
+const (
+	Const        Op = iota // push Constants[A]
+	Variable               // push the value of the variable Constants[A]
+	SetVariable            // set the variable Constants[A] to the top of the stack
+	Dynamic                // push the value of the dynamic variable Constants[A]
+	Pop                    // discard the top of the stack
+	Jump                   // continue at A
+	JumpIfNil              // pop a value and continue at A if it is nil
+	JumpIfNotNil           // continue at A if the top of the stack is not nil, or else pop it
+	PushScope              // enter a new lexical scope binding the top A values
+	PopScope               // leave the innermost lexical scope
+	Bind                   // pop a value and bind the variable Constants[A] to it, or leave the scope at B
+	BindDynamic            // pop a value and bind the dynamic variable Constants[A] to it, or leave the scope at B
+	BindFunction           // pop a function and bind the function name Constants[A] to it, or leave the scope at B
+	Defun                  // pop a function and define Constants[A] globally as it
+	Function               // push the function named Constants[A]
+	Callee                 // push the function called by the form Constants[A], or evaluate the form and continue at B if it calls a macro
+	Macro                  // expand and evaluate the macro form Constants[A]
+	Closure                // push a closure of Codes[A] over the current environment
+	Call                   // call the function below the A arguments on the stack
+	TailCall               // call like Call and return the value of the call
+	Return                 // return the top of the stack
+	Special                // evaluate the special form Constants[A] with the forms Constants[B]
+	Block                  // pop a tag and establish a block which ends at A
+	ReturnFrom             // pop a value and a tag and return the value from the block
+	Catch                  // pop a tag and establish a catch which ends at A
+	Throw                  // pop a value and a tag and throw the value to the catch
+	Tagbody                // establish a tagbody whose tags are at Tags[A]
+	Go                     // transfer control to the tag Constants[A]
+	Protect                // establish Codes[A] as cleanup forms of the code up to the next PopHandler, continuing at B
+	Cleanup                // evaluate the cleanup forms Codes[A]
+	PopHandler             // disestablish the innermost block, catch, tagbody or protect
+)
+
+var names = [...]string{
+	"CONST", "VARIABLE", "SET-VARIABLE", "DYNAMIC", "POP", "JUMP", "JUMP-IF-NIL",
+	"JUMP-IF-NOT-NIL", "PUSH-SCOPE", "POP-SCOPE", "BIND", "BIND-DYNAMIC",
+	"BIND-FUNCTION", "DEFUN", "FUNCTION", "CALLEE", "MACRO", "CLOSURE", "CALL",
+	"TAIL-CALL", "RETURN", "SPECIAL", "BLOCK", "RETURN-FROM", "CATCH", "THROW",
+	"TAGBODY", "GO", "PROTECT", "CLEANUP", "POP-HANDLER",
+}
+
+func (op Op) String() string {
+	return names[op]
+}
+
+// Instruction is an operation and its operands.
+type Instruction struct {
+	Op   Op
+	A, B int
+}
+
+// Code is a compiled function or top level form.
+type Code struct {
+	Name         ilos.Instance
+	Parameters   []ilos.Instance
+	Variadic     bool
+	Instructions []Instruction
+	Forms        []ilos.Instance // the innermost form each instruction is compiled from
+	Constants    []ilos.Instance
+	Codes        []*Code
+	Tags         []map[ilos.Instance]int
+}
+
+// String disassembles c and the codes in it.
+func (c *Code) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%v %v\n", c.Name, c.Parameters)
+	for pc, in := range c.Instructions {
+		fmt.Fprintf(&b, "%4d %-16v %d %d", pc, in.Op, in.A, in.B)
+		switch in.Op {
+		case Const, Variable, SetVariable, Dynamic, Bind, BindDynamic, BindFunction, Defun, Function, Callee, Macro, Go:
+			fmt.Fprintf(&b, "\t; %v", c.Constants[in.A])
+		}
+		b.WriteString("\n")
+	}
+	for _, d := range c.Codes {
+		b.WriteString(d.String())
+	}
+	return b.String()
+}
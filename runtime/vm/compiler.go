@@ -0,0 +1,576 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package vm
+
+import (
+	"github.com/ta2gch/iris/runtime/env"
+	"github.com/ta2gch/iris/runtime/ilos"
+	"github.com/ta2gch/iris/runtime/ilos/class"
+	"github.com/ta2gch/iris/runtime/ilos/instance"
+)
+
+type compiler struct {
+	machine *Machine
+	env     env.Environment // for the macros and special forms
+	code    *Code
+}
+
+// compilers compile the special forms the machine implements itself. The
+// others are evaluated by the runtime. A compiler returns false if the form
+// is malformed, so that the runtime signals the error.
+var compilers map[ilos.Instance]func(c *compiler, arguments []ilos.Instance, tail bool) bool
+
+func init() {
+	compilers = map[ilos.Instance]func(*compiler, []ilos.Instance, bool) bool{
+		instance.NewSymbol("QUOTE"):          (*compiler).compileQuote,
+		instance.NewSymbol("IF"):             (*compiler).compileIf,
+		instance.NewSymbol("COND"):           (*compiler).compileCond,
+		instance.NewSymbol("PROGN"):          (*compiler).compileProgn,
+		instance.NewSymbol("AND"):            (*compiler).compileAnd,
+		instance.NewSymbol("OR"):             (*compiler).compileOr,
+		instance.NewSymbol("WHILE"):          (*compiler).compileWhile,
+		instance.NewSymbol("LET"):            (*compiler).compileLet,
+		instance.NewSymbol("LET*"):           (*compiler).compileLetStar,
+		instance.NewSymbol("SETQ"):           (*compiler).compileSetq,
+		instance.NewSymbol("LAMBDA"):         (*compiler).compileLambda,
+		instance.NewSymbol("FUNCTION"):       (*compiler).compileFunction,
+		instance.NewSymbol("DEFUN"):          (*compiler).compileDefun,
+		instance.NewSymbol("FLET"):           (*compiler).compileFlet,
+		instance.NewSymbol("LABELS"):         (*compiler).compileLabels,
+		instance.NewSymbol("BLOCK"):          (*compiler).compileBlock,
+		instance.NewSymbol("RETURN-FROM"):    (*compiler).compileReturnFrom,
+		instance.NewSymbol("CATCH"):          (*compiler).compileCatch,
+		instance.NewSymbol("THROW"):          (*compiler).compileThrow,
+		instance.NewSymbol("TAGBODY"):        (*compiler).compileTagbody,
+		instance.NewSymbol("GO"):             (*compiler).compileGo,
+		instance.NewSymbol("UNWIND-PROTECT"): (*compiler).compileUnwindProtect,
+		instance.NewSymbol("DYNAMIC-LET"):    (*compiler).compileDynamicLet,
+		instance.NewSymbol("DYNAMIC"):        (*compiler).compileDynamic,
+	}
+}
+
+// Compile compiles obj to code that evaluates it. Macro calls are expanded
+// with the macros defined in e at compile time.
+func (m *Machine) Compile(e env.Environment, obj ilos.Instance) *Code {
+	c := &compiler{m, e, &Code{Name: instance.NewSymbol("TOP-LEVEL")}}
+	c.compile(obj, true)
+	c.emit(Return, 0, 0)
+	return c.code
+}
+
+func (c *compiler) emit(op Op, a, b int) int {
+	c.code.Instructions = append(c.code.Instructions, Instruction{op, a, b})
+	c.code.Forms = append(c.code.Forms, nil)
+	return len(c.code.Instructions) - 1
+}
+
+// patch sets the operand A of the instruction at pc to the next pc.
+func (c *compiler) patch(pc int) {
+	c.code.Instructions[pc].A = len(c.code.Instructions)
+}
+
+// patchScope sets the operand B of the bindings at pcs to the next pc, where
+// their scope ends.
+func (c *compiler) patchScope(pcs []int) {
+	for _, pc := range pcs {
+		c.code.Instructions[pc].B = len(c.code.Instructions)
+	}
+}
+
+func (c *compiler) constant(obj ilos.Instance) int {
+	c.code.Constants = append(c.code.Constants, obj)
+	return len(c.code.Constants) - 1
+}
+
+// compile emits code which pushes the value of obj. A call in tail position
+// is compiled to a tail call.
+func (c *compiler) compile(obj ilos.Instance, tail bool) {
+	switch {
+	case obj == instance.Nil:
+		c.emit(Const, c.constant(obj), 0)
+	case ilos.InstanceOf(class.Symbol, obj):
+		c.emit(Variable, c.constant(obj), 0)
+	case ilos.InstanceOf(class.Cons, obj):
+		start := len(c.code.Instructions)
+		c.compileForm(obj.(*instance.Cons), tail)
+		for pc := start; pc < len(c.code.Instructions); pc++ {
+			if c.code.Forms[pc] == nil {
+				c.code.Forms[pc] = obj
+			}
+		}
+	default:
+		c.emit(Const, c.constant(obj), 0)
+	}
+}
+
+func (c *compiler) compileBody(forms []ilos.Instance, tail bool) {
+	if len(forms) == 0 {
+		c.emit(Const, c.constant(instance.Nil), 0)
+		return
+	}
+	for i, form := range forms {
+		if i > 0 {
+			c.emit(Pop, 0, 0)
+		}
+		c.compile(form, tail && i == len(forms)-1)
+	}
+}
+
+func (c *compiler) compileForm(form *instance.Cons, tail bool) {
+	arguments, ok := instance.ProperList(form.Cdr)
+	if !ok {
+		c.compileCall(form, nil, tail)
+		return
+	}
+	if spl, ok := c.env.Special.Get(form.Car); ok {
+		if compiler, ok := compilers[form.Car]; ok && compiler(c, arguments, tail) {
+			return
+		}
+		c.emit(Special, c.constant(spl), c.constant(form.Cdr))
+		return
+	}
+	if mac, ok := c.env.Macro.Get(form.Car); ok {
+		expansion, err := mac.(instance.Applicable).Apply(c.env.NewDynamic(), arguments...)
+		if err != nil {
+			c.emit(Macro, c.constant(form), 0)
+			return
+		}
+		c.compile(expansion, tail)
+		return
+	}
+	c.compileCall(form, arguments, tail)
+}
+
+func (c *compiler) compileCall(form *instance.Cons, arguments []ilos.Instance, tail bool) {
+	callee := -1
+	if f, ok := form.Car.(*instance.Cons); ok && f.Car == instance.NewSymbol("LAMBDA") {
+		c.compile(f, false)
+	} else {
+		callee = c.emit(Callee, c.constant(form), 0)
+	}
+	for _, argument := range arguments {
+		c.compile(argument, false)
+	}
+	if tail {
+		c.emit(TailCall, len(arguments), 0)
+	} else {
+		c.emit(Call, len(arguments), 0)
+	}
+	if callee >= 0 {
+		c.code.Instructions[callee].B = len(c.code.Instructions)
+	}
+}
+
+func (c *compiler) compileQuote(arguments []ilos.Instance, tail bool) bool {
+	if len(arguments) != 1 {
+		return false
+	}
+	c.emit(Const, c.constant(arguments[0]), 0)
+	return true
+}
+
+func (c *compiler) compileIf(arguments []ilos.Instance, tail bool) bool {
+	if len(arguments) < 2 || len(arguments) > 3 {
+		return false
+	}
+	c.compile(arguments[0], false)
+	otherwise := c.emit(JumpIfNil, 0, 0)
+	c.compile(arguments[1], tail)
+	end := c.emit(Jump, 0, 0)
+	c.patch(otherwise)
+	c.compileBody(arguments[2:], tail)
+	c.patch(end)
+	return true
+}
+
+func (c *compiler) compileCond(arguments []ilos.Instance, tail bool) bool {
+	clauses := [][]ilos.Instance{}
+	for _, argument := range arguments {
+		clause, ok := instance.ProperList(argument)
+		if !ok || len(clause) == 0 {
+			return false
+		}
+		clauses = append(clauses, clause)
+	}
+	ends := []int{}
+	for _, clause := range clauses {
+		c.compile(clause[0], false)
+		next := c.emit(JumpIfNil, 0, 0)
+		c.compileBody(clause[1:], tail)
+		ends = append(ends, c.emit(Jump, 0, 0))
+		c.patch(next)
+	}
+	c.emit(Const, c.constant(instance.Nil), 0)
+	for _, end := range ends {
+		c.patch(end)
+	}
+	return true
+}
+
+func (c *compiler) compileProgn(arguments []ilos.Instance, tail bool) bool {
+	c.compileBody(arguments, tail)
+	return true
+}
+
+func (c *compiler) compileAnd(arguments []ilos.Instance, tail bool) bool {
+	if len(arguments) == 0 {
+		c.emit(Const, c.constant(instance.T), 0)
+		return true
+	}
+	jumps := []int{}
+	for _, argument := range arguments[:len(arguments)-1] {
+		c.compile(argument, false)
+		jumps = append(jumps, c.emit(JumpIfNil, 0, 0))
+	}
+	c.compile(arguments[len(arguments)-1], false)
+	end := c.emit(Jump, 0, 0)
+	for _, jump := range jumps {
+		c.patch(jump)
+	}
+	c.emit(Const, c.constant(instance.Nil), 0)
+	c.patch(end)
+	return true
+}
+
+func (c *compiler) compileOr(arguments []ilos.Instance, tail bool) bool {
+	if len(arguments) == 0 {
+		c.emit(Const, c.constant(instance.Nil), 0)
+		return true
+	}
+	jumps := []int{}
+	for _, argument := range arguments[:len(arguments)-1] {
+		c.compile(argument, false)
+		jumps = append(jumps, c.emit(JumpIfNotNil, 0, 0))
+	}
+	c.compile(arguments[len(arguments)-1], false)
+	for _, jump := range jumps {
+		c.patch(jump)
+	}
+	return true
+}
+
+func (c *compiler) compileWhile(arguments []ilos.Instance, tail bool) bool {
+	if len(arguments) < 1 {
+		return false
+	}
+	start := len(c.code.Instructions)
+	c.compile(arguments[0], false)
+	end := c.emit(JumpIfNil, 0, 0)
+	c.compileBody(arguments[1:], false)
+	c.emit(Pop, 0, 0)
+	c.emit(Jump, start, 0)
+	c.patch(end)
+	c.emit(Const, c.constant(instance.Nil), 0)
+	return true
+}
+
+func (c *compiler) compileLet(arguments []ilos.Instance, tail bool) bool {
+	if len(arguments) < 1 {
+		return false
+	}
+	variables, forms, ok := instance.Bindings(arguments[0])
+	if !ok {
+		return false
+	}
+	for _, form := range forms {
+		c.compile(form, false)
+	}
+	c.emit(PushScope, len(variables), 0)
+	binds := []int{}
+	for i := len(variables) - 1; i >= 0; i-- {
+		binds = append(binds, c.emit(Bind, c.constant(variables[i]), 0))
+	}
+	c.compileBody(arguments[1:], tail)
+	c.patchScope(binds)
+	c.emit(PopScope, 0, 0)
+	return true
+}
+
+func (c *compiler) compileLetStar(arguments []ilos.Instance, tail bool) bool {
+	if len(arguments) < 1 {
+		return false
+	}
+	variables, forms, ok := instance.Bindings(arguments[0])
+	if !ok {
+		return false
+	}
+	c.emit(PushScope, 0, 0)
+	binds := []int{}
+	for i, form := range forms {
+		c.compile(form, false)
+		binds = append(binds, c.emit(Bind, c.constant(variables[i]), 0))
+	}
+	c.compileBody(arguments[1:], tail)
+	c.patchScope(binds)
+	c.emit(PopScope, 0, 0)
+	return true
+}
+
+func (c *compiler) compileSetq(arguments []ilos.Instance, tail bool) bool {
+	if len(arguments) != 2 || !ilos.InstanceOf(class.Symbol, arguments[0]) {
+		return false
+	}
+	c.compile(arguments[1], false)
+	c.emit(SetVariable, c.constant(arguments[0]), 0)
+	return true
+}
+
+// compileFunctionCode compiles the function named name to a new code in
+// c.code.Codes and returns its index, or false if lambdaList is malformed.
+func (c *compiler) compileFunctionCode(name, lambdaList ilos.Instance, forms []ilos.Instance) (int, bool) {
+	parameters, variadic, ok := instance.ParseLambdaList(lambdaList)
+	if !ok {
+		return 0, false
+	}
+	d := &compiler{c.machine, c.env, &Code{Name: name, Parameters: parameters, Variadic: variadic}}
+	d.compileBody(forms, true)
+	d.emit(Return, 0, 0)
+	c.code.Codes = append(c.code.Codes, d.code)
+	return len(c.code.Codes) - 1, true
+}
+
+func (c *compiler) compileLambda(arguments []ilos.Instance, tail bool) bool {
+	if len(arguments) < 1 {
+		return false
+	}
+	i, ok := c.compileFunctionCode(instance.NewSymbol("ANONYMOUS-FUNCTION"), arguments[0], arguments[1:])
+	if !ok {
+		return false
+	}
+	c.emit(Closure, i, 0)
+	return true
+}
+
+func (c *compiler) compileFunction(arguments []ilos.Instance, tail bool) bool {
+	if len(arguments) != 1 || !ilos.InstanceOf(class.Symbol, arguments[0]) {
+		return false
+	}
+	c.emit(Function, c.constant(arguments[0]), 0)
+	return true
+}
+
+func (c *compiler) compileDefun(arguments []ilos.Instance, tail bool) bool {
+	if len(arguments) < 2 || !ilos.InstanceOf(class.Symbol, arguments[0]) {
+		return false
+	}
+	i, ok := c.compileFunctionCode(arguments[0], arguments[1], arguments[2:])
+	if !ok {
+		return false
+	}
+	c.emit(Closure, i, 0)
+	c.emit(Defun, c.constant(arguments[0]), 0)
+	return true
+}
+
+// functions returns the function definitions of flet and labels.
+func functions(obj ilos.Instance) ([][]ilos.Instance, bool) {
+	list, ok := instance.ProperList(obj)
+	if !ok {
+		return nil, false
+	}
+	definitions := [][]ilos.Instance{}
+	for _, cadr := range list {
+		definition, ok := instance.ProperList(cadr)
+		if !ok || len(definition) < 2 || !ilos.InstanceOf(class.Symbol, definition[0]) {
+			return nil, false
+		}
+		if _, _, ok := instance.ParseLambdaList(definition[1]); !ok {
+			return nil, false
+		}
+		definitions = append(definitions, definition)
+	}
+	return definitions, true
+}
+
+func (c *compiler) compileFlet(arguments []ilos.Instance, tail bool) bool {
+	if len(arguments) < 1 {
+		return false
+	}
+	definitions, ok := functions(arguments[0])
+	if !ok {
+		return false
+	}
+	for _, definition := range definitions {
+		i, _ := c.compileFunctionCode(definition[0], definition[1], definition[2:])
+		c.emit(Closure, i, 0)
+	}
+	c.emit(PushScope, len(definitions), 0)
+	binds := []int{}
+	for i := len(definitions) - 1; i >= 0; i-- {
+		binds = append(binds, c.emit(BindFunction, c.constant(definitions[i][0]), 0))
+	}
+	c.compileBody(arguments[1:], tail)
+	c.patchScope(binds)
+	c.emit(PopScope, 0, 0)
+	return true
+}
+
+func (c *compiler) compileLabels(arguments []ilos.Instance, tail bool) bool {
+	if len(arguments) < 1 {
+		return false
+	}
+	definitions, ok := functions(arguments[0])
+	if !ok {
+		return false
+	}
+	c.emit(PushScope, 0, 0)
+	binds := []int{}
+	for _, definition := range definitions {
+		i, _ := c.compileFunctionCode(definition[0], definition[1], definition[2:])
+		c.emit(Closure, i, 0)
+		binds = append(binds, c.emit(BindFunction, c.constant(definition[0]), 0))
+	}
+	c.compileBody(arguments[1:], tail)
+	c.patchScope(binds)
+	c.emit(PopScope, 0, 0)
+	return true
+}
+
+// compileHandler compiles the body of a block or catch established by op.
+// The body of a block is in tail position if nothing in it can return from
+// the block, for a tail call drops the handlers of the frame.
+func (c *compiler) compileHandler(op Op, arguments []ilos.Instance, tail bool) bool {
+	if len(arguments) < 1 {
+		return false
+	}
+	c.compile(arguments[0], false)
+	c.emit(PushScope, 0, 0)
+	handler := c.emit(op, 0, 0)
+	c.compileBody(arguments[1:], tail && op == Block && c.tailSafe(arguments[1:]...))
+	c.emit(PopHandler, 0, 0)
+	c.patch(handler)
+	c.emit(PopScope, 0, 0)
+	return true
+}
+
+func (c *compiler) compileBlock(arguments []ilos.Instance, tail bool) bool {
+	return c.compileHandler(Block, arguments, tail)
+}
+
+// tailSafe reports whether forms contain no return-from. Any macro call is
+// assumed to expand to return-from.
+func (c *compiler) tailSafe(forms ...ilos.Instance) bool {
+	for _, form := range forms {
+		cons, ok := form.(*instance.Cons)
+		if !ok {
+			continue
+		}
+		if cons.Car == instance.NewSymbol("RETURN-FROM") {
+			return false
+		}
+		if _, ok := c.env.Macro.Get(cons.Car); ok {
+			return false
+		}
+		if !c.tailSafe(cons.Car, cons.Cdr) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *compiler) compileCatch(arguments []ilos.Instance, tail bool) bool {
+	return c.compileHandler(Catch, arguments, tail)
+}
+
+func (c *compiler) compileReturnFrom(arguments []ilos.Instance, tail bool) bool {
+	if len(arguments) != 2 {
+		return false
+	}
+	c.compile(arguments[0], false)
+	c.compile(arguments[1], false)
+	c.emit(ReturnFrom, 0, 0)
+	return true
+}
+
+func (c *compiler) compileThrow(arguments []ilos.Instance, tail bool) bool {
+	if len(arguments) != 2 {
+		return false
+	}
+	c.compile(arguments[0], false)
+	c.compile(arguments[1], false)
+	c.emit(Throw, 0, 0)
+	return true
+}
+
+func (c *compiler) compileTagbody(arguments []ilos.Instance, tail bool) bool {
+	tags := map[ilos.Instance]int{}
+	for _, argument := range arguments {
+		if _, ok := tags[argument]; ok {
+			return false // The special form signals the repeated tag
+		}
+		if !ilos.InstanceOf(class.Cons, argument) {
+			tags[argument] = 0
+		}
+	}
+	c.code.Tags = append(c.code.Tags, tags)
+	c.emit(PushScope, 0, 0)
+	c.emit(Tagbody, len(c.code.Tags)-1, 0)
+	for _, argument := range arguments {
+		if ilos.InstanceOf(class.Cons, argument) {
+			c.compile(argument, false)
+			c.emit(Pop, 0, 0)
+		} else {
+			tags[argument] = len(c.code.Instructions)
+		}
+	}
+	c.emit(PopHandler, 0, 0)
+	c.emit(PopScope, 0, 0)
+	c.emit(Const, c.constant(instance.Nil), 0)
+	return true
+}
+
+func (c *compiler) compileGo(arguments []ilos.Instance, tail bool) bool {
+	if len(arguments) != 1 {
+		return false
+	}
+	c.emit(Go, c.constant(arguments[0]), 0)
+	return true
+}
+
+func (c *compiler) compileUnwindProtect(arguments []ilos.Instance, tail bool) bool {
+	if len(arguments) < 1 {
+		return false
+	}
+	d := &compiler{c.machine, c.env, &Code{Name: instance.NewSymbol("UNWIND-PROTECT")}}
+	d.compileBody(arguments[1:], false)
+	d.emit(Return, 0, 0)
+	c.code.Codes = append(c.code.Codes, d.code)
+	protect := c.emit(Protect, len(c.code.Codes)-1, 0)
+	c.compile(arguments[0], false)
+	c.emit(PopHandler, 0, 0)
+	c.emit(Cleanup, len(c.code.Codes)-1, 0)
+	c.code.Instructions[protect].B = len(c.code.Instructions)
+	return true
+}
+
+func (c *compiler) compileDynamicLet(arguments []ilos.Instance, tail bool) bool {
+	if len(arguments) < 1 {
+		return false
+	}
+	variables, forms, ok := instance.Bindings(arguments[0])
+	if !ok {
+		return false
+	}
+	for _, form := range forms {
+		c.compile(form, false)
+	}
+	c.emit(PushScope, len(variables), 0)
+	binds := []int{}
+	for i := len(variables) - 1; i >= 0; i-- {
+		binds = append(binds, c.emit(BindDynamic, c.constant(variables[i]), 0))
+	}
+	c.compileBody(arguments[1:], tail)
+	c.patchScope(binds)
+	c.emit(PopScope, 0, 0)
+	return true
+}
+
+func (c *compiler) compileDynamic(arguments []ilos.Instance, tail bool) bool {
+	if len(arguments) != 1 || !ilos.InstanceOf(class.Symbol, arguments[0]) {
+		return false
+	}
+	c.emit(Dynamic, c.constant(arguments[0]), 0)
+	return true
+}
@@ -0,0 +1,545 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+// Package vm implements a virtual machine that evaluates ISLisp forms
+// compiled to bytecode. The machine implements the special forms for control
+// flow and binding itself and leaves the others to the runtime, which it
+// reaches through the hooks of Runtime.
+package vm
+
+import (
+	"fmt"
+
+	"github.com/ta2gch/iris/runtime/env"
+	"github.com/ta2gch/iris/runtime/ilos"
+	"github.com/ta2gch/iris/runtime/ilos/class"
+	"github.com/ta2gch/iris/runtime/ilos/instance"
+)
+
+// Runtime is what the machine needs from the runtime.
+type Runtime struct {
+	// Signal signals condition like signal-condition.
+	Signal func(e env.Environment, condition, continuable ilos.Instance) (ilos.Instance, ilos.Instance)
+	// Special evaluates a special form the machine does not implement.
+	Special func(e env.Environment, special ilos.Instance, forms ...ilos.Instance) (ilos.Instance, ilos.Instance)
+	// Check counts one step of the evaluation and returns a condition if the
+	// evaluation is to be aborted.
+	Check func(e env.Environment) ilos.Instance
+	// Unique returns a number no other call has returned, to tell the
+	// activations of a block, catch or tagbody apart.
+	Unique func() int
+	// Locate records the source location of form in the condition err and
+	// returns err.
+	Locate func(form, err ilos.Instance) ilos.Instance
+}
+
+// Machine evaluates compiled code.
+type Machine struct {
+	runtime Runtime
+}
+
+// New creates a machine on top of r.
+func New(r Runtime) *Machine {
+	return &Machine{r}
+}
+
+// Eval compiles obj and evaluates it in e.
+func (m *Machine) Eval(e env.Environment, obj ilos.Instance) (ilos.Instance, ilos.Instance) {
+	return m.Run(e, m.Compile(e, obj))
+}
+
+// Run evaluates code in e.
+func (m *Machine) Run(e env.Environment, code *Code) (ilos.Instance, ilos.Instance) {
+	t := &thread{machine: m}
+	t.frames = []*frame{{code: code, env: e}}
+	return t.run()
+}
+
+// closure is a function compiled for the machine.
+type closure struct {
+	machine *Machine
+	code    *Code
+	lexical env.Environment
+}
+
+func (*closure) Class() ilos.Class {
+	return class.Function
+}
+
+func (c *closure) String() string {
+	return fmt.Sprintf("#%v", c.Class())
+}
+
+// Apply calls c with arguments and returns its value.
+func (c *closure) Apply(e env.Environment, arguments ...ilos.Instance) (ilos.Instance, ilos.Instance) {
+	t := &thread{machine: c.machine}
+	f, ret, err := t.enter(e, c, arguments)
+	if f == nil {
+		return ret, err
+	}
+	t.frames = []*frame{f}
+	return t.run()
+}
+
+// handler is a block, catch, tagbody or unwind-protect established by a
+// frame, with the state to restore when control is transferred to it.
+type handler struct {
+	op      Op
+	tag     ilos.Instance
+	uid     ilos.Instance
+	tags    map[ilos.Instance]int
+	end     int
+	cleanup *Code
+	env     env.Environment
+	scopes  int
+	stack   int
+}
+
+type frame struct {
+	code     *Code
+	pc       int
+	env      env.Environment
+	scopes   []scope
+	base     int // the stack index of the first value of the frame
+	handlers []handler
+}
+
+// scope is a lexical scope entered by a frame.
+type scope struct {
+	env   env.Environment // the environment outside of the scope
+	stack int             // the stack index of the first value the scope binds
+}
+
+type thread struct {
+	machine *Machine
+	stack   []ilos.Instance
+	frames  []*frame
+}
+
+func (t *thread) push(obj ilos.Instance) {
+	t.stack = append(t.stack, obj)
+}
+
+func (t *thread) pop() ilos.Instance {
+	obj := t.stack[len(t.stack)-1]
+	t.stack = t.stack[:len(t.stack)-1]
+	return obj
+}
+
+func (t *thread) signal(e env.Environment, condition ilos.Instance) (ilos.Instance, ilos.Instance) {
+	return t.machine.runtime.Signal(e, condition, instance.Nil)
+}
+
+// fail signals condition in the frame f. If a handler continues the
+// condition, its value is pushed in place of the value of the failed
+// operation.
+func (t *thread) fail(f *frame, condition ilos.Instance) ilos.Instance {
+	ret, err := t.signal(f.env, condition)
+	if err == nil {
+		t.push(ret)
+	}
+	return err
+}
+
+// rebind signals that a binding of the innermost scope of the frame f is
+// immutable. If a handler continues the condition, its value becomes the
+// value of the form of the scope, which ends at the pc end.
+func (t *thread) rebind(f *frame, end int) ilos.Instance {
+	ret, err := t.signal(f.env, instance.NewImmutableBinding(f.env))
+	if err != nil {
+		return err
+	}
+	t.stack = t.stack[:f.scopes[len(f.scopes)-1].stack]
+	t.push(ret)
+	f.pc = end
+	return nil
+}
+
+// enter binds the parameters of c to arguments in e and returns the frame of
+// the call. If the binding fails, enter returns no frame but the value or
+// error of the failure.
+func (t *thread) enter(e env.Environment, c *closure, arguments []ilos.Instance) (*frame, ilos.Instance, ilos.Instance) {
+	e.MergeLexical(c.lexical)
+	parameters := c.code.Parameters
+	if (c.code.Variadic && len(parameters)-2 > len(arguments)) || (!c.code.Variadic && len(parameters) != len(arguments)) {
+		ret, err := t.signal(e, instance.NewArityError(e))
+		return nil, ret, err
+	}
+	for i, key := range parameters {
+		if key == instance.NewSymbol(":REST") || key == instance.NewSymbol("&REST") {
+			value := ilos.Instance(instance.Nil)
+			for j := len(arguments) - 1; j >= i; j-- {
+				value = instance.NewCons(arguments[j], value)
+			}
+			if !e.Variable.Define(parameters[i+1], value) {
+				ret, err := t.signal(e, instance.NewImmutableBinding(e))
+				return nil, ret, err
+			}
+			break
+		}
+		if !e.Variable.Define(key, arguments[i]) {
+			ret, err := t.signal(e, instance.NewImmutableBinding(e))
+			return nil, ret, err
+		}
+	}
+	return &frame{code: c.code, env: e, base: len(t.stack)}, nil, nil
+}
+
+// escape returns the tag, uid and object of an escape condition.
+func escape(err ilos.Instance) (tag, uid, object ilos.Instance) {
+	tag, _ = err.(instance.Instance).GetSlotValue(instance.NewSymbol("IRIS.TAG"), class.Escape)
+	uid, _ = err.(instance.Instance).GetSlotValue(instance.NewSymbol("IRIS.UID"), class.Escape)
+	if !ilos.InstanceOf(class.TagbodyTag, err) {
+		object, _ = err.(instance.Instance).GetSlotValue(instance.NewSymbol("IRIS.OBJECT"), err.Class())
+	}
+	return tag, uid, object
+}
+
+func (t *thread) restore(f *frame, h handler) {
+	f.env = h.env
+	f.scopes = f.scopes[:h.scopes]
+	t.stack = t.stack[:h.stack]
+}
+
+// unwind transfers control to the handler of err, running the cleanup forms
+// of unwind-protect on the way, and returns nil. If there is no handler in the
+// thread, unwind pops every frame and returns the error to pass on.
+func (t *thread) unwind(err ilos.Instance) ilos.Instance {
+	for len(t.frames) > 0 {
+		f := t.frames[len(t.frames)-1]
+		for len(f.handlers) > 0 {
+			h := f.handlers[len(f.handlers)-1]
+			f.handlers = f.handlers[:len(f.handlers)-1]
+			switch h.op {
+			case Block, Catch:
+				if (h.op == Block && !ilos.InstanceOf(class.BlockTag, err)) || (h.op == Catch && !ilos.InstanceOf(class.CatchTag, err)) {
+					continue
+				}
+				tag, uid, object := escape(err)
+				if tag == h.tag && uid == h.uid {
+					t.restore(f, h)
+					t.push(object)
+					f.pc = h.end
+					return nil
+				}
+			case Tagbody:
+				if !ilos.InstanceOf(class.TagbodyTag, err) {
+					continue
+				}
+				tag, uid, _ := escape(err)
+				if pc, ok := h.tags[tag]; ok && uid == h.uid {
+					if e := t.machine.runtime.Check(f.env); e != nil {
+						err = e
+						continue
+					}
+					t.restore(f, h)
+					f.handlers = append(f.handlers, h)
+					f.pc = pc
+					return nil
+				}
+			case Protect:
+				t.restore(f, h)
+				ret, e := t.machine.Run(f.env, h.cleanup)
+				if e != nil && ilos.InstanceOf(class.Escape, e) {
+					if ret, e = t.signal(f.env, instance.NewControlError(f.env)); e == nil {
+						// A handler continued the control error, so its
+						// value is the value of unwind-protect
+						t.push(ret)
+						f.pc = h.end
+						return nil
+					}
+				}
+				if e != nil {
+					err = e
+				}
+			}
+		}
+		t.stack = t.stack[:f.base]
+		t.frames = t.frames[:len(t.frames)-1]
+	}
+	return err
+}
+
+// establish pops the tag of a block or catch and establishes the handler.
+func (t *thread) establish(f *frame, op Op, end int) ilos.Instance {
+	tag := t.pop()
+	if ilos.InstanceOf(class.Number, tag) || ilos.InstanceOf(class.Character, tag) {
+		return instance.NewDomainError(f.env, tag, class.Object)
+	}
+	uid := instance.NewInteger(t.machine.runtime.Unique())
+	var ok bool
+	if op == Block {
+		ok = f.env.BlockTag.Define(tag, uid)
+	} else {
+		ok = f.env.CatchTag.Define(tag, uid)
+	}
+	if !ok {
+		return instance.NewImmutableBinding(f.env)
+	}
+	f.handlers = append(f.handlers, handler{op: op, tag: tag, uid: uid, end: end, env: f.env, scopes: len(f.scopes), stack: len(t.stack)})
+	return nil
+}
+
+// exit pops an object and a tag and returns the escape to the block or catch
+// with the tag.
+func (t *thread) exit(f *frame, op Op) (ilos.Instance, bool) {
+	object := t.pop()
+	tag := t.pop()
+	if ilos.InstanceOf(class.Number, tag) || ilos.InstanceOf(class.Character, tag) {
+		return instance.NewDomainError(f.env, tag, class.Object), true
+	}
+	if op == ReturnFrom {
+		if uid, ok := f.env.BlockTag.Get(tag); ok {
+			return instance.NewBlockTag(tag, uid, object), false
+		}
+	} else {
+		if uid, ok := f.env.CatchTag.Get(tag); ok {
+			return instance.NewCatchTag(tag, uid, object), false
+		}
+	}
+	return instance.NewControlError(f.env), true
+}
+
+// call calls the function below argc arguments on the stack. It pushes the
+// frame of the call if the function is a closure of the machine, or its value
+// otherwise. The call frame of the environment records form, the call.
+func (t *thread) call(f *frame, argc int, tail bool, form ilos.Instance) ilos.Instance {
+	if err := t.machine.runtime.Check(f.env); err != nil {
+		return err
+	}
+	arguments := make([]ilos.Instance, argc)
+	copy(arguments, t.stack[len(t.stack)-argc:])
+	function := t.stack[len(t.stack)-argc-1]
+	t.stack = t.stack[:len(t.stack)-argc-1]
+	name := function
+	if cons, ok := form.(*instance.Cons); ok && ilos.InstanceOf(class.Symbol, cons.Car) {
+		name = cons.Car
+	}
+	e := f.env.NewDynamic()
+	c, ok := function.(*closure)
+	ok = ok && c.machine == t.machine
+	e.Call(&f.env, name, arguments, form, ok && tail)
+	if ok {
+		g, ret, err := t.enter(e, c, arguments)
+		if g == nil {
+			if err != nil {
+				return err
+			}
+			t.push(ret)
+			return nil
+		}
+		if tail {
+			t.stack = t.stack[:f.base]
+			g.base = f.base
+			t.frames[len(t.frames)-1] = g
+		} else {
+			t.frames = append(t.frames, g)
+		}
+		return nil
+	}
+	ret, err := function.(instance.Applicable).Apply(e, arguments...)
+	if err != nil {
+		return err
+	}
+	t.push(ret)
+	return nil
+}
+
+// leave pops the current frame and pushes its value to the caller.
+func (t *thread) leave() {
+	f := t.frames[len(t.frames)-1]
+	ret := t.pop()
+	t.stack = t.stack[:f.base]
+	t.frames = t.frames[:len(t.frames)-1]
+	t.push(ret)
+}
+
+// macro expands the macro form and evaluates the expansion.
+func (t *thread) macro(f *frame, form *instance.Cons, mac ilos.Instance) (ilos.Instance, ilos.Instance) {
+	arguments, ok := instance.ProperList(form.Cdr)
+	if !ok {
+		return t.signal(f.env, instance.NewDomainError(f.env, form.Cdr, class.List))
+	}
+	expansion, err := mac.(instance.Applicable).Apply(f.env.NewDynamic(), arguments...)
+	if err != nil {
+		return nil, err
+	}
+	return t.machine.Run(f.env, t.machine.Compile(f.env, expansion))
+}
+
+func (t *thread) run() (ilos.Instance, ilos.Instance) {
+	for {
+		f := t.frames[len(t.frames)-1]
+		pc := f.pc
+		in := f.code.Instructions[pc]
+		f.pc++
+		var err ilos.Instance
+		switch in.Op {
+		case Const:
+			t.push(f.code.Constants[in.A])
+		case Variable:
+			symbol := f.code.Constants[in.A]
+			if v, ok := f.env.Variable.Get(symbol); ok {
+				t.push(v)
+			} else if v, ok := f.env.Constant.Get(symbol); ok {
+				t.push(v)
+			} else {
+				err = t.fail(f, instance.NewUndefinedVariable(f.env, symbol))
+			}
+		case SetVariable:
+			symbol := f.code.Constants[in.A]
+			if !f.env.Variable.Set(symbol, t.stack[len(t.stack)-1]) {
+				t.pop()
+				err = t.fail(f, instance.NewUndefinedVariable(f.env, symbol))
+			}
+		case Dynamic:
+			symbol := f.code.Constants[in.A]
+			if v, ok := f.env.DynamicVariable.Get(symbol); ok {
+				t.push(v)
+			} else {
+				err = t.fail(f, instance.NewUndefinedVariable(f.env, symbol))
+			}
+		case Pop:
+			t.pop()
+		case Jump:
+			if in.A < f.pc {
+				err = t.machine.runtime.Check(f.env)
+			}
+			f.pc = in.A
+		case JumpIfNil:
+			if t.pop() == instance.Nil {
+				f.pc = in.A
+			}
+		case JumpIfNotNil:
+			if t.stack[len(t.stack)-1] != instance.Nil {
+				f.pc = in.A
+			} else {
+				t.pop()
+			}
+		case PushScope:
+			f.scopes = append(f.scopes, scope{f.env, len(t.stack) - in.A})
+			f.env = f.env.NewLexical()
+		case PopScope:
+			f.env = f.scopes[len(f.scopes)-1].env
+			f.scopes = f.scopes[:len(f.scopes)-1]
+		case Bind:
+			if !f.env.Variable.Define(f.code.Constants[in.A], t.pop()) {
+				err = t.rebind(f, in.B)
+			}
+		case BindDynamic:
+			if !f.env.DynamicVariable.Define(f.code.Constants[in.A], t.pop()) {
+				err = t.rebind(f, in.B)
+			}
+		case BindFunction:
+			if !f.env.Function.Define(f.code.Constants[in.A], t.pop()) {
+				err = t.rebind(f, in.B)
+			}
+		case Defun:
+			f.env.Function.Global().Define(f.code.Constants[in.A], t.pop())
+			t.push(f.code.Constants[in.A])
+		case Function:
+			symbol := f.code.Constants[in.A]
+			if v, ok := f.env.Function.Get(symbol); ok {
+				t.push(v)
+			} else {
+				err = t.fail(f, instance.NewUndefinedFunction(f.env, symbol))
+			}
+		case Callee:
+			form := f.code.Constants[in.A].(*instance.Cons)
+			if v, ok := f.env.Function.Get(form.Car); ok {
+				t.push(v)
+				break
+			}
+			// The macro may be defined after compilation
+			var ret ilos.Instance
+			if mac, ok := f.env.Macro.Get(form.Car); ok {
+				ret, err = t.macro(f, form, mac)
+			} else {
+				ret, err = t.signal(f.env, instance.NewUndefinedFunction(f.env, form.Car))
+			}
+			if err == nil {
+				t.push(ret)
+				f.pc = in.B
+			}
+		case Macro:
+			form := f.code.Constants[in.A].(*instance.Cons)
+			mac, _ := f.env.Macro.Get(form.Car)
+			var ret ilos.Instance
+			if ret, err = t.macro(f, form, mac); err == nil {
+				t.push(ret)
+			}
+		case Closure:
+			t.push(&closure{t.machine, f.code.Codes[in.A], f.env})
+		case Call:
+			err = t.call(f, in.A, false, f.code.Forms[pc])
+		case TailCall:
+			// The frame is still there if the function was not a closure
+			if err = t.call(f, in.A, true, f.code.Forms[pc]); err == nil && t.frames[len(t.frames)-1] == f {
+				if len(t.frames) == 1 {
+					return t.pop(), nil
+				}
+				t.leave()
+			}
+		case Return:
+			if len(t.frames) == 1 {
+				return t.pop(), nil
+			}
+			t.leave()
+		case Special:
+			forms, _ := instance.ProperList(f.code.Constants[in.B])
+			var ret ilos.Instance
+			if ret, err = t.machine.runtime.Special(f.env, f.code.Constants[in.A], forms...); err == nil {
+				t.push(ret)
+			}
+		case Block, Catch:
+			if err = t.establish(f, in.Op, in.A); err != nil {
+				var ret ilos.Instance
+				if ret, err = t.signal(f.env, err); err == nil {
+					t.push(ret)
+					f.pc = in.A
+				}
+			}
+		case ReturnFrom, Throw:
+			var signal bool
+			if err, signal = t.exit(f, in.Op); signal {
+				err = t.fail(f, err)
+			}
+		case Tagbody:
+			uid := instance.NewInteger(t.machine.runtime.Unique())
+			for tag := range f.code.Tags[in.A] {
+				f.env.TagbodyTag.Define(tag, uid) // The tags are distinct
+			}
+			f.handlers = append(f.handlers, handler{op: Tagbody, uid: uid, tags: f.code.Tags[in.A], env: f.env, scopes: len(f.scopes), stack: len(t.stack)})
+		case Go:
+			tag := f.code.Constants[in.A]
+			if uid, ok := f.env.TagbodyTag.Get(tag); ok {
+				err = instance.NewTagbodyTag(tag, uid)
+			} else {
+				err = t.fail(f, instance.NewControlError(f.env))
+			}
+		case Protect:
+			f.handlers = append(f.handlers, handler{op: Protect, cleanup: f.code.Codes[in.A], end: in.B, env: f.env, scopes: len(f.scopes), stack: len(t.stack)})
+		case Cleanup:
+			ret, e := t.machine.Run(f.env, f.code.Codes[in.A])
+			if e != nil && ilos.InstanceOf(class.Escape, e) {
+				ret, e = t.signal(f.env, instance.NewControlError(f.env))
+				if e == nil {
+					t.pop()
+					t.push(ret)
+				}
+			}
+			err = e
+		case PopHandler:
+			f.handlers = f.handlers[:len(f.handlers)-1]
+		}
+		if err != nil {
+			if form := f.code.Forms[pc]; form != nil && t.machine.runtime.Locate != nil {
+				err = t.machine.runtime.Locate(form, err)
+			}
+			if err = t.unwind(err); err != nil {
+				return nil, err
+			}
+		}
+	}
+}